@@ -0,0 +1,97 @@
+// Command apikey mints, lists and revokes the API keys accepted by the server.
+//
+//	apikey create -name music-night -scopes read,write
+//	apikey list
+//	apikey revoke -id 3
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/config"
+	"github.com/Xapsiel/EffectiveMobile/internal/repository"
+	"github.com/Xapsiel/EffectiveMobile/internal/service"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: apikey create -name NAME -scopes read[,write][,admin]")
+	fmt.Fprintln(os.Stderr, "       apikey list")
+	fmt.Fprintln(os.Stderr, "       apikey revoke -id ID")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd, args := os.Args[1], os.Args[2:]
+
+	flags := flag.NewFlagSet(cmd, flag.ExitOnError)
+	name := flags.String("name", "", "name telling who the key belongs to")
+	scopes := flags.String("scopes", "read", "comma-separated scopes: read, write, admin")
+	id := flags.Int("id", 0, "ID of the key to revoke")
+	flags.Parse(args)
+
+	cfg, err := config.New()
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	db, err := repository.NewPostgresDB(cfg.DatabaseConfig)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	defer db.Close()
+	keys := service.NewAPIKeyService(repository.NewAPIKeyRepository(db))
+
+	switch cmd {
+	case "create":
+		key, secret, err := keys.CreateAPIKey(*name, strings.Split(*scopes, ","))
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		fmt.Printf("Created key %d (%s) with scopes %s.\n", key.ID, key.Name, strings.Join(key.Scopes, ","))
+		fmt.Println("Store it now, it cannot be shown again:")
+		fmt.Println(secret)
+	case "list":
+		list, err := keys.ListAPIKeys()
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tPREFIX\tSCOPES\tCREATED\tLAST USED\tREVOKED")
+		for _, k := range list {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", k.ID, k.Name, k.Prefix, strings.Join(k.Scopes, ","),
+				k.CreatedAt.Format(time.DateTime), formatTime(k.LastUsedAt), formatTime(k.RevokedAt))
+		}
+		w.Flush()
+	case "revoke":
+		if *id < 1 {
+			usage()
+		}
+		key, err := keys.RevokeAPIKey(*id)
+		if err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+		fmt.Printf("Key %d (%s) revoked at %s\n", key.ID, key.Name, formatTime(key.RevokedAt))
+	default:
+		usage()
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
 	"log/slog"
+	"net"
 	"os"
 
+	"github.com/Xapsiel/EffectiveMobile/internal/api"
 	"github.com/Xapsiel/EffectiveMobile/internal/config"
+	"github.com/Xapsiel/EffectiveMobile/internal/graph"
 	"github.com/Xapsiel/EffectiveMobile/internal/handler"
+	"github.com/Xapsiel/EffectiveMobile/internal/i18n"
 	"github.com/Xapsiel/EffectiveMobile/internal/model"
 	"github.com/Xapsiel/EffectiveMobile/internal/repository"
+	"github.com/Xapsiel/EffectiveMobile/internal/rpc"
 	"github.com/Xapsiel/EffectiveMobile/internal/service"
 )
 
@@ -17,6 +23,11 @@ import (
 
 // @host			localhost:8080
 // @BasePath		/
+
+// @securityDefinitions.apikey	ApiKeyAuth
+// @in							header
+// @name						X-API-Key
+// @description				API-ключ, выданный командой apikey. API-ключ или токен доступа из /auth/login можно передать и заголовком Authorization: Bearer <токен>
 func main() {
 	cfg, err := config.New()
 	if err != nil {
@@ -29,8 +40,46 @@ func main() {
 		os.Exit(1)
 	}
 	repos := repository.NewRepository(db)
-	services := service.NewService(repos)
-	handlers := handler.NewHandler(services)
+	lyrics, err := service.NewLyrics(cfg.LyricsConfig)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	tokens, err := service.NewTokens(cfg.AuthConfig)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	sender, err := service.NewWebhookSender(cfg.WebhookConfig)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	services := service.NewService(repos, api.NewClient(cfg.APIConfig), lyrics, tokens, sender)
+	go services.DeliverWebhooks(context.Background())
+	locales, err := i18n.New(cfg.I18nConfig.DefaultLanguage)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	lis, err := net.Listen("tcp", ":"+cfg.GRPCConfig.Port)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	grpcServer := rpc.NewServer(services, locales)
+	go func() {
+		if err := grpcServer.Serve(lis); err != nil {
+			slog.Error(err.Error())
+			os.Exit(1)
+		}
+	}()
+	schema, err := graph.New(services)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	handlers := handler.NewHandler(services, schema, cfg.RateLimitConfig, locales)
 	srv := new(model.Server)
 	if err := srv.Run(cfg.HostConfig.Port, handlers.InitRoutes()); err != nil {
 		slog.Error(err.Error())
@@ -0,0 +1,44 @@
+// Command relay publishes the song events to the sinks set by outbox_sinks
+// until it is interrupted. Several relays may run at once: one of them
+// publishes to a sink at a time, and another takes over when it stops.
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/config"
+	"github.com/Xapsiel/EffectiveMobile/internal/outbox"
+	"github.com/Xapsiel/EffectiveMobile/internal/repository"
+	"github.com/Xapsiel/EffectiveMobile/internal/service"
+)
+
+func main() {
+	cfg, err := config.New()
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	db, err := repository.NewPostgresDB(cfg.DatabaseConfig)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	sinks, err := outbox.New(cfg.OutboxConfig)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	// The relay only reads song events: it changes no songs, sends no
+	// webhooks and issues no tokens.
+	services := service.NewService(repository.NewRepository(db), nil, nil, nil, nil)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	services.RelayOutbox(ctx, sinks)
+}
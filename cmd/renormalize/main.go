@@ -0,0 +1,61 @@
+// Command renormalize runs the lyrics of every stored song through the
+// normalization pipeline and reports the songs whose verse count changed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/api"
+	"github.com/Xapsiel/EffectiveMobile/internal/config"
+	"github.com/Xapsiel/EffectiveMobile/internal/repository"
+	"github.com/Xapsiel/EffectiveMobile/internal/service"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only report changes, do not write them")
+	flag.Parse()
+
+	cfg, err := config.New()
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	db, err := repository.NewPostgresDB(cfg.DatabaseConfig)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	repos := repository.NewRepository(db)
+	lyrics, err := service.NewLyrics(cfg.LyricsConfig)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+	// The command issues no access tokens.
+	services := service.NewService(repos.As("cli:renormalize"), api.NewClient(cfg.APIConfig), lyrics, nil, nil)
+
+	report, changed, err := services.Renormalize(*dryRun)
+	if err != nil {
+		slog.Error(err.Error())
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tGROUP\tSONG\tVERSES BEFORE\tVERSES AFTER")
+	for _, r := range report {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%d\n", r.ID, r.Group, r.SongName, r.VersesBefore, r.VersesAfter)
+	}
+	w.Flush()
+
+	verb := "renormalized"
+	if *dryRun {
+		verb = "would be renormalized"
+	}
+	fmt.Printf("\n%d songs %s, %d of them changed verse count\n", changed, verb, len(report))
+}
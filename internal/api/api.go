@@ -2,7 +2,6 @@ package api
 
 import (
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 	"net/url"
@@ -34,19 +33,24 @@ func (c *Client) GetInfo(group, song string) (model.Song, error) {
 
 	resp, err := c.Client.Do(req)
 	if err != nil {
-		return model.Song{}, err
+		return model.Song{}, model.NewError(model.ErrUpstreamUnavailable, "Сервис информации о песнях недоступен: %v", err)
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return model.Song{}, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return model.Song{}, model.NewError(model.ErrNotFound, "Сервис информации о песнях не знает песню %q группы %q", song, group)
+	case resp.StatusCode == http.StatusBadRequest:
+		return model.Song{}, model.NewError(model.ErrValidation, "Сервис информации о песнях отклонил песню %q группы %q", song, group)
+	case resp.StatusCode != http.StatusOK:
+		return model.Song{}, model.NewError(model.ErrUpstreamUnavailable, "Сервис информации о песнях ответил статусом %d", resp.StatusCode)
 	}
 	var songResponse model.Song
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return model.Song{}, err
+		return model.Song{}, model.NewError(model.ErrUpstreamUnavailable, "Не удалось прочитать ответ сервиса информации о песнях: %v", err)
 	}
 	if err := json.Unmarshal(body, &songResponse); err != nil {
-		return model.Song{}, err
+		return model.Song{}, model.NewError(model.ErrUpstreamUnavailable, "Сервис информации о песнях вернул некорректный ответ: %v", err)
 	}
 	return songResponse, nil
 }
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/joho/godotenv"
 )
@@ -8,7 +10,14 @@ import (
 type Config struct {
 	DatabaseConfig
 	HostConfig
+	GRPCConfig
 	APIConfig
+	LyricsConfig
+	AuthConfig
+	RateLimitConfig
+	I18nConfig
+	WebhookConfig
+	OutboxConfig
 }
 type DatabaseConfig struct {
 	Host     string `env:"db_host"`
@@ -21,9 +30,68 @@ type DatabaseConfig struct {
 type HostConfig struct {
 	Port string `env:"host_port"`
 }
+
+// GRPCConfig sets the port of the gRPC API, served next to the HTTP one.
+type GRPCConfig struct {
+	Port string `env:"grpc_port" env-default:"9090"`
+}
 type APIConfig struct {
 	Domain string `env:"domain"`
 }
+type LyricsConfig struct {
+	StraightenQuotes bool   `env:"lyrics_straighten_quotes" env-default:"false"`
+	SectionParser    string `env:"lyrics_section_parser" env-default:"plain"`
+}
+
+// AuthConfig configures the user access tokens. Tokens are signed with RS256
+// when JWTPrivateKeyFile points to a PEM RSA key, with HS256 and JWTSecret
+// otherwise.
+type AuthConfig struct {
+	JWTSecret         string        `env:"jwt_secret"`
+	JWTPrivateKeyFile string        `env:"jwt_private_key_file"`
+	AccessTokenTTL    time.Duration `env:"jwt_access_ttl" env-default:"15m"`
+	RefreshTokenTTL   time.Duration `env:"jwt_refresh_ttl" env-default:"720h"`
+}
+
+// I18nConfig sets the language of API messages for clients whose
+// Accept-Language names no supported language.
+type I18nConfig struct {
+	DefaultLanguage string `env:"default_language" env-default:"ru"`
+}
+
+// RateLimitConfig sets the token buckets of each client: requests per second
+// refilled and the burst allowed, separately for reading (GET, HEAD, OPTIONS)
+// and writing requests. A zero rate turns the limit off.
+type RateLimitConfig struct {
+	ReadRate   float64 `env:"rate_limit_read_rps" env-default:"20"`
+	ReadBurst  int     `env:"rate_limit_read_burst" env-default:"40"`
+	WriteRate  float64 `env:"rate_limit_write_rps" env-default:"2"`
+	WriteBurst int     `env:"rate_limit_write_burst" env-default:"10"`
+	// TrustedProxies are the proxies whose X-Forwarded-For header is believed
+	// when telling anonymous clients apart by IP.
+	TrustedProxies []string `env:"trusted_proxies" env-separator:","`
+}
+
+// WebhookConfig sets how webhook deliveries are sent: the timeout of a
+// request and the attempts made before a delivery is marked dead, waiting
+// RetryBase after the first failure and twice as long after each next one,
+// up to RetryMax.
+type WebhookConfig struct {
+	Timeout     time.Duration `env:"webhook_timeout" env-default:"10s"`
+	MaxAttempts int           `env:"webhook_max_attempts" env-default:"8"`
+	RetryBase   time.Duration `env:"webhook_retry_base" env-default:"30s"`
+	RetryMax    time.Duration `env:"webhook_retry_max" env-default:"1h"`
+}
+
+// OutboxConfig sets the sinks the outbox relay publishes the song events to:
+// log writes them to the log, file appends them to File as JSON lines and
+// http posts them to URL.
+type OutboxConfig struct {
+	Sinks   []string      `env:"outbox_sinks" env-separator:"," env-default:"log"`
+	File    string        `env:"outbox_file" env-default:"outbox.ndjson"`
+	URL     string        `env:"outbox_url"`
+	Timeout time.Duration `env:"outbox_timeout" env-default:"10s"`
+}
 
 func New() (*Config, error) {
 	if err := godotenv.Load(); err != nil {
@@ -0,0 +1,97 @@
+package graph
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/i18n"
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"golang.org/x/text/message"
+)
+
+// errorCodes maps the domain error kinds to the code and HTTP status reported
+// in the extensions of a GraphQL error, the status being the one the REST
+// endpoints answer with.
+var errorCodes = []struct {
+	kind   error
+	code   string
+	status int
+}{
+	{model.ErrNotFound, "NOT_FOUND", http.StatusNotFound},
+	{model.ErrConflict, "CONFLICT", http.StatusConflict},
+	{model.ErrValidation, "VALIDATION_FAILED", http.StatusUnprocessableEntity},
+	{model.ErrUpstreamUnavailable, "UPSTREAM_UNAVAILABLE", http.StatusBadGateway},
+	{model.ErrPreconditionFailed, "PRECONDITION_FAILED", http.StatusPreconditionFailed},
+	{model.ErrUnauthorized, "UNAUTHENTICATED", http.StatusUnauthorized},
+	{model.ErrForbidden, "FORBIDDEN", http.StatusForbidden},
+}
+
+// songFieldNames renames the song fields of model.Song to the arguments of
+// the schema in field errors.
+var songFieldNames = map[string]string{
+	"song_name":   "songName",
+	"group_name":  "group",
+	"releaseDate": "releaseDate",
+	"text":        "text",
+}
+
+// formatError translates the message of a resolver error into the language of
+// p and describes it in the extensions. Errors of the query itself, such as
+// syntax errors, are left as the executor reported them; errors that are not
+// domain errors are logged and reported without their message.
+func formatError(p *message.Printer, ferr gqlerrors.FormattedError) gqlerrors.FormattedError {
+	err := originalError(ferr)
+	if err == nil {
+		return ferr
+	}
+	var merr *model.Error
+	if !errors.As(err, &merr) {
+		slog.Error("Ошибка при выполнении запроса GraphQL", "error", err)
+		ferr.Message = i18n.Sprintf(p, "Не удалось обработать запрос")
+		ferr.Extensions = map[string]any{"code": "INTERNAL", "status": http.StatusInternalServerError}
+		return ferr
+	}
+
+	ferr.Message = i18n.Error(p, err)
+	ferr.Extensions = map[string]any{"code": "INTERNAL", "status": http.StatusInternalServerError}
+	for _, e := range errorCodes {
+		if errors.Is(err, e.kind) {
+			ferr.Extensions = map[string]any{"code": e.code, "status": e.status}
+			break
+		}
+	}
+	if len(merr.Fields) > 0 {
+		fields := make([]map[string]string, 0, len(merr.Fields))
+		for _, f := range merr.Fields {
+			field := f.Field
+			if name, ok := songFieldNames[field]; ok {
+				field = name
+			}
+			msg := f.Message
+			if f.Format != "" {
+				msg = i18n.Sprintf(p, f.Format, f.Args...)
+			}
+			fields = append(fields, map[string]string{"field": field, "message": msg})
+		}
+		ferr.Extensions["fields"] = fields
+	}
+	return ferr
+}
+
+// originalError digs the error a resolver returned out of the wrappers the
+// executor put around it, or returns nil for errors of the query itself.
+func originalError(err error) error {
+	for err != nil {
+		switch e := err.(type) {
+		case gqlerrors.FormattedError:
+			err = e.OriginalError()
+		case *gqlerrors.Error:
+			err = e.OriginalError
+		default:
+			return err
+		}
+	}
+	return nil
+}
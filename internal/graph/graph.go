@@ -0,0 +1,136 @@
+// Package graph serves songs, groups and verses as a GraphQL schema over the
+// service layer. Nested lists are loaded in batches, one service call per
+// level of the query, instead of one call per parent object.
+package graph
+
+import (
+	"context"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/Xapsiel/EffectiveMobile/internal/service"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"golang.org/x/text/message"
+)
+
+// Schema executes GraphQL requests against the service layer.
+type Schema struct {
+	full     graphql.Schema
+	readOnly graphql.Schema
+	services service.Service
+}
+
+// Call is a GraphQL request made on behalf of Caller.
+type Call struct {
+	Query         string
+	OperationName string
+	Variables     map[string]any
+	Caller        model.Principal
+	// ReadOnly rejects mutations, for requests that must not change anything.
+	ReadOnly bool
+}
+
+// New builds the schema over services.
+func New(services service.Service) (*Schema, error) {
+	query, mutation := newQuery(), newMutation()
+	full, err := graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+	if err != nil {
+		return nil, err
+	}
+	readOnly, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{full: full, readOnly: readOnly, services: services}, nil
+}
+
+// Do executes call. Error messages are printed with p and carry the error
+// code and the matching HTTP status in their extensions.
+func (s *Schema) Do(ctx context.Context, call Call, p *message.Printer) *graphql.Result {
+	schema := s.full
+	if call.ReadOnly {
+		schema = s.readOnly
+	}
+	// A query that does not parse gets its syntax error from graphql.Do.
+	if doc, err := parser.Parse(parser.ParseParams{Source: call.Query}); err == nil {
+		if depth := queryDepth(doc); depth > maxQueryDepth {
+			err := model.NewError(model.ErrValidation, "Глубина запроса %d больше допустимой %d", depth, maxQueryDepth)
+			return &graphql.Result{Errors: []gqlerrors.FormattedError{formatError(p, gqlerrors.FormatError(err))}}
+		}
+	}
+	res := graphql.Do(graphql.Params{
+		Schema:         schema,
+		RequestString:  call.Query,
+		OperationName:  call.OperationName,
+		VariableValues: call.Variables,
+		Context:        context.WithValue(ctx, requestKey{}, newRequest(s.services, call.Caller)),
+	})
+	for i, err := range res.Errors {
+		res.Errors[i] = formatError(p, err)
+	}
+	return res
+}
+
+type requestKey struct{}
+
+// request is the state of one GraphQL request: who makes it and the loaders
+// batching its lookups.
+type request struct {
+	services   service.Service
+	caller     model.Principal
+	verses     *loader[int, []model.Verse]
+	groupSongs *loader[groupSongsKey, []model.Song]
+}
+
+// groupSongsKey is a page of a group's songs; the songs of all groups asked
+// for with the same page, limit and order are fetched together.
+type groupSongsKey struct {
+	groupID     int
+	sort        model.SongSort
+	page, limit int
+}
+
+type groupSongsPage struct {
+	sort        model.SongSort
+	page, limit int
+}
+
+func newRequest(services service.Service, caller model.Principal) *request {
+	return &request{
+		services: services,
+		caller:   caller,
+		verses:   newLoader(services.GetSongsVerses),
+		groupSongs: newLoader(func(keys []groupSongsKey) (map[groupSongsKey][]model.Song, error) {
+			pages := make(map[groupSongsPage][]int)
+			for _, key := range keys {
+				page := groupSongsPage{sort: key.sort, page: key.page, limit: key.limit}
+				pages[page] = append(pages[page], key.groupID)
+			}
+			res := make(map[groupSongsKey][]model.Song, len(keys))
+			for page, groupIDs := range pages {
+				songs, err := services.GetGroupsSongs(groupIDs, page.sort, page.page, page.limit)
+				if err != nil {
+					return nil, err
+				}
+				for groupID, groupSongs := range songs {
+					res[groupSongsKey{groupID: groupID, sort: page.sort, page: page.page, limit: page.limit}] = groupSongs
+				}
+			}
+			return res, nil
+		}),
+	}
+}
+
+func requestFrom(ctx context.Context) *request {
+	return ctx.Value(requestKey{}).(*request)
+}
+
+// as returns the services acting on behalf of the caller after checking that
+// the caller's role includes role.
+func (r *request) as(role string) (service.Service, error) {
+	if !r.caller.Allows(role) {
+		return service.Service{}, model.NewError(model.ErrForbidden, "Недостаточно прав: нужна роль %s", role)
+	}
+	return r.services.As(r.caller.Actor()), nil
+}
@@ -0,0 +1,59 @@
+package graph
+
+import "sync"
+
+// loader batches the lookups of one request. The executor resolves all
+// fields of a query level before running the thunks they returned, so the
+// keys loaded on a level are fetched together by the first thunk that runs.
+type loader[K comparable, V any] struct {
+	fetch func(keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending []K
+	queued  map[K]bool
+	results map[K]V
+	errs    map[K]error
+}
+
+func newLoader[K comparable, V any](fetch func(keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{
+		fetch:   fetch,
+		queued:  make(map[K]bool),
+		results: make(map[K]V),
+		errs:    make(map[K]error),
+	}
+}
+
+// load queues key for the next batch and returns the thunk giving its value.
+// Keys missing from the fetched map get the zero value.
+func (l *loader[K, V]) load(key K) func() (V, error) {
+	l.mu.Lock()
+	if !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (V, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if _, done := l.results[key]; !done && l.errs[key] == nil {
+			l.flush()
+		}
+		return l.results[key], l.errs[key]
+	}
+}
+
+// flush fetches the pending keys; l.mu must be held.
+func (l *loader[K, V]) flush() {
+	keys := l.pending
+	l.pending = nil
+	res, err := l.fetch(keys)
+	for _, key := range keys {
+		if err != nil {
+			l.errs[key] = err
+			continue
+		}
+		l.results[key] = res[key]
+	}
+}
@@ -0,0 +1,107 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/Xapsiel/EffectiveMobile/internal/service"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestLoader(t *testing.T) {
+	errFetch := errors.New("fetch failed")
+	var batches [][]int
+	l := newLoader(func(keys []int) (map[int]string, error) {
+		batches = append(batches, slices.Clone(keys))
+		if slices.Contains(keys, 13) {
+			return nil, errFetch
+		}
+		res := make(map[int]string)
+		for _, key := range keys {
+			if key != 0 {
+				res[key] = string(rune('a' + key))
+			}
+		}
+		return res, nil
+	})
+
+	// One level of a query: every key is queued before the first thunk runs.
+	thunks := map[int]func() (string, error){}
+	for _, key := range []int{1, 2, 1, 0, 3} {
+		thunks[key] = l.load(key)
+	}
+	for key, want := range map[int]string{1: "b", 2: "c", 3: "d", 0: ""} {
+		if got, err := thunks[key](); got != want || err != nil {
+			t.Errorf("key %d = %q, %v, want %q", key, got, err, want)
+		}
+	}
+	// The next level fetches only the keys not loaded yet.
+	again, fresh := l.load(2), l.load(4)
+	if got, _ := fresh(); got != "e" {
+		t.Errorf("key 4 = %q, want e", got)
+	}
+	if got, _ := again(); got != "c" {
+		t.Errorf("key 2 = %q, want c", got)
+	}
+	// A failed batch fails every key in it.
+	failed, sibling := l.load(13), l.load(5)
+	if _, err := sibling(); !errors.Is(err, errFetch) {
+		t.Errorf("key 5 error = %v, want %v", err, errFetch)
+	}
+	if _, err := failed(); !errors.Is(err, errFetch) {
+		t.Errorf("key 13 error = %v, want %v", err, errFetch)
+	}
+
+	want := [][]int{{1, 2, 0, 3}, {4}, {13, 5}}
+	if !slices.EqualFunc(batches, want, slices.Equal) {
+		t.Errorf("batches = %v, want %v", batches, want)
+	}
+}
+
+type listSongs struct {
+	service.Song
+	songs []model.Song
+}
+
+func (f listSongs) GetSongs(filter model.Song, sort model.SongSort, page int, limit int) ([]model.Song, error) {
+	return f.songs, nil
+}
+
+type countingVerses struct {
+	service.Verse
+	calls *[][]int
+}
+
+func (f countingVerses) GetSongsVerses(songIDs []int) (map[int][]model.Verse, error) {
+	*f.calls = append(*f.calls, slices.Clone(songIDs))
+	res := make(map[int][]model.Verse, len(songIDs))
+	for _, id := range songIDs {
+		text := "verse"
+		res[id] = []model.Verse{{Text: &text}}
+	}
+	return res, nil
+}
+
+func TestDoBatchesVerses(t *testing.T) {
+	songs := make([]model.Song, 3)
+	for i := range songs {
+		id := i + 1
+		songs[i].ID = &id
+	}
+	var calls [][]int
+	schema, err := New(service.Service{Song: listSongs{songs: songs}, Verse: countingVerses{calls: &calls}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := schema.Do(context.Background(), Call{Query: `{ songs { id verses { text } } }`}, message.NewPrinter(language.Russian))
+	if len(res.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", res.Errors)
+	}
+	if want := [][]int{{1, 2, 3}}; !slices.EqualFunc(calls, want, slices.Equal) {
+		t.Errorf("GetSongsVerses calls = %v, want %v", calls, want)
+	}
+}
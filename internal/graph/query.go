@@ -0,0 +1,93 @@
+package graph
+
+import (
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// maxQueryDepth caps how deeply fields may be nested. Groups list songs and
+// songs their group, so without it a query could fan out without end.
+const maxQueryDepth = 6
+
+// Mutates reports whether the operation of query named operationName, or its
+// only operation, is a mutation. A query that does not parse mutates nothing.
+func Mutates(query, operationName string) bool {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return false
+	}
+	var op *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		if d, ok := def.(*ast.OperationDefinition); ok {
+			if operationName == "" || (d.Name != nil && d.Name.Value == operationName) {
+				op = d
+				break
+			}
+		}
+	}
+	return op != nil && op.Operation == ast.OperationTypeMutation
+}
+
+// queryDepth is the deepest nesting of fields in the operations of doc,
+// following fragments. Introspection fields are not counted.
+func queryDepth(doc *ast.Document) int {
+	d := depthWalker{fragments: make(map[string]*ast.FragmentDefinition), depths: make(map[string]int)}
+	for _, def := range doc.Definitions {
+		if f, ok := def.(*ast.FragmentDefinition); ok && f.Name != nil {
+			d.fragments[f.Name.Value] = f
+		}
+	}
+	depth := 0
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok {
+			depth = max(depth, d.selections(op.SelectionSet))
+		}
+	}
+	return depth
+}
+
+type depthWalker struct {
+	fragments map[string]*ast.FragmentDefinition
+	// depths holds the depth of the fragments walked, -1 while one is being
+	// walked: a fragment spreading itself is left to the validation to report.
+	depths map[string]int
+}
+
+func (d depthWalker) selections(set *ast.SelectionSet) int {
+	if set == nil {
+		return 0
+	}
+	depth := 0
+	for _, sel := range set.Selections {
+		switch s := sel.(type) {
+		case *ast.Field:
+			if s.Name != nil && strings.HasPrefix(s.Name.Value, "__") {
+				continue
+			}
+			depth = max(depth, 1+d.selections(s.SelectionSet))
+		case *ast.InlineFragment:
+			depth = max(depth, d.selections(s.SelectionSet))
+		case *ast.FragmentSpread:
+			if s.Name != nil {
+				depth = max(depth, d.fragment(s.Name.Value))
+			}
+		}
+	}
+	return depth
+}
+
+func (d depthWalker) fragment(name string) int {
+	if depth, ok := d.depths[name]; ok {
+		return max(depth, 0)
+	}
+	f, ok := d.fragments[name]
+	if !ok {
+		return 0
+	}
+	d.depths[name] = -1
+	depth := d.selections(f.SelectionSet)
+	d.depths[name] = depth
+	return depth
+}
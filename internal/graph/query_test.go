@@ -0,0 +1,115 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/Xapsiel/EffectiveMobile/internal/service"
+	"github.com/graphql-go/graphql/language/parser"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func TestQueryDepth(t *testing.T) {
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{query: `{ songs { id } }`, want: 2},
+		{query: `{ groups { name songs { songName verses { text } } } }`, want: 4},
+		{query: `{ songs { group { songs { group { songs { id } } } } } }`, want: 6},
+		{query: `query A { songs { id } } query B { groups { songs { verses { text } } } }`, want: 4},
+		{query: `{ songs { ... on Song { group { name } } } }`, want: 3},
+		{query: `{ groups { ...G } } fragment G on Group { songs { ...S } } fragment S on Song { verses { text } }`, want: 4},
+		{query: `{ songs { ...S ...S } } fragment S on Song { id ...S }`, want: 2},
+		{query: `{ __schema { types { fields { type { ofType { ofType { name } } } } } } }`, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			doc, err := parser.Parse(parser.ParseParams{Source: tt.query})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := queryDepth(doc); got != tt.want {
+				t.Errorf("depth = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMutates(t *testing.T) {
+	tests := []struct {
+		query, operation string
+		want             bool
+	}{
+		{query: `{ songs { id } }`, want: false},
+		{query: `query { songs { id } }`, want: false},
+		{query: `mutation { deleteSong(id: 1, version: 1) }`, want: true},
+		{query: `query Q { songs { id } } mutation M { deleteSong(id: 1, version: 1) }`, operation: "M", want: true},
+		{query: `query Q { songs { id } } mutation M { deleteSong(id: 1, version: 1) }`, operation: "Q", want: false},
+		{query: `mutation {`, want: false},
+	}
+	for _, tt := range tests {
+		if got := Mutates(tt.query, tt.operation); got != tt.want {
+			t.Errorf("Mutates(%q, %q) = %v, want %v", tt.query, tt.operation, got, tt.want)
+		}
+	}
+}
+
+type fakeSongs struct {
+	service.Song
+	pages [][2]int
+}
+
+func (f *fakeSongs) GetSongs(filter model.Song, sort model.SongSort, page int, limit int) ([]model.Song, error) {
+	f.pages = append(f.pages, [2]int{page, limit})
+	return nil, nil
+}
+
+type fakeVerses struct {
+	service.Verse
+}
+
+func (fakeVerses) GetSongsVerses(songIDs []int) (map[int][]model.Verse, error) {
+	return nil, nil
+}
+
+func TestDoLimits(t *testing.T) {
+	tests := []struct {
+		query string
+		vars  map[string]any
+		code  string
+	}{
+		{query: `{ songs { id } }`},
+		{query: `{ songs(page: 2, limit: 100) { id } }`},
+		{query: `{ songs(page: 0) { id } }`, code: "VALIDATION_FAILED"},
+		{query: `{ songs(limit: -1) { id } }`, code: "VALIDATION_FAILED"},
+		{query: `{ songs(limit: 1000000) { id } }`, code: "VALIDATION_FAILED"},
+		{query: `query($page: Int) { songs(page: $page) { id } }`, vars: map[string]any{"page": 0}, code: "VALIDATION_FAILED"},
+		{query: `{ songs { group { songs { group { songs { group { name } } } } } } }`, code: "VALIDATION_FAILED"},
+	}
+	p := message.NewPrinter(language.Russian)
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			songs := &fakeSongs{}
+			schema, err := New(service.Service{Song: songs, Verse: fakeVerses{}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := schema.Do(context.Background(), Call{Query: tt.query, Variables: tt.vars}, p)
+			if tt.code == "" {
+				if len(res.Errors) > 0 {
+					t.Fatalf("unexpected errors: %v", res.Errors)
+				}
+				return
+			}
+			if len(res.Errors) != 1 || res.Errors[0].Extensions["code"] != tt.code {
+				t.Fatalf("errors = %v, want one %s", res.Errors, tt.code)
+			}
+			if len(songs.pages) > 0 {
+				t.Errorf("GetSongs called with %v", songs.pages)
+			}
+		})
+	}
+}
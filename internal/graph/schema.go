@@ -0,0 +1,396 @@
+package graph
+
+import (
+	"log/slog"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/graphql-go/graphql"
+)
+
+var songSortEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name:        "SongSort",
+	Description: "Поле сортировки песен; равные песни упорядочиваются по ID.",
+	Values: graphql.EnumValueConfigMap{
+		"ID":           {Value: model.SortByID},
+		"SONG_NAME":    {Value: model.SortBySongName},
+		"GROUP":        {Value: model.SortByGroup},
+		"RELEASE_DATE": {Value: model.SortByReleaseDate},
+	},
+})
+
+// maxListLimit caps the limit argument of lists.
+const maxListLimit = 100
+
+// pageArgs are the sorting and pagination arguments of song lists, with the
+// defaults of GET /info.
+func pageArgs() graphql.FieldConfigArgument {
+	return graphql.FieldConfigArgument{
+		"sort":  {Type: songSortEnum, DefaultValue: model.SortByID},
+		"desc":  {Type: graphql.Boolean, DefaultValue: false},
+		"page":  {Type: graphql.Int, DefaultValue: 1},
+		"limit": {Type: graphql.Int, DefaultValue: 10, Description: "Не больше 100"},
+	}
+}
+
+var verseType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Verse",
+	Fields: graphql.Fields{
+		"ordinal": verseField(graphql.NewNonNull(graphql.Int), func(v model.Verse) any { return valueOrZero(v.Ordinal) }),
+		"type":    verseField(graphql.NewNonNull(graphql.String), func(v model.Verse) any { return valueOrZero(v.Type) }),
+		"text":    verseField(graphql.NewNonNull(graphql.String), func(v model.Verse) any { return valueOrZero(v.Text) }),
+		"label":   verseField(graphql.String, func(v model.Verse) any { return v.Label }),
+		"repeat":  verseField(graphql.Int, func(v model.Verse) any { return v.Repeat }),
+	},
+})
+
+var groupType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Group",
+	Fields: graphql.Fields{
+		"id":   groupField(graphql.NewNonNull(graphql.Int), func(g model.Group) any { return valueOrZero(g.ID) }),
+		"name": groupField(graphql.NewNonNull(graphql.String), func(g model.Group) any { return valueOrZero(g.Name) }),
+	},
+})
+
+var songType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Song",
+	Fields: graphql.Fields{
+		"id":          songField(graphql.NewNonNull(graphql.Int), func(s model.Song) any { return valueOrZero(s.ID) }),
+		"songName":    songField(graphql.NewNonNull(graphql.String), func(s model.Song) any { return valueOrZero(s.SongName) }),
+		"group":       songField(graphql.NewNonNull(groupType), func(s model.Song) any { return model.Group{ID: s.GroupId, Name: s.Group} }),
+		"releaseDate": songField(graphql.NewNonNull(graphql.String), func(s model.Song) any { return valueOrZero(s.ReleaseDate) }),
+		"link":        songField(graphql.NewNonNull(graphql.String), func(s model.Song) any { return valueOrZero(s.Link) }),
+		"text":        songField(graphql.NewNonNull(graphql.String), func(s model.Song) any { return valueOrZero(s.Text) }),
+		"version":     songField(graphql.NewNonNull(graphql.Int), func(s model.Song) any { return valueOrZero(s.Version) }),
+	},
+})
+
+var songInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name:        "SongInput",
+	Description: "Изменяемые поля песни; не указанные поля остаются прежними.",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"songName":      {Type: graphql.String},
+		"group":         {Type: graphql.String},
+		"releaseDate":   {Type: graphql.String, Description: "Дата выпуска в формате ДД.ММ.ГГГГ"},
+		"link":          {Type: graphql.String},
+		"text":          {Type: graphql.String},
+		"sectionParser": {Type: graphql.String},
+	},
+})
+
+func init() {
+	songType.AddFieldConfig("verses", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(verseType))),
+		Description: "Куплеты песни по порядку; ordinal выбирает один куплет.",
+		Args:        graphql.FieldConfigArgument{"ordinal": {Type: graphql.Int}},
+		Resolve:     resolveVerses,
+	})
+	groupType.AddFieldConfig("songs", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(songType))),
+		Description: "Песни группы с сортировкой и пагинацией.",
+		Args:        pageArgs(),
+		Resolve:     resolveGroupSongs,
+	})
+}
+
+func newQuery() *graphql.Object {
+	songsArgs := pageArgs()
+	songsArgs["id"] = &graphql.ArgumentConfig{Type: graphql.Int}
+	songsArgs["groupId"] = &graphql.ArgumentConfig{Type: graphql.Int}
+	songsArgs["song"] = &graphql.ArgumentConfig{Type: graphql.String, Description: "Часть названия песни"}
+	songsArgs["group"] = &graphql.ArgumentConfig{Type: graphql.String, Description: "Часть названия группы"}
+	songsArgs["text"] = &graphql.ArgumentConfig{Type: graphql.String, Description: "Часть текста песни"}
+	songsArgs["link"] = &graphql.ArgumentConfig{Type: graphql.String, Description: "Часть ссылки на клип"}
+	songsArgs["date"] = &graphql.ArgumentConfig{Type: graphql.String, Description: "Песни, выпущенные после даты ДД.ММ.ГГГГ"}
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"songs": {
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(songType))),
+				Description: "Список песен с фильтрами GET /info.",
+				Args:        songsArgs,
+				Resolve:     resolveSongs,
+			},
+			"song": {
+				Type:    songType,
+				Args:    graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.Int)}},
+				Resolve: resolveSong,
+			},
+			"groups": {
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(groupType))),
+				Args: graphql.FieldConfigArgument{
+					"id":    {Type: graphql.Int},
+					"name":  {Type: graphql.String, Description: "Часть названия группы"},
+					"page":  {Type: graphql.Int, DefaultValue: 1},
+					"limit": {Type: graphql.Int, DefaultValue: 10, Description: "Не больше 100"},
+				},
+				Resolve: resolveGroups,
+			},
+			"group": {
+				Type:    groupType,
+				Args:    graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.Int)}},
+				Resolve: resolveGroup,
+			},
+		},
+	})
+}
+
+func newMutation() *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"addSong": {
+				Type:        graphql.NewNonNull(songType),
+				Description: "Добавление песни с данными из сервиса информации о песнях. Нужна роль editor.",
+				Args: graphql.FieldConfigArgument{
+					"song":  {Type: graphql.NewNonNull(graphql.String)},
+					"group": {Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: resolveAddSong,
+			},
+			"updateSong": {
+				Type:        graphql.NewNonNull(songType),
+				Description: "Изменение песни, если её версия всё ещё равна version. Нужна роль editor.",
+				Args: graphql.FieldConfigArgument{
+					"id":      {Type: graphql.NewNonNull(graphql.Int)},
+					"version": {Type: graphql.NewNonNull(graphql.Int)},
+					"input":   {Type: graphql.NewNonNull(songInputType)},
+				},
+				Resolve: resolveUpdateSong,
+			},
+			"deleteSong": {
+				Type:        graphql.NewNonNull(graphql.Boolean),
+				Description: "Удаление песни, если её версия всё ещё равна version. Нужна роль admin.",
+				Args: graphql.FieldConfigArgument{
+					"id":      {Type: graphql.NewNonNull(graphql.Int)},
+					"version": {Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: resolveDeleteSong,
+			},
+		},
+	})
+}
+
+func resolveSongs(p graphql.ResolveParams) (any, error) {
+	filter := model.Song{
+		ID:          intArg(p, "id"),
+		GroupId:     intArg(p, "groupId"),
+		SongName:    stringArg(p, "song"),
+		Group:       stringArg(p, "group"),
+		Text:        stringArg(p, "text"),
+		Link:        stringArg(p, "link"),
+		ReleaseDate: stringArg(p, "date"),
+	}
+	page, limit, err := pageArg(p)
+	if err != nil {
+		return nil, err
+	}
+	songs, err := requestFrom(p.Context).services.GetSongs(filter, sortArg(p), page, limit)
+	if err != nil {
+		slog.Error("Ошибка при получении песен", "error", err)
+		return nil, err
+	}
+	return nonNil(songs), nil
+}
+
+func resolveSong(p graphql.ResolveParams) (any, error) {
+	song, err := requestFrom(p.Context).services.GetSong(p.Args["id"].(int))
+	if err != nil {
+		slog.Error("Ошибка при получении песни", "error", err)
+		return nil, err
+	}
+	return song, nil
+}
+
+func resolveGroups(p graphql.ResolveParams) (any, error) {
+	filter := model.Group{ID: intArg(p, "id"), Name: stringArg(p, "name")}
+	page, limit, err := pageArg(p)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := requestFrom(p.Context).services.GetGroups(filter, page, limit)
+	if err != nil {
+		slog.Error("Ошибка при получении групп", "error", err)
+		return nil, err
+	}
+	return nonNil(groups), nil
+}
+
+func resolveGroup(p graphql.ResolveParams) (any, error) {
+	group, err := requestFrom(p.Context).services.GetGroup(p.Args["id"].(int))
+	if err != nil {
+		slog.Error("Ошибка при получении группы", "error", err)
+		return nil, err
+	}
+	return group, nil
+}
+
+func resolveVerses(p graphql.ResolveParams) (any, error) {
+	song := p.Source.(model.Song)
+	load := requestFrom(p.Context).verses.load(valueOrZero(song.ID))
+	ordinal := intArg(p, "ordinal")
+	return func() (any, error) {
+		verses, err := load()
+		if err != nil {
+			slog.Error("Ошибка при получении куплетов", "error", err)
+			return nil, err
+		}
+		if ordinal == nil {
+			return nonNil(verses), nil
+		}
+		if *ordinal < 1 || *ordinal > len(verses) {
+			return []model.Verse{}, nil
+		}
+		return verses[*ordinal-1 : *ordinal], nil
+	}, nil
+}
+
+func resolveGroupSongs(p graphql.ResolveParams) (any, error) {
+	group := p.Source.(model.Group)
+	page, limit, err := pageArg(p)
+	if err != nil {
+		return nil, err
+	}
+	load := requestFrom(p.Context).groupSongs.load(groupSongsKey{
+		groupID: valueOrZero(group.ID),
+		sort:    sortArg(p),
+		page:    page,
+		limit:   limit,
+	})
+	return func() (any, error) {
+		songs, err := load()
+		if err != nil {
+			slog.Error("Ошибка при получении песен группы", "error", err)
+			return nil, err
+		}
+		return nonNil(songs), nil
+	}, nil
+}
+
+func resolveAddSong(p graphql.ResolveParams) (any, error) {
+	services, err := requestFrom(p.Context).as(model.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	id, err := services.Add(p.Args["song"].(string), p.Args["group"].(string))
+	if err != nil {
+		slog.Error("Ошибка при добавлении песни", "error", err)
+		return nil, err
+	}
+	slog.Info("Песня успешно добавлена", "id", id)
+	return services.GetSong(id)
+}
+
+func resolveUpdateSong(p graphql.ResolveParams) (any, error) {
+	services, err := requestFrom(p.Context).as(model.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	input := p.Args["input"].(map[string]any)
+	version := p.Args["version"].(int)
+	song := model.Song{
+		SongName:      stringField(input, "songName"),
+		Group:         stringField(input, "group"),
+		ReleaseDate:   stringField(input, "releaseDate"),
+		Link:          stringField(input, "link"),
+		Text:          stringField(input, "text"),
+		SectionParser: stringField(input, "sectionParser"),
+		Version:       &version,
+	}
+	updated, err := services.UpdateSongByID(p.Args["id"].(int), song)
+	if err != nil {
+		slog.Error("Ошибка при обновлении песни", "error", err)
+		return nil, err
+	}
+	slog.Info("Песня успешно обновлена", "id", valueOrZero(updated.ID), "version", valueOrZero(updated.Version))
+	return updated, nil
+}
+
+func resolveDeleteSong(p graphql.ResolveParams) (any, error) {
+	services, err := requestFrom(p.Context).as(model.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	id, version := p.Args["id"].(int), p.Args["version"].(int)
+	if err := services.DeleteSongByID(id, &version); err != nil {
+		slog.Error("Ошибка при удалении песни", "error", err)
+		return nil, err
+	}
+	slog.Info("Песня успешно удалена", "id", id)
+	return true, nil
+}
+
+func songField(typ graphql.Output, get func(model.Song) any) *graphql.Field {
+	return &graphql.Field{Type: typ, Resolve: func(p graphql.ResolveParams) (any, error) {
+		return get(p.Source.(model.Song)), nil
+	}}
+}
+
+func groupField(typ graphql.Output, get func(model.Group) any) *graphql.Field {
+	return &graphql.Field{Type: typ, Resolve: func(p graphql.ResolveParams) (any, error) {
+		return get(p.Source.(model.Group)), nil
+	}}
+}
+
+func verseField(typ graphql.Output, get func(model.Verse) any) *graphql.Field {
+	return &graphql.Field{Type: typ, Resolve: func(p graphql.ResolveParams) (any, error) {
+		return get(p.Source.(model.Verse)), nil
+	}}
+}
+
+func sortArg(p graphql.ResolveParams) model.SongSort {
+	field, _ := p.Args["sort"].(string)
+	desc, _ := p.Args["desc"].(bool)
+	return model.SongSort{Field: field, Desc: desc}
+}
+
+// pageArg returns the page and limit arguments, checking that page is
+// positive and limit between 1 and maxListLimit.
+func pageArg(p graphql.ResolveParams) (int, int, error) {
+	page, ok := p.Args["page"].(int)
+	if !ok || page < 1 {
+		return 0, 0, model.NewError(model.ErrValidation, "Аргумент page должен быть положительным целым числом").WithField("page")
+	}
+	limit, ok := p.Args["limit"].(int)
+	if !ok || limit < 1 || limit > maxListLimit {
+		return 0, 0, model.NewError(model.ErrValidation, "Аргумент limit должен быть целым числом от 1 до %d", maxListLimit).WithField("limit")
+	}
+	return page, limit, nil
+}
+
+func intArg(p graphql.ResolveParams, name string) *int {
+	if v, ok := p.Args[name].(int); ok {
+		return &v
+	}
+	return nil
+}
+
+func stringArg(p graphql.ResolveParams, name string) *string {
+	if v, ok := p.Args[name].(string); ok {
+		return &v
+	}
+	return nil
+}
+
+func stringField(input map[string]any, name string) *string {
+	if v, ok := input[name].(string); ok {
+		return &v
+	}
+	return nil
+}
+
+// nonNil turns a nil slice into an empty one, as list fields are non-null.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+func valueOrZero[T any](v *T) T {
+	if v == nil {
+		var zero T
+		return zero
+	}
+	return *v
+}
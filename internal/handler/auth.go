@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/Xapsiel/EffectiveMobile/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// principalContextKey is where authenticate stores the caller's
+// model.Principal.
+const principalContextKey = "principal"
+
+// credentialFromRequest reads the API key or access token from
+// "Authorization: Bearer <credential>" or, as a fallback, the X-API-Key header.
+func credentialFromRequest(c *gin.Context) string {
+	if auth := c.GetHeader("Authorization"); auth != "" {
+		scheme, credential, ok := strings.Cut(auth, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(credential)
+		}
+		return ""
+	}
+	return c.GetHeader("X-API-Key")
+}
+
+// authenticate rejects requests without a valid API key or access token.
+// Rejected requests are charged to the bucket of the client's IP address, so
+// guessing credentials is rate limited too.
+func (h *Handler) authenticate(c *gin.Context) {
+	credential := credentialFromRequest(c)
+	if credential == "" {
+		h.unauthorized(c, model.NewError(model.ErrUnauthorized, "Нужен API-ключ или токен доступа"))
+		return
+	}
+	principal, err := h.service.Authenticate(credential)
+	if err != nil {
+		h.unauthorized(c, err)
+		return
+	}
+	c.Set(principalContextKey, principal)
+	c.Next()
+}
+
+// requireRole lets through callers whose role includes role; it must run
+// after authenticate.
+func requireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !principal(c).Allows(role) {
+			newErrorFromErr(c, model.NewError(model.ErrForbidden, "Недостаточно прав: нужна роль %s", role))
+			return
+		}
+		c.Next()
+	}
+}
+
+func principal(c *gin.Context) model.Principal {
+	p, _ := c.Get(principalContextKey)
+	res, _ := p.(model.Principal)
+	return res
+}
+
+// as returns the services acting on behalf of the caller, so that song
+// changes are recorded with who made them.
+func (h *Handler) as(c *gin.Context) service.Service {
+	return h.service.As(principal(c).Actor())
+}
+
+// unauthorized answers for a request that failed authentication, or 429 when
+// the client's IP address ran out of tokens.
+func (h *Handler) unauthorized(c *gin.Context, err error) {
+	if !h.takeToken(c, h.requestLimiter(c)) {
+		return
+	}
+	if errors.Is(err, model.ErrUnauthorized) {
+		c.Header("WWW-Authenticate", `Bearer realm="songs"`)
+	}
+	newErrorFromErr(c, err)
+}
+
+// roleGroups are route groups sharing a path, one per role needed to use the
+// routes registered in it.
+type roleGroups struct {
+	viewer, editor, admin *gin.RouterGroup
+}
+
+func newRoleGroups(g *gin.RouterGroup) roleGroups {
+	return roleGroups{
+		viewer: g.Group("", requireRole(model.RoleViewer)),
+		editor: g.Group("", requireRole(model.RoleEditor)),
+		admin:  g.Group("", requireRole(model.RoleAdmin)),
+	}
+}
+
+func (r roleGroups) group(path string, handlers ...gin.HandlerFunc) roleGroups {
+	return roleGroups{
+		viewer: r.viewer.Group(path, handlers...),
+		editor: r.editor.Group(path, handlers...),
+		admin:  r.admin.Group(path, handlers...),
+	}
+}
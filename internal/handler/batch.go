@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+type batchRequest struct {
+	// Atomic commits all the operations together or none of them.
+	Atomic     bool             `json:"atomic" example:"true"`
+	Operations []batchOperation `json:"operations" binding:"required"`
+}
+
+// batchOperation is a write of a batch. Song is the body the single song
+// endpoint takes: the new song of POST /songs for add, the changed fields of
+// PATCH /songs/{id} for update. Updates and deletes need the song version.
+type batchOperation struct {
+	Op      string          `json:"op" enums:"add,update,delete" example:"update"`
+	ID      int             `json:"id,omitempty" example:"1"`
+	Version *int            `json:"version,omitempty" example:"2"`
+	Song    json.RawMessage `json:"song,omitempty" swaggertype:"object"`
+}
+
+type batchResponse struct {
+	Atomic    bool          `json:"atomic" example:"true"`
+	Committed bool          `json:"committed" example:"true"`
+	Results   []batchResult `json:"results"`
+}
+
+// batchResult is the outcome of an operation: the status and the song the
+// single song endpoint answers with, or the problem it reports.
+type batchResult struct {
+	Op     string         `json:"op" example:"update"`
+	ID     int            `json:"id,omitempty" example:"1"`
+	Status int            `json:"status" example:"200"`
+	Song   any            `json:"song,omitempty"`
+	Error  *errorResponse `json:"error,omitempty"`
+}
+
+// @Summary Пакетное изменение песен
+// @Description Добавление (add), изменение (update) и удаление (delete) песен одним запросом, по порядку. В song передаётся то же тело, что и в POST /v1/songs для добавления или PATCH /v1/songs/{id} для изменения; изменение и удаление требуют версию песни в поле version, удаление — роль admin. С atomic=true операции фиксируются вместе или не фиксируются вовсе: если хотя бы одна завершилась ошибкой, остальные отменяются со статусом 424. Результат каждой операции содержит статус и песню или ошибку, которыми ответил бы соответствующий одиночный запрос
+// @Tags v1
+// @Accept json
+// @Produce json
+// @Param batch body batchRequest true "Операции"
+// @Success 200 {object} batchResponse
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 422 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /v1/songs/batch [post]
+func (h *Handler) SongsBatch(c *gin.Context) {
+	slog.Info("Начало обработки запроса SongsBatch")
+	h.songsBatch(c, songCodecV1)
+}
+
+func (h *Handler) songsBatch(c *gin.Context, v songCodec) {
+	var req batchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	ops := make([]model.BatchOp, len(req.Operations))
+	for i, op := range req.Operations {
+		ops[i] = batchOp(c, v, op)
+	}
+
+	results, committed, err := h.as(c).Batch(ops, req.Atomic)
+	if err != nil {
+		slog.Error("Ошибка при выполнении пакета", "error", err)
+		v.errorFromErr(c, err)
+		return
+	}
+
+	resp := batchResponse{Atomic: req.Atomic, Committed: committed, Results: make([]batchResult, len(results))}
+	failed := 0
+	for i, res := range results {
+		op := req.Operations[i]
+		result := batchResult{Op: op.Op, ID: op.ID}
+		switch {
+		case res.Err != nil:
+			status, detail, fields := describeError(c, v.renameFields(res.Err))
+			problem := newProblem(c, status, detail, c.Request.URL.Path+"#/operations/"+strconv.Itoa(i), fields...)
+			result.Status, result.Error = status, &problem
+			failed++
+		case op.Op == model.BatchAdd:
+			result.ID, result.Status, result.Song = *res.Song.ID, http.StatusCreated, v.encode(res.Song)
+		case op.Op == model.BatchUpdate:
+			result.Status, result.Song = http.StatusOK, v.encode(res.Song)
+		default:
+			result.Status = http.StatusNoContent
+		}
+		resp.Results[i] = result
+	}
+
+	slog.Info("Пакет выполнен", "operations", len(ops), "failed", failed, "atomic", req.Atomic, "committed", committed)
+	c.AbortWithStatusJSON(http.StatusOK, resp)
+}
+
+// batchOp reads an operation of the batch, checking what the single song
+// endpoints check before calling the service: the ID and version of the song
+// and, for deletion, the caller's role.
+func batchOp(c *gin.Context, v songCodec, req batchOperation) model.BatchOp {
+	op := model.BatchOp{Op: req.Op, ID: req.ID, Version: req.Version}
+	switch req.Op {
+	case model.BatchAdd:
+		if len(req.Song) == 0 {
+			op.Err = model.NewError(model.ErrValidation, "Не указана песня").WithField("song")
+			break
+		}
+		name, group, err := v.decodeNewJSON(req.Song)
+		op.Song, op.Err = model.Song{SongName: &name, Group: &group}, err
+	case model.BatchUpdate, model.BatchDelete:
+		switch {
+		case req.ID < 1:
+			op.Err = errParam("id", "ожидается положительное целое число, получено %q", strconv.Itoa(req.ID))
+		case req.Version == nil:
+			op.Err = &statusError{status: http.StatusPreconditionRequired, format: "Укажите версию песни в поле version"}
+		case req.Op == model.BatchDelete && !principal(c).Allows(model.RoleAdmin):
+			op.Err = model.NewError(model.ErrForbidden, "Недостаточно прав: нужна роль %s", model.RoleAdmin)
+		case req.Op == model.BatchUpdate && len(req.Song) == 0:
+			op.Err = model.NewError(model.ErrValidation, "Не указана песня").WithField("song")
+		case req.Op == model.BatchUpdate:
+			op.Song, op.Err = v.decodeJSON(req.Song)
+		}
+	default:
+		op.Err = errParam("op", "ожидается add, update или delete, получено %q", req.Op)
+	}
+	return op
+}
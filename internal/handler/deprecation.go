@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// deprecation describes a retiring route or API version: when it was
+// deprecated, when it stops being served (zero if not scheduled yet) and where
+// clients should go instead.
+type deprecation struct {
+	At        time.Time
+	Sunset    time.Time
+	Successor func(c *gin.Context) string
+}
+
+// legacy covers the unversioned routes superseded by /v1.
+var legacy = deprecation{
+	At:     time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC),
+	Sunset: time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC),
+}
+
+// replacedBy returns d pointing at successor, a route pattern whose path
+// parameters (":id") are filled from the request.
+func (d deprecation) replacedBy(successor string) deprecation {
+	d.Successor = func(c *gin.Context) string {
+		link := successor
+		for _, p := range c.Params {
+			link = strings.ReplaceAll(link, ":"+p.Key, p.Value)
+		}
+		return link
+	}
+	return d
+}
+
+// movedUnder returns d pointing at the same path under prefix.
+func (d deprecation) movedUnder(prefix string) deprecation {
+	d.Successor = func(c *gin.Context) string {
+		return prefix + c.Request.URL.Path
+	}
+	return d
+}
+
+// setHeaders sets the Deprecation (RFC 9745), Sunset (RFC 8594) and successor
+// Link headers.
+func (d deprecation) setHeaders(c *gin.Context) {
+	c.Header("Deprecation", "@"+strconv.FormatInt(d.At.Unix(), 10))
+	if !d.Sunset.IsZero() {
+		c.Header("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.Successor != nil {
+		c.Header("Link", "<"+d.Successor(c)+`>; rel="successor-version"`)
+	}
+}
+
+// deprecated marks the responses of a route with the headers of d.
+func deprecated(d deprecation) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d.setHeaders(c)
+		c.Next()
+	}
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// songV1 is a song as /v1 sends and accepts it. It keeps the field names
+// model.Song had when v1 was published.
+type songV1 struct {
+	ID            *int    `json:"id,omitempty" example:"1"`
+	Group         *string `json:"group_name,omitempty" example:"Muse"`
+	SongName      *string `json:"song_name,omitempty" example:"Supermassive Black Hole"`
+	ReleaseDate   *string `json:"releaseDate,omitempty" example:"19.07.2006"`
+	Link          *string `json:"link,omitempty" example:"https://www.youtube.com/watch?v=Xsp3_a-PMTw"`
+	Text          *string `json:"text,omitempty" example:"Ooh baby, don't you know I suffer?\nOoh baby, can you hear me moan?"`
+	Version       *int    `json:"version,omitempty" example:"1"`
+	SectionParser *string `json:"section_parser,omitempty" example:"labeled"`
+}
+
+func newSongV1(song model.Song) songV1 {
+	return songV1{
+		ID:            song.ID,
+		Group:         song.Group,
+		SongName:      song.SongName,
+		ReleaseDate:   song.ReleaseDate,
+		Link:          song.Link,
+		Text:          song.Text,
+		Version:       song.Version,
+		SectionParser: song.SectionParser,
+	}
+}
+
+func (s songV1) model() (model.Song, error) {
+	return model.Song{
+		ID:            s.ID,
+		Group:         s.Group,
+		SongName:      s.SongName,
+		ReleaseDate:   s.ReleaseDate,
+		Link:          s.Link,
+		Text:          s.Text,
+		Version:       s.Version,
+		SectionParser: s.SectionParser,
+	}, nil
+}
+
+var songCodecV1 = songCodec{
+	prefix: "/v1",
+	encode: func(song model.Song) any { return newSongV1(song) },
+	decode: func(c *gin.Context) (model.Song, error) {
+		var req songV1
+		if err := c.ShouldBindJSON(&req); err != nil {
+			return model.Song{}, err
+		}
+		return req.model()
+	},
+	decodeNew: func(c *gin.Context) (string, string, error) {
+		var req Song
+		if err := c.ShouldBindJSON(&req); err != nil {
+			return "", "", err
+		}
+		return req.SongName, req.Group, nil
+	},
+	decodeJSON: func(data []byte) (model.Song, error) {
+		var req songV1
+		if err := decodeBody(data, &req); err != nil {
+			return model.Song{}, err
+		}
+		return req.model()
+	},
+	decodeNewJSON: func(data []byte) (string, string, error) {
+		var req Song
+		if err := decodeBody(data, &req); err != nil {
+			return "", "", err
+		}
+		return req.SongName, req.Group, nil
+	},
+	decodeDoc: func(data []byte) (model.Song, error) {
+		var doc songV1
+		if err := decodeStrict(data, &doc); err != nil {
+			return model.Song{}, err
+		}
+		return doc.model()
+	},
+	encodeBrief: func(song model.Song) any {
+		song.Text = nil
+		return newSongV1(song)
+	},
+	encodeChanges: func(song model.Song) any { return newSongV1(song) },
+	columns: []songColumn{
+		{name: "id", value: func(s model.Song) string { return intOrEmpty(s.ID) }},
+		{name: "group_name", value: func(s model.Song) string { return valueOrEmpty(s.Group) }},
+		{name: "song_name", value: func(s model.Song) string { return valueOrEmpty(s.SongName) }},
+		{name: "releaseDate", value: func(s model.Song) string { return valueOrEmpty(s.ReleaseDate) }},
+		{name: "link", value: func(s model.Song) string { return valueOrEmpty(s.Link) }},
+		{name: "text", lyrics: true, value: func(s model.Song) string { return valueOrEmpty(s.Text) }},
+		{name: "version", value: func(s model.Song) string { return intOrEmpty(s.Version) }},
+	},
+}
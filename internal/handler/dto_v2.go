@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// modelDateLayout is the release date format of model.Song.
+const modelDateLayout = "02.01.2006"
+
+// songV2 is a song as /v2 sends it: plain values instead of optional fields,
+// "title"/"artist"/"lyrics" naming and ISO 8601 release dates.
+type songV2 struct {
+	ID            int    `json:"id" example:"1"`
+	Title         string `json:"title" example:"Supermassive Black Hole"`
+	Artist        string `json:"artist" example:"Muse"`
+	ReleaseDate   string `json:"release_date" example:"2006-07-19"`
+	Link          string `json:"link" example:"https://www.youtube.com/watch?v=Xsp3_a-PMTw"`
+	Lyrics        string `json:"lyrics" example:"Ooh baby, don't you know I suffer?\nOoh baby, can you hear me moan?"`
+	SectionParser string `json:"section_parser,omitempty" example:"labeled"`
+	Version       int    `json:"version" example:"1"`
+}
+
+// songV2Brief is a songV2 without the lyrics, as exports send it on request.
+type songV2Brief struct {
+	ID            int    `json:"id" example:"1"`
+	Title         string `json:"title" example:"Supermassive Black Hole"`
+	Artist        string `json:"artist" example:"Muse"`
+	ReleaseDate   string `json:"release_date" example:"2006-07-19"`
+	Link          string `json:"link" example:"https://www.youtube.com/watch?v=Xsp3_a-PMTw"`
+	SectionParser string `json:"section_parser,omitempty" example:"labeled"`
+	Version       int    `json:"version" example:"1"`
+}
+
+func newSongV2Brief(song model.Song) songV2Brief {
+	full := newSongV2(song)
+	return songV2Brief{
+		ID:            full.ID,
+		Title:         full.Title,
+		Artist:        full.Artist,
+		ReleaseDate:   full.ReleaseDate,
+		Link:          full.Link,
+		SectionParser: full.SectionParser,
+		Version:       full.Version,
+	}
+}
+
+// songV2Request carries the song fields a /v2 client writes; absent fields are
+// left unchanged by PATCH.
+type songV2Request struct {
+	Title         *string `json:"title,omitempty" example:"Supermassive Black Hole"`
+	Artist        *string `json:"artist,omitempty" example:"Muse"`
+	ReleaseDate   *string `json:"release_date,omitempty" example:"2006-07-19"`
+	Link          *string `json:"link,omitempty" example:"https://www.youtube.com/watch?v=Xsp3_a-PMTw"`
+	Lyrics        *string `json:"lyrics,omitempty" example:"Ooh baby, don't you know I suffer?\nOoh baby, can you hear me moan?"`
+	SectionParser *string `json:"section_parser,omitempty" example:"labeled"`
+	Version       *int    `json:"version,omitempty" example:"1"`
+}
+
+type newSongV2Request struct {
+	Title  string `json:"title" binding:"required" example:"Supermassive Black Hole"`
+	Artist string `json:"artist" binding:"required" example:"Muse"`
+}
+
+func newSongV2(song model.Song) songV2 {
+	res := songV2{
+		Title:         valueOrEmpty(song.SongName),
+		Artist:        valueOrEmpty(song.Group),
+		ReleaseDate:   valueOrEmpty(song.ReleaseDate),
+		Link:          valueOrEmpty(song.Link),
+		Lyrics:        valueOrEmpty(song.Text),
+		SectionParser: valueOrEmpty(song.SectionParser),
+	}
+	if song.ID != nil {
+		res.ID = *song.ID
+	}
+	if song.Version != nil {
+		res.Version = *song.Version
+	}
+	if date, err := time.Parse(modelDateLayout, res.ReleaseDate); err == nil {
+		res.ReleaseDate = date.Format(time.DateOnly)
+	}
+	return res
+}
+
+// songV2Changes is the set fields of a partial song in /v2 naming.
+func songV2Changes(song model.Song) songV2Request {
+	res := songV2Request{
+		Title:         song.SongName,
+		Artist:        song.Group,
+		ReleaseDate:   song.ReleaseDate,
+		Link:          song.Link,
+		Lyrics:        song.Text,
+		SectionParser: song.SectionParser,
+		Version:       song.Version,
+	}
+	if song.ReleaseDate != nil {
+		if date, err := time.Parse(modelDateLayout, *song.ReleaseDate); err == nil {
+			formatted := date.Format(time.DateOnly)
+			res.ReleaseDate = &formatted
+		}
+	}
+	return res
+}
+
+func (r songV2Request) model() (model.Song, error) {
+	song := model.Song{
+		SongName:      r.Title,
+		Group:         r.Artist,
+		Link:          r.Link,
+		Text:          r.Lyrics,
+		Version:       r.Version,
+		SectionParser: r.SectionParser,
+	}
+	if r.ReleaseDate != nil {
+		date, err := time.Parse(time.DateOnly, *r.ReleaseDate)
+		if err != nil {
+			return model.Song{}, model.NewError(model.ErrValidation, "Некорректная дата выпуска %q, ожидается формат ГГГГ-ММ-ДД", *r.ReleaseDate).WithField("release_date")
+		}
+		formatted := date.Format(modelDateLayout)
+		song.ReleaseDate = &formatted
+	}
+	return song, nil
+}
+
+// model turns a complete /v2 song back into the service model. Zero ID and
+// version mean they were left out.
+func (s songV2) model() (model.Song, error) {
+	song, err := songV2Request{
+		Title:         &s.Title,
+		Artist:        &s.Artist,
+		ReleaseDate:   &s.ReleaseDate,
+		Link:          &s.Link,
+		Lyrics:        &s.Lyrics,
+		SectionParser: &s.SectionParser,
+	}.model()
+	if err != nil {
+		return model.Song{}, err
+	}
+	if s.ID != 0 {
+		song.ID = &s.ID
+	}
+	if s.Version != 0 {
+		song.Version = &s.Version
+	}
+	return song, nil
+}
+
+var songCodecV2 = songCodec{
+	prefix: "/v2",
+	encode: func(song model.Song) any { return newSongV2(song) },
+	decode: func(c *gin.Context) (model.Song, error) {
+		var req songV2Request
+		if err := c.ShouldBindJSON(&req); err != nil {
+			return model.Song{}, err
+		}
+		return req.model()
+	},
+	decodeNew: func(c *gin.Context) (string, string, error) {
+		var req newSongV2Request
+		if err := c.ShouldBindJSON(&req); err != nil {
+			return "", "", err
+		}
+		return req.Title, req.Artist, nil
+	},
+	decodeJSON: func(data []byte) (model.Song, error) {
+		var req songV2Request
+		if err := decodeBody(data, &req); err != nil {
+			return model.Song{}, err
+		}
+		return req.model()
+	},
+	decodeNewJSON: func(data []byte) (string, string, error) {
+		var req newSongV2Request
+		if err := decodeBody(data, &req); err != nil {
+			return "", "", err
+		}
+		return req.Title, req.Artist, nil
+	},
+	decodeDoc: func(data []byte) (model.Song, error) {
+		var doc songV2
+		if err := decodeStrict(data, &doc); err != nil {
+			return model.Song{}, err
+		}
+		return doc.model()
+	},
+	encodeBrief: func(song model.Song) any { return newSongV2Brief(song) },
+	encodeChanges: func(song model.Song) any { return songV2Changes(song) },
+	columns: []songColumn{
+		{name: "id", value: func(s model.Song) string { return intOrEmpty(s.ID) }},
+		{name: "artist", value: func(s model.Song) string { return valueOrEmpty(s.Group) }},
+		{name: "title", value: func(s model.Song) string { return valueOrEmpty(s.SongName) }},
+		{name: "release_date", value: func(s model.Song) string { return newSongV2(s).ReleaseDate }},
+		{name: "link", value: func(s model.Song) string { return valueOrEmpty(s.Link) }},
+		{name: "lyrics", lyrics: true, value: func(s model.Song) string { return valueOrEmpty(s.Text) }},
+		{name: "version", value: func(s model.Song) string { return intOrEmpty(s.Version) }},
+	},
+	fields: map[string]string{
+		"song_name":   "title",
+		"group_name":  "artist",
+		"releaseDate": "release_date",
+		"text":        "lyrics",
+	},
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setETag exposes the song version as a strong entity tag.
+func setETag(c *gin.Context, version *int) {
+	if version == nil {
+		return
+	}
+	c.Header("ETag", strconv.Quote(strconv.Itoa(*version)))
+}
+
+// expectedVersion resolves the song version a write is conditioned on. The
+// If-Match header wins over the version field of the body; "If-Match: *" makes
+// the write unconditional. ok is false when the client sent neither.
+func expectedVersion(c *gin.Context, bodyVersion *int) (version *int, ok bool, err error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		return bodyVersion, bodyVersion != nil, nil
+	}
+	if header == "*" {
+		return nil, true, nil
+	}
+	tag, err := strconv.Unquote(header)
+	if err != nil {
+		return nil, false, errParam("If-Match", "ожидается версия в кавычках или *, получено %s", header)
+	}
+	v, err := strconv.Atoi(tag)
+	if err != nil {
+		return nil, false, errParam("If-Match", "ожидается версия в кавычках или *, получено %s", header)
+	}
+	return &v, true, nil
+}
+
+// requireVersion is expectedVersion for handlers: it answers 400 for a broken
+// If-Match header and 428 when the client gave no version at all.
+func requireVersion(c *gin.Context, bodyVersion *int) (*int, bool) {
+	version, ok, err := expectedVersion(c, bodyVersion)
+	if err != nil {
+		newErrorFromErr(c, err)
+		return nil, false
+	}
+	if !ok {
+		newErrorResponce(c, http.StatusPreconditionRequired, "Укажите версию песни в заголовке If-Match или в поле version")
+		return nil, false
+	}
+	return version, true
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// songEventsBatch is the number of events read from the log at once.
+	songEventsBatch = 100
+	// songEventsHeartbeat is how often an idle stream sends a comment, so that
+	// proxies keep it open and a broken notification channel only delays events.
+	songEventsHeartbeat = 15 * time.Second
+	// songEventsRetry is the reconnection delay suggested to clients, in ms.
+	songEventsRetry = 3000
+)
+
+// songEventResponse is the data of a song event. Changes has the song fields
+// the change set, named as in the song format of the API version.
+type songEventResponse struct {
+	ID      int64     `json:"id" example:"42"`
+	Type    string    `json:"type" example:"updated"`
+	SongID  int       `json:"song_id" example:"1"`
+	GroupID int       `json:"group_id" example:"1"`
+	Version int       `json:"version" example:"2"`
+	Actor   *string   `json:"actor,omitempty" example:"user:editor"`
+	At      time.Time `json:"at" example:"2026-10-19T12:00:00Z"`
+	Changes any       `json:"changes"`
+}
+
+// @Summary Лента изменений песен
+// @Description Поток Server-Sent Events о добавлении (song.created), изменении (song.updated) и удалении (song.deleted) песен с изменёнными полями. События хранятся в журнале: после переподключения с Last-Event-ID поток продолжается с пропущенных событий, без него — с новых
+// @Tags v1
+// @Produce text/event-stream
+// @Param Last-Event-ID header int false "ID последнего полученного события"
+// @Param last_event_id query int false "ID последнего полученного события, если заголовок задать нельзя"
+// @Param group_id query int false "ID группы"
+// @Param group query string false "Группа"
+// @Success 200 {object} songEventResponse
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /v1/songs/events [get]
+func (h *Handler) SongEvents(c *gin.Context) {
+	slog.Info("Начало обработки запроса SongEvents")
+	h.songEvents(c, songCodecV1)
+}
+
+func (h *Handler) songEvents(c *gin.Context, v songCodec) {
+	var filter model.SongEventFilter
+	if value := c.Query("group_id"); value != "" {
+		groupID, err := strconv.Atoi(value)
+		if err != nil {
+			newErrorFromErr(c, errParam("group_id", "ожидается целое число, получено %q", value))
+			return
+		}
+		filter.GroupID = &groupID
+	}
+	if group := c.Query("group"); group != "" {
+		filter.Group = &group
+	}
+
+	last, param := c.GetHeader("Last-Event-ID"), "Last-Event-ID"
+	if last == "" {
+		last, param = c.Query("last_event_id"), "last_event_id"
+	}
+	var after int64
+	var err error
+	if last != "" {
+		after, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || after < 0 {
+			newErrorFromErr(c, errParam(param, "ожидается положительное целое число, получено %q", last))
+			return
+		}
+	} else if after, err = h.service.LastSongEventID(); err != nil {
+		slog.Error("Ошибка при получении последнего события", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+
+	wake, unsubscribe := h.service.SubscribeSongEvents()
+	defer unsubscribe()
+
+	// The stream outlives the server's write timeout.
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		slog.Warn("Не удалось снять ограничение времени записи", "error", err)
+	}
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", songEventsRetry)
+	c.Writer.Flush()
+
+	slog.Info("Подписка на события песен", "after", after, "filter", filter)
+	heartbeat := time.NewTicker(songEventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		events, err := h.service.SongEvents(after, filter, songEventsBatch)
+		if err != nil {
+			// The status is already sent; the client reconnects and resumes.
+			slog.Error("Ошибка при чтении событий песен", "error", err)
+			return
+		}
+		for _, event := range events {
+			if err := writeSongEvent(c, v, event); err != nil {
+				slog.Info("Клиент отключился от ленты событий", "error", err)
+				return
+			}
+			after = event.ID
+		}
+		c.Writer.Flush()
+		if len(events) == songEventsBatch {
+			continue
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			slog.Info("Клиент отключился от ленты событий", "last_event_id", after)
+			return
+		case <-wake:
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+func writeSongEvent(c *gin.Context, v songCodec, event model.SongEvent) error {
+	data, err := json.Marshal(songEventResponse{
+		ID:      event.ID,
+		Type:    event.Type,
+		SongID:  event.SongID,
+		GroupID: event.GroupID,
+		Version: event.Version,
+		Actor:   event.Actor,
+		At:      event.At,
+		Changes: v.encodeChanges(event.Changes),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.Writer, "id: %d\nevent: song.%s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
@@ -0,0 +1,267 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// Export file formats.
+const (
+	exportNDJSON = "ndjson"
+	exportCSV    = "csv"
+	exportJSON   = "json"
+)
+
+// exportFlushEvery is the number of songs written between flushes to the
+// client.
+const exportFlushEvery = 100
+
+// songColumn is a CSV column of an exported song.
+type songColumn struct {
+	name string
+	// lyrics marks the column left out when the export excludes lyrics.
+	lyrics bool
+	value  func(model.Song) string
+}
+
+func intOrEmpty(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+// songExporter writes exported songs in one file format.
+type songExporter interface {
+	write(song model.Song) error
+	// flush pushes buffered songs to the underlying writer.
+	flush() error
+	// close completes the file, also when no song was written.
+	close() error
+}
+
+type ndjsonExporter struct {
+	enc    *json.Encoder
+	encode func(model.Song) any
+}
+
+func (e *ndjsonExporter) write(song model.Song) error { return e.enc.Encode(e.encode(song)) }
+func (e *ndjsonExporter) flush() error                { return nil }
+func (e *ndjsonExporter) close() error                { return nil }
+
+type jsonExporter struct {
+	w      io.Writer
+	encode func(model.Song) any
+	n      int
+}
+
+func (e *jsonExporter) write(song model.Song) error {
+	data, err := json.Marshal(e.encode(song))
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if e.n == 0 {
+		sep = "[\n"
+	}
+	e.n++
+	if _, err := io.WriteString(e.w, sep); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonExporter) flush() error { return nil }
+
+func (e *jsonExporter) close() error {
+	end := "\n]\n"
+	if e.n == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+type csvExporter struct {
+	w       *csv.Writer
+	columns []songColumn
+	header  bool
+}
+
+func (e *csvExporter) writeHeader() error {
+	if e.header {
+		return nil
+	}
+	e.header = true
+	names := make([]string, len(e.columns))
+	for i, col := range e.columns {
+		names[i] = col.name
+	}
+	return e.w.Write(names)
+}
+
+func (e *csvExporter) write(song model.Song) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	record := make([]string, len(e.columns))
+	for i, col := range e.columns {
+		record[i] = col.value(song)
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvExporter) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExporter) close() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	return e.flush()
+}
+
+// exportResponseWriter sends the export headers with the first byte written,
+// so that an error raised before any song is exported still gets a regular
+// error response.
+type exportResponseWriter struct {
+	c           *gin.Context
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (w *exportResponseWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+	w.c.Header("Content-Type", w.contentType)
+	w.c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", w.filename))
+	w.c.Status(http.StatusOK)
+}
+
+func (w *exportResponseWriter) Write(p []byte) (int, error) {
+	w.start()
+	return w.c.Writer.Write(p)
+}
+
+// newSongExporter returns the exporter of the given format writing to w, or
+// nil for an unknown format.
+func newSongExporter(format string, w *exportResponseWriter, v songCodec, withText bool) songExporter {
+	encode := v.encode
+	if !withText {
+		encode = v.encodeBrief
+	}
+	w.filename = "songs." + format
+	switch format {
+	case exportNDJSON:
+		w.contentType = "application/x-ndjson"
+		return &ndjsonExporter{enc: json.NewEncoder(w), encode: encode}
+	case exportJSON:
+		w.contentType = "application/json; charset=utf-8"
+		return &jsonExporter{w: w, encode: encode}
+	case exportCSV:
+		w.contentType = "text/csv; charset=utf-8"
+		columns := make([]songColumn, 0, len(v.columns))
+		for _, col := range v.columns {
+			if withText || !col.lyrics {
+				columns = append(columns, col)
+			}
+		}
+		return &csvExporter{w: csv.NewWriter(w), columns: columns}
+	}
+	return nil
+}
+
+// @Summary Экспорт песен
+// @Description Выгрузка всех песен, подходящих под фильтры (те же, что у /info), в формате NDJSON, CSV или JSON. Песни передаются потоком в порядке ID, поэтому выгрузка не зависит от размера каталога. При lyrics=false тексты песен не выгружаются
+// @Tags v1
+// @Produce json,text/csv,application/x-ndjson
+// @Param format query string false "Формат выгрузки" Enums(ndjson, csv, json) default(ndjson)
+// @Param lyrics query bool false "Выгружать тексты песен" default(true)
+// @Param id query int false "ID песни"
+// @Param group_id query int false "ID группы"
+// @Param song query string false "Название песни"
+// @Param group query string false "Группа"
+// @Param link query string false "Ссылка на клип"
+// @Param text query string false "Фрагмент текста песни"
+// @Param date query string false "Выпущены после даты" example(19.07.2006)
+// @Success 200 {array} songV1
+// @Header 200 {string} Content-Disposition "Имя файла выгрузки"
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /v1/songs/export [get]
+func (h *Handler) ExportSongs(c *gin.Context) {
+	slog.Info("Начало обработки запроса ExportSongs")
+	h.exportSongs(c, songCodecV1)
+}
+
+func (h *Handler) exportSongs(c *gin.Context, v songCodec) {
+	filter, ok := songQueryFilter(c)
+	if !ok {
+		return
+	}
+	withText, err := strconv.ParseBool(c.DefaultQuery("lyrics", "true"))
+	if err != nil {
+		newErrorFromErr(c, errParam("lyrics", "ожидается true или false, получено %q", c.Query("lyrics")))
+		return
+	}
+	format := c.DefaultQuery("format", exportNDJSON)
+	w := &exportResponseWriter{c: c}
+	exporter := newSongExporter(format, w, v, withText)
+	if exporter == nil {
+		newErrorFromErr(c, errParam("format", "допустимы %s, %s и %s, получено %q", exportNDJSON, exportCSV, exportJSON, format))
+		return
+	}
+
+	slog.Debug("Параметры выгрузки", "filter", filter, "format", format, "lyrics", withText)
+
+	count := 0
+	err = h.service.ExportSongs(filter, withText, func(song model.Song) error {
+		if err := exporter.write(song); err != nil {
+			return err
+		}
+		count++
+		if count%exportFlushEvery == 0 {
+			if err := exporter.flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = exporter.close()
+	}
+	if err != nil {
+		slog.Error("Ошибка при выгрузке песен", "error", err, "exported", count)
+		if !w.started {
+			newErrorFromErr(c, err)
+			return
+		}
+		// The status is already sent; the client sees a truncated file.
+		c.Abort()
+		return
+	}
+	w.start()
+	c.Writer.Flush()
+
+	slog.Info("Песни успешно выгружены", "format", format, "count", count)
+	c.Abort()
+}
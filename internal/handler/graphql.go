@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/graph"
+	"github.com/gin-gonic/gin"
+)
+
+type graphQLRequest struct {
+	Query         string         `json:"query" binding:"required" example:"{ groups(name: \"Muse\") { name songs(limit: 5) { songName verses(ordinal: 1) { text } } } }"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// graphQLResponse documents the GraphQL result: errors carry extensions.code
+// and extensions.status, the HTTP status the REST endpoints answer with.
+type graphQLResponse struct {
+	Data   any   `json:"data,omitempty"`
+	Errors []any `json:"errors,omitempty"`
+}
+
+// @Summary GraphQL
+// @Description Выполнение запроса GraphQL по песням, группам и куплетам. Запрос через GET не может содержать мутаций. Запросы без мутаций расходуют ведро чтения ограничения частоты, мутации — ведро записи. Ошибки выполнения возвращаются со статусом 200 в поле errors
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Param request body graphQLRequest true "Запрос GraphQL"
+// @Success 200 {object} graphQLResponse
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 422 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /graphql [post]
+func (h *Handler) GraphQL(c *gin.Context) {
+	slog.Info("Начало обработки запроса GraphQL")
+
+	var req graphQLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		if h.takeToken(c, h.limits.read) {
+			bindError(c, err)
+		}
+		return
+	}
+	// Queries are charged like GET requests, mutations like writes.
+	limiter := h.limits.read
+	if graph.Mutates(req.Query, req.OperationName) {
+		limiter = h.limits.write
+	}
+	if !h.takeToken(c, limiter) {
+		return
+	}
+	h.graphQL(c, req, false)
+}
+
+// @Summary GraphQL (чтение)
+// @Description Выполнение запроса GraphQL без мутаций, параметры передаются в строке запроса
+// @Tags graphql
+// @Produce json
+// @Param query query string true "Запрос GraphQL"
+// @Param operationName query string false "Имя операции"
+// @Param variables query string false "Переменные в формате JSON"
+// @Success 200 {object} graphQLResponse
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /graphql [get]
+func (h *Handler) GraphQLQuery(c *gin.Context) {
+	slog.Info("Начало обработки запроса GraphQLQuery")
+
+	req := graphQLRequest{Query: c.Query("query"), OperationName: c.Query("operationName")}
+	if req.Query == "" {
+		newErrorFromErr(c, errParam("query", "Обязательное поле"))
+		return
+	}
+	if variables := c.Query("variables"); variables != "" {
+		if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+			slog.Error("Ошибка при парсинге variables", "error", err)
+			newErrorFromErr(c, errParam("variables", "ожидается JSON-объект, получено %q", variables))
+			return
+		}
+	}
+	h.graphQL(c, req, true)
+}
+
+func (h *Handler) graphQL(c *gin.Context, req graphQLRequest, readOnly bool) {
+	res := h.graph.Do(c.Request.Context(), graph.Call{
+		Query:         req.Query,
+		OperationName: req.OperationName,
+		Variables:     req.Variables,
+		Caller:        principal(c),
+		ReadOnly:      readOnly,
+	}, printer(c))
+
+	slog.Info("Запрос GraphQL выполнен", "errors", len(res.Errors))
+	c.AbortWithStatusJSON(http.StatusOK, res)
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/graph"
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/Xapsiel/EffectiveMobile/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type fakeGraphSongs struct {
+	service.Song
+}
+
+func (fakeGraphSongs) GetSongs(model.Song, model.SongSort, int, int) ([]model.Song, error) {
+	return nil, nil
+}
+
+type fakeGraphVerses struct {
+	service.Verse
+}
+
+func (fakeGraphVerses) GetSongsVerses([]int) (map[int][]model.Verse, error) {
+	return nil, nil
+}
+
+func TestGraphQLRateLimitBucket(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	services := service.Service{Song: fakeGraphSongs{}, Verse: fakeGraphVerses{}}
+	schema, err := graph.New(services)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{
+		service: services,
+		graph:   schema,
+		limits:  rateLimits{read: newRateLimiter(0.001, 2), write: newRateLimiter(0.001, 1)},
+	}
+	router := gin.New()
+	router.POST("/graphql", h.rateLimit, h.GraphQL)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+		return w
+	}
+	steps := []struct {
+		body       string
+		wantStatus int
+		wantLimit  string
+	}{
+		{body: `{"query": "{ songs { id } }"}`, wantStatus: http.StatusOK, wantLimit: "2"},
+		{body: `{"query": "mutation { deleteSong(id: 1, version: 1) }"}`, wantStatus: http.StatusOK, wantLimit: "1"},
+		{body: `{"query": "query Q { songs { id } }", "operationName": "Q"}`, wantStatus: http.StatusOK, wantLimit: "2"},
+		// Both buckets are empty now.
+		{body: `{"query": "{ songs { id } }"}`, wantStatus: http.StatusTooManyRequests, wantLimit: "2"},
+		{body: `{"query": "mutation { deleteSong(id: 1, version: 1) }"}`, wantStatus: http.StatusTooManyRequests, wantLimit: "1"},
+	}
+	for i, step := range steps {
+		w := post(step.body)
+		if w.Code != step.wantStatus {
+			t.Fatalf("step %d: status = %d, want %d: %s", i, w.Code, step.wantStatus, w.Body)
+		}
+		if got := w.Header().Get("RateLimit-Limit"); got != step.wantLimit {
+			t.Errorf("step %d: RateLimit-Limit = %q, want %q", i, got, step.wantLimit)
+		}
+	}
+}
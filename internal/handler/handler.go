@@ -1,31 +1,96 @@
 package handler
 
 import (
+	"log/slog"
+	"net/http"
+
 	_ "github.com/Xapsiel/EffectiveMobile/docs"
+	"github.com/Xapsiel/EffectiveMobile/internal/config"
+	"github.com/Xapsiel/EffectiveMobile/internal/graph"
+	"github.com/Xapsiel/EffectiveMobile/internal/i18n"
 	"github.com/Xapsiel/EffectiveMobile/internal/service"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
 type Handler struct {
-	service service.Service
+	service        service.Service
+	graph          *graph.Schema
+	limits         rateLimits
+	trustedProxies []string
+	locales        *i18n.Localizer
 }
 
-func NewHandler(service service.Service) *Handler {
-	return &Handler{service: service}
+func NewHandler(service service.Service, schema *graph.Schema, limits config.RateLimitConfig, locales *i18n.Localizer) *Handler {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(jsonFieldName)
+	}
+	return &Handler{service: service, graph: schema, limits: newRateLimits(limits), trustedProxies: limits.TrustedProxies, locales: locales}
 }
 
 func (h *Handler) InitRoutes() *gin.Engine {
 	router := gin.New()
-	router.Use(gin.Recovery())
+	if err := router.SetTrustedProxies(h.trustedProxies); err != nil {
+		slog.Error("Некорректный список доверенных прокси", "error", err)
+	}
+	router.Use(gin.CustomRecovery(func(c *gin.Context, err any) {
+		slog.Error("Паника при обработке запроса", "error", err)
+		newErrorResponce(c, http.StatusInternalServerError, "Не удалось обработать запрос")
+	}))
 	router.Use(gin.Logger())
+	router.Use(h.localize)
+	router.NoRoute(func(c *gin.Context) {
+		newErrorResponce(c, http.StatusNotFound, "Маршрут %s %s не существует", c.Request.Method, c.Request.URL.Path)
+	})
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-	router.GET("/info", h.GetSongs)
-	router.POST("/songs", h.AddSong)
-	router.GET("/info/verse", h.GetSongVerse)
-	router.DELETE("/songs", h.DeleteSong)
-	router.PUT("/songs", h.UpdateSong)
+	router.GET(problemTypesPath+":code", h.rateLimit, h.GetProblemType)
+	auth := router.Group("/auth", h.rateLimit)
+	{
+		auth.POST("/register", h.Register)
+		auth.POST("/login", h.Login)
+		auth.POST("/refresh", h.Refresh)
+		auth.POST("/logout", h.Logout)
+	}
+
+	api := newRoleGroups(router.Group("", h.authenticate, h.rateLimit))
+	api.viewer.GET("/auth/me", h.Me)
+	api.admin.GET("/users", h.ListUsers)
+	api.admin.PUT("/users/:id/role", h.SetUserRole)
+	api.admin.POST("/webhooks", h.CreateWebhook)
+	api.admin.GET("/webhooks", h.ListWebhooks)
+	api.admin.GET("/webhooks/:id", h.GetWebhook)
+	api.admin.PUT("/webhooks/:id", h.UpdateWebhook)
+	api.admin.DELETE("/webhooks/:id", h.DeleteWebhook)
+	api.admin.GET("/webhooks/:id/deliveries", h.GetWebhookDeliveries)
+	api.admin.POST("/webhooks/:id/deliveries/:delivery/retry", h.RetryWebhookDelivery)
+	api.viewer.GET("/graphql", h.GraphQLQuery)
+	api.viewer.POST("/graphql", h.GraphQL)
+
+	legacySongs := legacy.replacedBy("/v1/songs")
+	api.viewer.GET("/info", deprecated(legacySongs), h.GetSongs)
+	api.editor.POST("/songs", deprecated(legacySongs), h.AddSong)
+	api.viewer.GET("/info/verse", deprecated(legacySongs), h.GetSongVerse)
+	api.viewer.GET("/info/verse/search", deprecated(legacySongs), h.FindPhrase)
+	api.admin.DELETE("/songs", deprecated(legacySongs), h.DeleteSong)
+	api.editor.PUT("/songs", deprecated(legacySongs), h.UpdateSong)
+
+	api.viewer.GET("/songs/:id/sections", deprecated(legacy.movedUnder("/v1")), h.GetSections)
+
+	verses := api.group("/songs/:id/verses", deprecated(legacy.movedUnder("/v1")))
+	{
+		verses.viewer.GET("", h.GetVerses)
+		verses.editor.POST("", h.InsertVerse)
+		verses.editor.PUT("/order", h.ReorderVerses)
+		verses.editor.PUT("/:n", h.UpdateVerse)
+		verses.editor.DELETE("/:n", h.DeleteVerse)
+	}
+
+	for _, v := range apiVersions() {
+		v.routes(h, api.group("/"+v.name, versionHeaders(v)))
+	}
 	return router
 }
@@ -0,0 +1,407 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// Import file formats.
+const (
+	importCSV    = "csv"
+	importNDJSON = "ndjson"
+)
+
+const (
+	maxImportSize = 32 << 20
+	maxImportRows = 10000
+	maxNDJSONLine = 1 << 20
+)
+
+// Song fields an import row can fill.
+const (
+	importGroup = "group"
+	importSong  = "song"
+	importDate  = "date"
+	importText  = "text"
+	importLink  = "link"
+)
+
+// defaultImportColumns lists the source columns (CSV header or NDJSON key)
+// tried for each field when no mapping is given.
+var defaultImportColumns = map[string][]string{
+	importGroup: {"group", "group_name", "artist"},
+	importSong:  {"song", "song_name", "title"},
+	importDate:  {"date", "releaseDate", "release_date"},
+	importText:  {"text", "lyrics"},
+	importLink:  {"link"},
+}
+
+type importResponse struct {
+	Status  string               `json:"status" example:"success"`
+	DryRun  bool                 `json:"dry_run" example:"false"`
+	Total   int                  `json:"total" example:"3"`
+	Created int                  `json:"created" example:"1"`
+	Updated int                  `json:"updated" example:"0"`
+	Skipped int                  `json:"skipped" example:"1"`
+	Failed  int                  `json:"failed" example:"1"`
+	Rows    []model.ImportResult `json:"rows"`
+	// Error is why the import stopped before the end of the file; the rows
+	// before that are imported all the same.
+	Error *errorResponse `json:"error,omitempty"`
+}
+
+// importRecord is one raw row of an import file keyed by source column.
+type importRecord struct {
+	line   int
+	values map[string]string
+	err    error
+}
+
+// importReader yields the records of an import file; it returns io.EOF after
+// the last one.
+type importReader interface {
+	Next() (importRecord, error)
+}
+
+type csvImportReader struct {
+	r      *csv.Reader
+	header []string
+}
+
+func newCSVImportReader(r io.Reader, delimiter rune) (*csvImportReader, error) {
+	cr := csv.NewReader(r)
+	cr.Comma = delimiter
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, importReadError(err, "Не удалось прочитать заголовок CSV: %v")
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+	return &csvImportReader{r: cr, header: header}, nil
+}
+
+func (r *csvImportReader) Next() (importRecord, error) {
+	fields, err := r.r.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return importRecord{line: parseErr.StartLine, err: model.NewError(model.ErrValidation, "Ошибка разбора CSV: %v", parseErr.Err)}, nil
+	}
+	if err != nil {
+		return importRecord{}, err
+	}
+	line, _ := r.r.FieldPos(0)
+	values := make(map[string]string, len(fields))
+	for i, field := range fields {
+		if i < len(r.header) && field != "" {
+			values[r.header[i]] = field
+		}
+	}
+	return importRecord{line: line, values: values}, nil
+}
+
+type ndjsonImportReader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+func newNDJSONImportReader(r io.Reader) *ndjsonImportReader {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), maxNDJSONLine)
+	return &ndjsonImportReader{s: s}
+}
+
+func (r *ndjsonImportReader) Next() (importRecord, error) {
+	for r.s.Scan() {
+		r.line++
+		text := strings.TrimSpace(r.s.Text())
+		if text == "" {
+			continue
+		}
+		var raw map[string]any
+		if err := json.Unmarshal([]byte(text), &raw); err != nil {
+			return importRecord{line: r.line, err: model.NewError(model.ErrValidation, "Ошибка разбора JSON: %v", err)}, nil
+		}
+		values := make(map[string]string, len(raw))
+		for key, value := range raw {
+			switch v := value.(type) {
+			case nil:
+			case string:
+				values[key] = v
+			default:
+				values[key] = fmt.Sprint(v)
+			}
+		}
+		return importRecord{line: r.line, values: values}, nil
+	}
+	if err := r.s.Err(); err != nil {
+		return importRecord{}, err
+	}
+	return importRecord{}, io.EOF
+}
+
+// parseImportMapping reads "field=column" pairs separated by commas, e.g.
+// "group=Artist,song=Title". Fields not mentioned keep their default columns.
+func parseImportMapping(raw string) (map[string][]string, error) {
+	mapping := make(map[string][]string, len(defaultImportColumns))
+	for field, columns := range defaultImportColumns {
+		mapping[field] = columns
+	}
+	if strings.TrimSpace(raw) == "" {
+		return mapping, nil
+	}
+	for _, pair := range strings.Split(raw, ",") {
+		field, column, ok := strings.Cut(pair, "=")
+		field, column = strings.TrimSpace(field), strings.TrimSpace(column)
+		if _, known := defaultImportColumns[field]; !ok || !known || column == "" {
+			return nil, errParam("mapping", "ожидается поле=столбец, где поле одно из group, song, date, text, link, получено %q", pair)
+		}
+		mapping[field] = []string{column}
+	}
+	return mapping, nil
+}
+
+// importRow maps a record onto a song. Release dates may be given either as
+// ДД.ММ.ГГГГ or as ISO 8601.
+func importRow(rec importRecord, mapping map[string][]string) model.ImportRow {
+	row := model.ImportRow{Line: rec.line, Err: rec.err}
+	if rec.err != nil {
+		return row
+	}
+	get := func(field string) *string {
+		for _, column := range mapping[field] {
+			if value, ok := rec.values[column]; ok {
+				return &value
+			}
+		}
+		return nil
+	}
+	row.Song = model.Song{
+		Group:       get(importGroup),
+		SongName:    get(importSong),
+		ReleaseDate: get(importDate),
+		Text:        get(importText),
+		Link:        get(importLink),
+	}
+	if date := row.Song.ReleaseDate; date != nil {
+		if t, err := time.Parse(time.DateOnly, strings.TrimSpace(*date)); err == nil {
+			formatted := t.Format(modelDateLayout)
+			row.Song.ReleaseDate = &formatted
+		}
+	}
+	return row
+}
+
+// importReadError reports a failure to read the import file: 413 when the
+// request body outgrew maxImportSize, 400 described by format otherwise.
+func importReadError(err error, format string) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return &statusError{status: http.StatusRequestEntityTooLarge, format: "Файл импорта больше %d байт", args: []any{maxErr.Limit}}
+	}
+	return errRequest(format, err)
+}
+
+// importSource opens the uploaded file: the "file" part of a multipart form or
+// the request body itself. The form is read as a stream, so the file is never
+// buffered whole. The format comes from the format parameter, the file
+// extension or the content type.
+func importSource(c *gin.Context) (io.ReadCloser, string, error) {
+	format := strings.ToLower(c.Query("format"))
+	if c.ContentType() == "multipart/form-data" {
+		form, err := c.Request.MultipartReader()
+		if err != nil {
+			return nil, "", errRequest("Не удалось прочитать форму: %v", err)
+		}
+		for {
+			part, err := form.NextPart()
+			if errors.Is(err, io.EOF) {
+				return nil, "", errRequest("В форме нет файла file")
+			}
+			if err != nil {
+				return nil, "", importReadError(err, "Не удалось прочитать форму: %v")
+			}
+			if part.FormName() != "file" {
+				part.Close()
+				continue
+			}
+			if format == "" {
+				format = importFormat(path.Ext(part.FileName()), part.Header.Get("Content-Type"))
+			}
+			return part, format, nil
+		}
+	}
+	if format == "" {
+		format = importFormat("", c.ContentType())
+	}
+	return c.Request.Body, format, nil
+}
+
+func importFormat(ext, contentType string) string {
+	switch {
+	case ext == ".csv", contentType == "text/csv":
+		return importCSV
+	case ext == ".ndjson", ext == ".jsonl",
+		contentType == "application/x-ndjson", contentType == "application/ndjson", contentType == "application/jsonl":
+		return importNDJSON
+	}
+	return ""
+}
+
+// @Summary Импорт песен
+// @Description Массовое добавление песен из CSV (первая строка — заголовок) или NDJSON. Файл передаётся частью file формы multipart/form-data или телом запроса. Поля: group, song, date (ДД.ММ.ГГГГ или ГГГГ-ММ-ДД), text, link; mapping задаёт соответствие полей колонкам. Для каждой строки возвращается результат: created, updated, skipped или failed с причиной
+// @Tags import
+// @Accept mpfd,text/csv,application/x-ndjson
+// @Produce json
+// @Param file formData file false "Файл CSV или NDJSON"
+// @Param format query string false "Формат файла" Enums(csv, ndjson)
+// @Param mapping query string false "Соответствие полей колонкам" example(group=Artist,song=Title,date=Released)
+// @Param delimiter query string false "Разделитель CSV" default(,)
+// @Param dry_run query bool false "Только проверить строки, ничего не записывая" default(false)
+// @Param enrich query bool false "Дополнить недостающие дату, текст и ссылку из внешнего API" default(false)
+// @Param on_conflict query string false "Что делать с уже существующими песнями" Enums(skip, update) default(skip)
+// @Success 200 {object} importResponse
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 413 {object} importResponse
+// @Failure 422 {object} importResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} importResponse
+// @Security ApiKeyAuth
+// @Router /v1/songs/import [post]
+// @Router /v2/songs/import [post]
+func (h *Handler) ImportSongs(c *gin.Context) {
+	slog.Info("Начало обработки запроса ImportSongs")
+
+	var opts model.ImportOptions
+	var err error
+	if opts.DryRun, err = strconv.ParseBool(c.DefaultQuery("dry_run", "false")); err != nil {
+		newErrorFromErr(c, errParam("dry_run", "ожидается true или false, получено %q", c.Query("dry_run")))
+		return
+	}
+	if opts.Enrich, err = strconv.ParseBool(c.DefaultQuery("enrich", "false")); err != nil {
+		newErrorFromErr(c, errParam("enrich", "ожидается true или false, получено %q", c.Query("enrich")))
+		return
+	}
+	switch c.DefaultQuery("on_conflict", "skip") {
+	case "skip":
+	case "update":
+		opts.Update = true
+	default:
+		newErrorFromErr(c, errParam("on_conflict", "допустимы skip и update, получено %q", c.Query("on_conflict")))
+		return
+	}
+	mapping, err := parseImportMapping(c.Query("mapping"))
+	if err != nil {
+		newErrorFromErr(c, err)
+		return
+	}
+	rawDelimiter := c.DefaultQuery("delimiter", ",")
+	if utf8.RuneCountInString(rawDelimiter) != 1 {
+		newErrorFromErr(c, errParam("delimiter", "ожидается один символ, получено %q", rawDelimiter))
+		return
+	}
+	delimiter, _ := utf8.DecodeRuneInString(rawDelimiter)
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	source, format, err := importSource(c)
+	if err != nil {
+		slog.Error("Ошибка при чтении файла импорта", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+	defer source.Close()
+
+	var reader importReader
+	switch format {
+	case importCSV:
+		reader, err = newCSVImportReader(source, delimiter)
+		if err != nil {
+			newErrorFromErr(c, err)
+			return
+		}
+	case importNDJSON:
+		reader = newNDJSONImportReader(source)
+	default:
+		newErrorResponce(c, http.StatusBadRequest, "Не удалось определить формат файла, укажите format=csv или format=ndjson")
+		return
+	}
+
+	lines := 0
+	next := func() (model.ImportRow, error) {
+		rec, err := reader.Next()
+		switch {
+		case errors.Is(err, io.EOF):
+			return model.ImportRow{}, err
+		case err != nil:
+			return model.ImportRow{}, importReadError(err, "Не удалось прочитать файл импорта: %v")
+		case lines == maxImportRows:
+			return model.ImportRow{}, &statusError{status: http.StatusUnprocessableEntity, format: "Файл содержит больше %d строк", args: []any{maxImportRows}}
+		}
+		lines++
+		return importRow(rec, mapping), nil
+	}
+
+	slog.Debug("Импорт файла", "format", format, "options", opts)
+
+	results, importErr := h.as(c).Import(next, opts)
+	if importErr != nil {
+		slog.Error("Ошибка при импорте песен", "error", importErr, "rows", len(results))
+		if len(results) == 0 {
+			newErrorFromErr(c, importErr)
+			return
+		}
+	}
+
+	resp := importResponse{Status: "success", DryRun: opts.DryRun, Total: len(results), Rows: results}
+	for i, res := range results {
+		if res.Err != nil {
+			results[i].Reason = localizeErr(c, res.Err)
+		}
+		switch res.Status {
+		case model.ImportCreated:
+			resp.Created++
+		case model.ImportUpdated:
+			resp.Updated++
+		case model.ImportSkipped:
+			resp.Skipped++
+		case model.ImportFailed:
+			resp.Failed++
+		}
+	}
+	if resp.Rows == nil {
+		resp.Rows = make([]model.ImportResult, 0)
+	}
+
+	status := http.StatusOK
+	if importErr != nil {
+		var detail string
+		var fields []problemField
+		status, detail, fields = describeError(c, importErr)
+		problem := newProblem(c, status, detail, c.Request.URL.Path, fields...)
+		resp.Status, resp.Error = "fail", &problem
+	}
+
+	slog.Info("Импорт завершён", "total", resp.Total, "created", resp.Created, "updated", resp.Updated, "skipped", resp.Skipped, "failed", resp.Failed)
+	c.AbortWithStatusJSON(status, resp)
+}
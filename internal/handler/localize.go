@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/i18n"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// printerContextKey is where localize stores the message printer of the
+// request.
+const printerContextKey = "printer"
+
+// defaultPrinter prints messages untranslated, for requests that did not go
+// through localize.
+var defaultPrinter = message.NewPrinter(language.Russian)
+
+// localize picks the language of the response from Accept-Language.
+func (h *Handler) localize(c *gin.Context) {
+	if h.locales == nil {
+		c.Next()
+		return
+	}
+	p, tag := h.locales.Printer(c.GetHeader("Accept-Language"))
+	c.Set(printerContextKey, p)
+	c.Header("Content-Language", tag.String())
+	c.Writer.Header().Add("Vary", "Accept-Language")
+	c.Next()
+}
+
+func printer(c *gin.Context) *message.Printer {
+	if p, ok := c.Get(printerContextKey); ok {
+		return p.(*message.Printer)
+	}
+	return defaultPrinter
+}
+
+// tr formats a message written in Russian in the language of the response.
+func tr(c *gin.Context, format string, args ...any) string {
+	return i18n.Sprintf(printer(c), format, args...)
+}
+
+// localizeErr returns the message of err in the language of the response.
+// Only domain and request errors are translated.
+func localizeErr(c *gin.Context, err error) string {
+	var rerr *requestError
+	if errors.As(err, &rerr) {
+		return rerr.localize(c)
+	}
+	return i18n.Error(printer(c), err)
+}
@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	verseAll          = "all"
+	defaultVerseLimit = 10
+	maxVerseLimit     = 100
+)
+
+type verseResponse struct {
+	Status string `json:"status" example:"success"`
+	Id     int    `json:"id" example:"1"`
+	Verse  int    `json:"verse" example:"2"`
+	Total  int    `json:"total" example:"5"`
+	Type   string `json:"type" example:"chorus"`
+	Label  string `json:"label,omitempty" example:"Chorus"`
+	Repeat int    `json:"repeat,omitempty" example:"2"`
+	Text   string `json:"text" example:"Ooh baby, don't you know I suffer?\nOoh baby, can you hear me moan?"`
+	Prev   string `json:"prev,omitempty" example:"/info/verse?group=Muse&song=Supermassive+Black+Hole&verse=1"`
+	Next   string `json:"next,omitempty" example:"/info/verse?group=Muse&song=Supermassive+Black+Hole&verse=3"`
+}
+
+type verseItem struct {
+	Verse  int    `json:"verse" example:"2"`
+	Type   string `json:"type" example:"chorus"`
+	Label  string `json:"label,omitempty" example:"Chorus"`
+	Repeat int    `json:"repeat,omitempty" example:"2"`
+	Text   string `json:"text" example:"Ooh baby, don't you know I suffer?\nOoh baby, can you hear me moan?"`
+}
+
+type versesResponse struct {
+	Status string      `json:"status" example:"success"`
+	Id     int         `json:"id" example:"1"`
+	Total  int         `json:"total" example:"5"`
+	From   int         `json:"from,omitempty" example:"2"`
+	To     int         `json:"to,omitempty" example:"4"`
+	Page   int         `json:"page,omitempty" example:"1"`
+	Limit  int         `json:"limit,omitempty" example:"10"`
+	Verses []verseItem `json:"verses"`
+	Prev   string      `json:"prev,omitempty" example:"/info/verse?group=Muse&song=Supermassive+Black+Hole&verse=1"`
+	Next   string      `json:"next,omitempty" example:"/info/verse?group=Muse&song=Supermassive+Black+Hole&verse=5-7"`
+}
+
+// verseSelection is what the verse query parameter asks for: a single verse,
+// an inclusive range of verses or a page of all of them.
+type verseSelection struct {
+	from, to    int
+	all         bool
+	page, limit int
+}
+
+func (s verseSelection) single() bool {
+	return !s.all && s.from == s.to
+}
+
+// parseVerseSelection reads verse=N, verse=A-B or verse=all (with page and
+// limit) from the query. A missing verse parameter means the first verse.
+func parseVerseSelection(c *gin.Context) (verseSelection, error) {
+	raw := strings.TrimSpace(c.DefaultQuery("verse", "1"))
+	if raw == verseAll {
+		page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+		if err != nil || page < 1 {
+			return verseSelection{}, errParam("page", "ожидается положительное целое число, получено %q", c.Query("page"))
+		}
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultVerseLimit)))
+		if err != nil || limit < 1 || limit > maxVerseLimit {
+			return verseSelection{}, errParam("limit", "ожидается целое число от 1 до %d, получено %q", maxVerseLimit, c.Query("limit"))
+		}
+		return verseSelection{all: true, page: page, limit: limit}, nil
+	}
+
+	first, last, isRange := strings.Cut(raw, "-")
+	from, err := strconv.Atoi(strings.TrimSpace(first))
+	if err != nil || from < 1 {
+		return verseSelection{}, errParam("verse", "ожидается номер куплета, диапазон A-B или all, получено %q", raw)
+	}
+	to := from
+	if isRange {
+		to, err = strconv.Atoi(strings.TrimSpace(last))
+		if err != nil || to < from {
+			return verseSelection{}, errParam("verse", "конец диапазона должен быть не меньше начала, получено %q", raw)
+		}
+	}
+	return verseSelection{from: from, to: to}, nil
+}
+
+// verseLink builds a link to the same request with the given query
+// parameters replaced.
+func verseLink(c *gin.Context, params map[string]string) string {
+	query := c.Request.URL.Query()
+	for key, value := range params {
+		query.Set(key, value)
+	}
+	return c.Request.URL.Path + "?" + query.Encode()
+}
+
+func newVerseItem(ordinal int, verse model.Verse) verseItem {
+	item := verseItem{Verse: ordinal, Text: valueOrEmpty(verse.Text), Label: valueOrEmpty(verse.Label)}
+	if verse.Type != nil {
+		item.Type = *verse.Type
+	}
+	if verse.Repeat != nil {
+		item.Repeat = *verse.Repeat
+	}
+	return item
+}
+
+// writeVerses answers with the selected verses of the song, or 404 when the
+// selection starts past the last verse or the page past the page after it.
+func writeVerses(c *gin.Context, song model.Song, verses []model.Verse, sel verseSelection) {
+	total := len(verses)
+
+	if sel.single() {
+		if sel.from > total {
+			newErrorFromErr(c, model.NewError(model.ErrNotFound, "Куплет %d не найден", sel.from))
+			return
+		}
+		item := newVerseItem(sel.from, verses[sel.from-1])
+		resp := verseResponse{
+			Status: "success",
+			Id:     *song.ID,
+			Verse:  item.Verse,
+			Total:  total,
+			Type:   item.Type,
+			Label:  item.Label,
+			Repeat: item.Repeat,
+			Text:   item.Text,
+		}
+		if sel.from > 1 {
+			resp.Prev = verseLink(c, map[string]string{"verse": strconv.Itoa(sel.from - 1)})
+		}
+		if sel.from < total {
+			resp.Next = verseLink(c, map[string]string{"verse": strconv.Itoa(sel.from + 1)})
+		}
+		setETag(c, song.Version)
+		c.AbortWithStatusJSON(http.StatusOK, resp)
+		return
+	}
+
+	resp := versesResponse{Status: "success", Id: *song.ID, Total: total}
+	from, to := sel.from, sel.to
+	if sel.all {
+		// (page-1)*limit > total, without overflowing for a huge page.
+		if sel.page-1 > total/sel.limit {
+			newErrorFromErr(c, model.NewError(model.ErrNotFound, "Страница %d не найдена", sel.page))
+			return
+		}
+		from = (sel.page-1)*sel.limit + 1
+		to = from + sel.limit - 1
+		resp.Page, resp.Limit = sel.page, sel.limit
+	} else if from > total {
+		newErrorFromErr(c, model.NewError(model.ErrNotFound, "Куплеты %d-%d не найдены", sel.from, sel.to))
+		return
+	}
+	to = min(to, total)
+
+	resp.Verses = make([]verseItem, 0, max(to-from+1, 0))
+	for ordinal := from; ordinal <= to; ordinal++ {
+		resp.Verses = append(resp.Verses, newVerseItem(ordinal, verses[ordinal-1]))
+	}
+	if len(resp.Verses) > 0 {
+		resp.From, resp.To = from, to
+	}
+
+	if sel.all {
+		if sel.page > 1 && from-sel.limit <= total {
+			resp.Prev = verseLink(c, map[string]string{"page": strconv.Itoa(sel.page - 1)})
+		}
+		if to < total {
+			resp.Next = verseLink(c, map[string]string{"page": strconv.Itoa(sel.page + 1)})
+		}
+	} else {
+		width := sel.to - sel.from + 1
+		if from > 1 {
+			prevFrom := max(from-width, 1)
+			resp.Prev = verseLink(c, map[string]string{"verse": verseRange(prevFrom, from-1)})
+		}
+		if to < total {
+			resp.Next = verseLink(c, map[string]string{"verse": verseRange(to+1, to+min(width, total-to))})
+		}
+	}
+	setETag(c, song.Version)
+	c.AbortWithStatusJSON(http.StatusOK, resp)
+}
+
+func verseRange(from, to int) string {
+	if from == to {
+		return strconv.Itoa(from)
+	}
+	return strconv.Itoa(from) + "-" + strconv.Itoa(to)
+}
+
+func valueOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+func testContext(target string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, target, nil)
+	return c, w
+}
+
+func TestParseVerseSelection(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    verseSelection
+		wantErr string
+	}{
+		{query: "", want: verseSelection{from: 1, to: 1}},
+		{query: "verse=3", want: verseSelection{from: 3, to: 3}},
+		{query: "verse=2-4", want: verseSelection{from: 2, to: 4}},
+		{query: "verse=%202%20-%204%20", want: verseSelection{from: 2, to: 4}},
+		{query: "verse=all", want: verseSelection{all: true, page: 1, limit: defaultVerseLimit}},
+		{query: "verse=all&page=3&limit=100", want: verseSelection{all: true, page: 3, limit: 100}},
+		{query: "verse=0", wantErr: "verse"},
+		{query: "verse=x", wantErr: "verse"},
+		{query: "verse=4-2", wantErr: "verse"},
+		{query: "verse=2-x", wantErr: "verse"},
+		{query: "verse=all&page=0", wantErr: "page"},
+		{query: "verse=all&page=x", wantErr: "page"},
+		{query: "verse=all&limit=0", wantErr: "limit"},
+		{query: "verse=all&limit=101", wantErr: "limit"},
+		{query: "verse=all&page=3&limit=4611686018427387904", wantErr: "limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			c, _ := testContext("/info/verse?" + tt.query)
+			got, err := parseVerseSelection(c)
+			if tt.wantErr != "" {
+				var reqErr *requestError
+				if !errors.As(err, &reqErr) || reqErr.param != tt.wantErr {
+					t.Fatalf("error = %v, want an error of parameter %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("selection = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteVersesPages(t *testing.T) {
+	id, version := 1, 1
+	song := model.Song{ID: &id, Version: &version}
+	verses := make([]model.Verse, 5)
+	for i := range verses {
+		text := strings.Repeat("la ", i+1)
+		verses[i].Text = &text
+	}
+
+	tests := []struct {
+		name       string
+		sel        verseSelection
+		wantStatus int
+		wantCount  int
+	}{
+		{name: "first page", sel: verseSelection{all: true, page: 1, limit: 2}, wantStatus: http.StatusOK, wantCount: 2},
+		{name: "last page", sel: verseSelection{all: true, page: 3, limit: 2}, wantStatus: http.StatusOK, wantCount: 1},
+		{name: "page after the last", sel: verseSelection{all: true, page: 2, limit: 5}, wantStatus: http.StatusOK, wantCount: 0},
+		{name: "page past the end", sel: verseSelection{all: true, page: 4, limit: 2}, wantStatus: http.StatusNotFound},
+		{name: "huge page", sel: verseSelection{all: true, page: 1 << 62, limit: maxVerseLimit}, wantStatus: http.StatusNotFound},
+		{name: "range past the end", sel: verseSelection{from: 6, to: 8}, wantStatus: http.StatusNotFound},
+		{name: "huge range", sel: verseSelection{from: 2, to: 1<<63 - 1}, wantStatus: http.StatusOK, wantCount: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := testContext("/info/verse")
+			writeVerses(c, song, verses, tt.sel)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantStatus == http.StatusOK {
+				if got := strings.Count(w.Body.String(), `"verse":`); got != tt.wantCount {
+					t.Errorf("verses = %d, want %d: %s", got, tt.wantCount, w.Body)
+				}
+			}
+		})
+	}
+}
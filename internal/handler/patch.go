@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin"
+)
+
+// Patch document media types accepted by PATCH in addition to plain JSON.
+const (
+	mimeMergePatch = "application/merge-patch+json"
+	mimeJSONPatch  = "application/json-patch+json"
+)
+
+var acceptPatch = "application/json, " + mimeMergePatch + ", " + mimeJSONPatch
+
+// patchFunc applies a parsed patch document to a JSON document.
+type patchFunc func(doc []byte) ([]byte, error)
+
+// parsePatch reads a JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902)
+// document of the given media type.
+func parsePatch(mediaType string, body []byte) (patchFunc, error) {
+	switch mediaType {
+	case mimeMergePatch:
+		if !json.Valid(body) {
+			return nil, errRequest("Документ merge patch не является корректным JSON")
+		}
+		return func(doc []byte) ([]byte, error) {
+			patched, err := jsonpatch.MergePatch(doc, body)
+			if err != nil {
+				return nil, model.NewError(model.ErrValidation, "Не удалось применить патч: %v", err)
+			}
+			return patched, nil
+		}, nil
+	case mimeJSONPatch:
+		patch, err := jsonpatch.DecodePatch(body)
+		if err != nil {
+			return nil, errRequest("Некорректный документ JSON Patch: %v", err)
+		}
+		return func(doc []byte) ([]byte, error) {
+			patched, err := patch.Apply(doc)
+			if errors.Is(err, jsonpatch.ErrTestFailed) {
+				return nil, model.NewError(model.ErrConflict, "Операция test не выполнена: %v", err)
+			}
+			if err != nil {
+				return nil, model.NewError(model.ErrValidation, "Не удалось применить патч: %v", err)
+			}
+			return patched, nil
+		}, nil
+	default:
+		return nil, errRequest("Неподдерживаемый формат патча %q", mediaType)
+	}
+}
+
+// patchSong serves PATCH: plain JSON updates the given fields, merge patch and
+// JSON patch documents are applied to the song in the codec's format.
+func (h *Handler) patchSong(c *gin.Context, v songCodec) {
+	c.Header("Accept-Patch", acceptPatch)
+
+	mediaType := c.ContentType()
+	if mediaType == "" || mediaType == gin.MIMEJSON {
+		h.writeSong(c, v, h.as(c).UpdateSongByID)
+		return
+	}
+	if mediaType != mimeMergePatch && mediaType != mimeJSONPatch {
+		newErrorResponce(c, http.StatusUnsupportedMediaType, "Неподдерживаемый формат патча %q, допустимы %s", mediaType, acceptPatch)
+		return
+	}
+
+	id, ok := intParam(c, "id")
+	if !ok {
+		return
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		slog.Error("Ошибка при чтении тела запроса", "error", err)
+		newErrorResponce(c, http.StatusBadRequest, "Не удалось прочитать тело запроса")
+		return
+	}
+	patch, err := parsePatch(mediaType, body)
+	if err != nil {
+		slog.Error("Ошибка при разборе патча", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+	version, ok := requireVersion(c, nil)
+	if !ok {
+		return
+	}
+
+	updated, err := h.as(c).PatchSong(id, version, func(current model.Song) (model.Song, error) {
+		doc, err := json.Marshal(v.encode(current))
+		if err != nil {
+			return model.Song{}, err
+		}
+		patched, err := patch(doc)
+		if err != nil {
+			return model.Song{}, err
+		}
+		next, err := v.decodeDoc(patched)
+		if err != nil {
+			return model.Song{}, err
+		}
+		if next.ID != nil && *next.ID != *current.ID {
+			return model.Song{}, model.NewError(model.ErrValidation, "ID песни нельзя изменить").WithField("id")
+		}
+		if next.Version != nil && *next.Version != *current.Version {
+			return model.Song{}, model.NewError(model.ErrValidation, "Версию песни нельзя изменить патчем").WithField("version")
+		}
+		return next, nil
+	})
+	if err != nil {
+		slog.Error("Ошибка при применении патча", "error", err)
+		v.errorFromErr(c, err)
+		return
+	}
+
+	slog.Info("Патч успешно применён", "id", id, "version", *updated.Version)
+	c.Header("Location", v.location(id))
+	setETag(c, updated.Version)
+	c.AbortWithStatusJSON(200, v.encode(updated))
+}
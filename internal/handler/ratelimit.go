@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// sweepInterval is how often buckets that refilled completely are dropped.
+const sweepInterval = time.Minute
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// rateLimiter keeps a token bucket per client: every request takes a token,
+// tokens are refilled at rate per second up to burst.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// newRateLimiter returns nil, meaning no limit, for a non-positive rate.
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &rateLimiter{rate: rate, burst: float64(max(burst, 1)), buckets: make(map[string]*tokenBucket)}
+}
+
+// rateLimitState is what take reports about a client's bucket.
+type rateLimitState struct {
+	allowed   bool
+	remaining int
+	// reset is the time until the bucket is full again.
+	reset time.Duration
+	// retryAfter is the time until the next token, when not allowed.
+	retryAfter time.Duration
+}
+
+func (l *rateLimiter) take(key string, now time.Time) rateLimitState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+
+	var state rateLimitState
+	if b.tokens >= 1 {
+		b.tokens--
+		state.allowed = true
+	} else {
+		state.retryAfter = l.duration(1 - b.tokens)
+	}
+	state.remaining = int(b.tokens)
+	state.reset = l.duration(l.burst - b.tokens)
+	return state
+}
+
+// duration is the time needed to refill tokens.
+func (l *rateLimiter) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+func (l *rateLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// policy describes the limit for the RateLimit-Policy header: the burst
+// quota and the window in which it refills.
+func (l *rateLimiter) policy() string {
+	return fmt.Sprintf("%d;w=%d", int(l.burst), seconds(l.duration(l.burst)))
+}
+
+// rateLimits holds the read and write limiters.
+type rateLimits struct {
+	read, write *rateLimiter
+}
+
+func newRateLimits(cfg config.RateLimitConfig) rateLimits {
+	return rateLimits{
+		read:  newRateLimiter(cfg.ReadRate, cfg.ReadBurst),
+		write: newRateLimiter(cfg.WriteRate, cfg.WriteBurst),
+	}
+}
+
+// rateLimitKey identifies the client: the API key or user it authenticated
+// as, or its IP address.
+func rateLimitKey(c *gin.Context) string {
+	if p := principal(c); p.Kind != "" {
+		return p.Kind + ":" + strconv.Itoa(p.ID)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// rateLimit takes a token from the client's read or write bucket, answering
+// 429 when it is empty. POST /graphql is charged by its handler, which knows
+// whether the query mutates.
+func (h *Handler) rateLimit(c *gin.Context) {
+	if isGraphQLPost(c) {
+		c.Next()
+		return
+	}
+	if h.takeToken(c, h.requestLimiter(c)) {
+		c.Next()
+	}
+}
+
+// requestLimiter is the limiter charged for the request before it is handled:
+// read for GET, HEAD and OPTIONS and, as its query is not parsed yet, for
+// POST /graphql; write for the rest.
+func (h *Handler) requestLimiter(c *gin.Context) *rateLimiter {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return h.limits.read
+	}
+	if isGraphQLPost(c) {
+		return h.limits.read
+	}
+	return h.limits.write
+}
+
+func isGraphQLPost(c *gin.Context) bool {
+	return c.Request.Method == http.MethodPost && c.FullPath() == "/graphql"
+}
+
+// takeToken takes a token from the client's bucket of limiter and reports it
+// in RateLimit-* headers. When the bucket is empty it answers 429 and returns
+// false.
+func (h *Handler) takeToken(c *gin.Context, limiter *rateLimiter) bool {
+	if limiter == nil {
+		return true
+	}
+
+	state := limiter.take(rateLimitKey(c), time.Now())
+	c.Header("RateLimit-Policy", limiter.policy())
+	c.Header("RateLimit-Limit", strconv.Itoa(int(limiter.burst)))
+	c.Header("RateLimit-Remaining", strconv.Itoa(state.remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(seconds(state.reset)))
+	if !state.allowed {
+		retry := seconds(state.retryAfter)
+		c.Header("Retry-After", strconv.Itoa(retry))
+		newErrorResponce(c, http.StatusTooManyRequests, "Слишком много запросов, повторите через %d с", retry)
+		return false
+	}
+	return true
+}
+
+// seconds rounds d up to whole seconds.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
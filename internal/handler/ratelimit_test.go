@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/Xapsiel/EffectiveMobile/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimiterTake(t *testing.T) {
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	l := newRateLimiter(2, 3)
+	steps := []struct {
+		key   string
+		after time.Duration
+		want  rateLimitState
+	}{
+		{key: "a", want: rateLimitState{allowed: true, remaining: 2, reset: 500 * time.Millisecond}},
+		{key: "a", want: rateLimitState{allowed: true, remaining: 1, reset: time.Second}},
+		{key: "a", want: rateLimitState{allowed: true, remaining: 0, reset: 1500 * time.Millisecond}},
+		{key: "a", want: rateLimitState{remaining: 0, reset: 1500 * time.Millisecond, retryAfter: 500 * time.Millisecond}},
+		{key: "b", want: rateLimitState{allowed: true, remaining: 2, reset: 500 * time.Millisecond}},
+		{key: "a", after: 250 * time.Millisecond, want: rateLimitState{remaining: 0, reset: 1250 * time.Millisecond, retryAfter: 250 * time.Millisecond}},
+		{key: "a", after: 500 * time.Millisecond, want: rateLimitState{allowed: true, remaining: 0, reset: 1500 * time.Millisecond}},
+		{key: "a", after: 1500 * time.Millisecond, want: rateLimitState{allowed: true, remaining: 1, reset: time.Second}},
+		// A bucket idle for long refills up to burst, no further.
+		{key: "a", after: time.Hour, want: rateLimitState{allowed: true, remaining: 2, reset: 500 * time.Millisecond}},
+	}
+	for i, step := range steps {
+		if got := l.take(step.key, start.Add(step.after)); got != step.want {
+			t.Errorf("step %d: take(%q) at +%v = %+v, want %+v", i, step.key, step.after, got, step.want)
+		}
+	}
+	if _, ok := l.buckets["b"]; ok {
+		t.Error("the full bucket of b was not swept")
+	}
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	tests := []struct {
+		rate      float64
+		burst     int
+		wantNil   bool
+		wantBurst float64
+	}{
+		{rate: 0, burst: 10, wantNil: true},
+		{rate: -1, burst: 10, wantNil: true},
+		{rate: 1, burst: 0, wantBurst: 1},
+		{rate: 1, burst: 20, wantBurst: 20},
+	}
+	for _, tt := range tests {
+		l := newRateLimiter(tt.rate, tt.burst)
+		if (l == nil) != tt.wantNil {
+			t.Errorf("newRateLimiter(%v, %d) = %v, want nil %v", tt.rate, tt.burst, l, tt.wantNil)
+			continue
+		}
+		if l != nil && l.burst != tt.wantBurst {
+			t.Errorf("newRateLimiter(%v, %d).burst = %v, want %v", tt.rate, tt.burst, l.burst, tt.wantBurst)
+		}
+	}
+}
+
+type rejectingAuth struct {
+	service.Auth
+	calls int
+}
+
+func (a *rejectingAuth) Authenticate(string) (model.Principal, error) {
+	a.calls++
+	return model.Principal{}, model.NewError(model.ErrUnauthorized, "Неверный API-ключ")
+}
+
+func TestFailedAuthenticationIsRateLimited(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth := &rejectingAuth{}
+	h := &Handler{
+		service: service.Service{Auth: auth},
+		limits:  rateLimits{read: newRateLimiter(0.001, 3), write: newRateLimiter(0.001, 3)},
+	}
+	router := gin.New()
+	router.GET("/info", h.authenticate, h.rateLimit, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	get := func(remoteAddr, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/info", nil)
+		req.RemoteAddr = remoteAddr
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	steps := []struct {
+		remoteAddr, key string
+		wantStatus      int
+	}{
+		{remoteAddr: "192.0.2.1:1000", key: "guess-1", wantStatus: http.StatusUnauthorized},
+		{remoteAddr: "192.0.2.1:1001", key: "guess-2", wantStatus: http.StatusUnauthorized},
+		{remoteAddr: "192.0.2.1:1002", wantStatus: http.StatusUnauthorized},
+		{remoteAddr: "192.0.2.1:1003", key: "guess-3", wantStatus: http.StatusTooManyRequests},
+		{remoteAddr: "192.0.2.1:1004", wantStatus: http.StatusTooManyRequests},
+		// Another address has a bucket of its own.
+		{remoteAddr: "192.0.2.2:1000", key: "guess-4", wantStatus: http.StatusUnauthorized},
+	}
+	for i, step := range steps {
+		if w := get(step.remoteAddr, step.key); w.Code != step.wantStatus {
+			t.Fatalf("step %d: status = %d, want %d: %s", i, w.Code, step.wantStatus, w.Body)
+		}
+	}
+	if auth.calls != 4 {
+		t.Errorf("Authenticate called %d times, want 4", auth.calls)
+	}
+}
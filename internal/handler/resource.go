@@ -0,0 +1,415 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// songCodec converts songs between the service model and the wire format of
+// one API version.
+type songCodec struct {
+	prefix string
+	encode func(model.Song) any
+	// decode binds the song fields written by PUT and PATCH.
+	decode func(c *gin.Context) (model.Song, error)
+	// decodeNew binds the song name and group of a song to create.
+	decodeNew func(c *gin.Context) (song, group string, err error)
+	// decodeJSON and decodeNewJSON strictly decode what decode and decodeNew
+	// bind, from the song of a batch operation.
+	decodeJSON    func(data []byte) (model.Song, error)
+	decodeNewJSON func(data []byte) (song, group string, err error)
+	// decodeDoc strictly decodes a complete song in the encode format; it is
+	// what a patched document must turn into.
+	decodeDoc func(data []byte) (model.Song, error)
+	// encodeBrief encodes a song without its lyrics.
+	encodeBrief func(model.Song) any
+	// encodeChanges encodes the fields set in a partial song, leaving the
+	// others out.
+	encodeChanges func(model.Song) any
+	// columns are the CSV columns of an exported song.
+	columns []songColumn
+	// fields renames model.Song JSON fields to the version's names in error
+	// reports.
+	fields map[string]string
+}
+
+// decodeStrict unmarshals data into v rejecting unknown fields and trailing
+// data, reporting problems as validation errors.
+func decodeStrict(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		if verr := decodeError(err); verr != nil {
+			return verr
+		}
+		return model.NewError(model.ErrValidation, "Песня не соответствует схеме: некорректный JSON")
+	}
+	if dec.More() {
+		return model.NewError(model.ErrValidation, "Песня не соответствует схеме: лишние данные после объекта")
+	}
+	return nil
+}
+
+// decodeBody is decodeStrict followed by the binding validation that gin
+// runs on request bodies.
+func decodeBody(data []byte, v any) error {
+	if err := decodeStrict(data, v); err != nil {
+		return err
+	}
+	if err := binding.Validator.ValidateStruct(v); err != nil {
+		if verr := decodeError(err); verr != nil {
+			return verr
+		}
+		return err
+	}
+	return nil
+}
+
+func (v songCodec) location(id int) string {
+	return v.prefix + "/songs/" + strconv.Itoa(id)
+}
+
+// errorFromErr is newErrorFromErr reporting model.Song fields under the
+// names of the codec's version.
+func (v songCodec) errorFromErr(c *gin.Context, err error) {
+	newErrorFromErr(c, v.renameFields(err))
+}
+
+// renameFields renames the model.Song fields err points at to the names of
+// the codec's version.
+func (v songCodec) renameFields(err error) error {
+	var merr *model.Error
+	if errors.As(err, &merr) && len(merr.Fields) > 0 && v.fields != nil {
+		renamed := *merr
+		renamed.Fields = make([]model.FieldError, len(merr.Fields))
+		for i, f := range merr.Fields {
+			if name, ok := v.fields[f.Field]; ok {
+				f.Field = name
+			}
+			renamed.Fields[i] = f
+		}
+		return &renamed
+	}
+	return err
+}
+
+// @Summary Получение песни
+// @Description Получение всех данных песни по ID
+// @Tags v1
+// @Produce json
+// @Param id path int true "ID песни"
+// @Success 200 {object} songV1
+// @Header 200 {string} ETag "Версия песни"
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /v1/songs/{id} [get]
+func (h *Handler) GetSongByID(c *gin.Context) {
+	slog.Info("Начало обработки запроса GetSongByID")
+	h.getSong(c, songCodecV1)
+}
+
+func (h *Handler) getSong(c *gin.Context, v songCodec) {
+	id, ok := intParam(c, "id")
+	if !ok {
+		return
+	}
+
+	song, err := h.service.GetSong(id)
+	if err != nil {
+		slog.Error("Ошибка при получении песни", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+
+	slog.Info("Песня успешно получена", "id", id)
+	setETag(c, song.Version)
+	c.AbortWithStatusJSON(200, v.encode(song))
+}
+
+// @Summary Добавление песни
+// @Description Добавление новой песни с обогащением из внешнего API. Возвращает созданную песню
+// @Tags v1
+// @Accept json
+// @Produce json
+// @Param song body Song true "Данные песни"
+// @Success 201 {object} songV1
+// @Header 201 {string} Location "Адрес созданной песни"
+// @Header 201 {string} ETag "Версия песни"
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 409 {object} errorResponse
+// @Failure 422 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure 502 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /v1/songs [post]
+func (h *Handler) CreateSong(c *gin.Context) {
+	slog.Info("Начало обработки запроса CreateSong")
+	h.createSong(c, songCodecV1)
+}
+
+func (h *Handler) createSong(c *gin.Context, v songCodec) {
+	songName, group, err := v.decodeNew(c)
+	if err != nil {
+		bindError(c, err)
+		return
+	}
+
+	id, err := h.as(c).Add(songName, group)
+	if err != nil {
+		slog.Error("Ошибка при добавлении песни", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+	song, err := h.service.GetSong(id)
+	if err != nil {
+		slog.Error("Ошибка при получении песни", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+
+	slog.Info("Песня успешно добавлена", "id", id)
+	c.Header("Location", v.location(id))
+	setETag(c, song.Version)
+	c.AbortWithStatusJSON(http.StatusCreated, v.encode(song))
+}
+
+// @Summary Замена песни
+// @Description Полная замена данных песни: нужно передать название, группу, дату выпуска, текст и ссылку
+// @Tags v1
+// @Accept json
+// @Produce json
+// @Param id path int true "ID песни"
+// @Param If-Match header string false "Ожидаемая версия песни (ETag)"
+// @Param song body songV1 true "Новые данные песни"
+// @Success 200 {object} songV1
+// @Header 200 {string} Location "Адрес песни"
+// @Header 200 {string} ETag "Новая версия песни"
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 409 {object} errorResponse
+// @Failure 412 {object} errorResponse
+// @Failure 422 {object} errorResponse
+// @Failure 428 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /v1/songs/{id} [put]
+func (h *Handler) ReplaceSong(c *gin.Context) {
+	slog.Info("Начало обработки запроса ReplaceSong")
+	h.writeSong(c, songCodecV1, h.as(c).ReplaceSong)
+}
+
+// @Summary Частичное обновление песни
+// @Description С application/json обновляются переданные поля песни. application/merge-patch+json (RFC 7396) и application/json-patch+json (RFC 6902) применяются к песне целиком: так можно очистить текст или ссылку (null / remove). Для патчей версия передаётся только в If-Match
+// @Tags v1
+// @Accept json,application/merge-patch+json,application/json-patch+json
+// @Produce json
+// @Param id path int true "ID песни"
+// @Param If-Match header string false "Ожидаемая версия песни (ETag)"
+// @Param song body songV1 true "Изменяемые поля песни"
+// @Success 200 {object} songV1
+// @Header 200 {string} Location "Адрес песни"
+// @Header 200 {string} ETag "Новая версия песни"
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 409 {object} errorResponse
+// @Failure 412 {object} errorResponse
+// @Failure 422 {object} errorResponse
+// @Failure 415 {object} errorResponse
+// @Failure 428 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /v1/songs/{id} [patch]
+func (h *Handler) PatchSong(c *gin.Context) {
+	slog.Info("Начало обработки запроса PatchSong")
+	h.patchSong(c, songCodecV1)
+}
+
+// writeSong binds the song from the body, checks the expected version and
+// answers with the song as stored after apply.
+func (h *Handler) writeSong(c *gin.Context, v songCodec, apply func(id int, song model.Song) (model.Song, error)) {
+	id, ok := intParam(c, "id")
+	if !ok {
+		return
+	}
+	song, err := v.decode(c)
+	if err != nil {
+		bindError(c, err)
+		return
+	}
+	version, ok := requireVersion(c, song.Version)
+	if !ok {
+		return
+	}
+	song.Version = version
+
+	updated, err := apply(id, song)
+	if err != nil {
+		slog.Error("Ошибка при обновлении песни", "error", err)
+		v.errorFromErr(c, err)
+		return
+	}
+
+	slog.Info("Песня успешно обновлена", "id", id, "version", *updated.Version)
+	c.Header("Location", v.location(id))
+	setETag(c, updated.Version)
+	c.AbortWithStatusJSON(200, v.encode(updated))
+}
+
+// @Summary Удаление песни
+// @Description Удаление песни по ID
+// @Tags v1
+// @Param id path int true "ID песни"
+// @Param If-Match header string true "Ожидаемая версия песни (ETag)"
+// @Success 204
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 412 {object} errorResponse
+// @Failure 428 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /v1/songs/{id} [delete]
+// @Router /v2/songs/{id} [delete]
+func (h *Handler) DeleteSongByID(c *gin.Context) {
+	slog.Info("Начало обработки запроса DeleteSongByID")
+
+	id, ok := intParam(c, "id")
+	if !ok {
+		return
+	}
+	version, ok := requireVersion(c, nil)
+	if !ok {
+		return
+	}
+
+	if err := h.as(c).DeleteSongByID(id, version); err != nil {
+		slog.Error("Ошибка при удалении песни", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+
+	slog.Info("Песня успешно удалена", "id", id)
+	c.AbortWithStatus(http.StatusNoContent)
+}
+
+// @Summary Получение куплета
+// @Description Получение куплета песни по номеру
+// @Tags v1
+// @Produce json
+// @Param id path int true "ID песни"
+// @Param n path int true "Номер куплета"
+// @Success 200 {object} model.Verse
+// @Header 200 {string} ETag "Версия песни"
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /v1/songs/{id}/verses/{n} [get]
+// @Router /v2/songs/{id}/verses/{n} [get]
+func (h *Handler) GetVerse(c *gin.Context) {
+	slog.Info("Начало обработки запроса GetVerse")
+
+	songID, ok := intParam(c, "id")
+	if !ok {
+		return
+	}
+	ordinal, ok := intParam(c, "n")
+	if !ok {
+		return
+	}
+
+	verses, song, err := h.service.GetVerses(songID)
+	if err != nil {
+		slog.Error("Ошибка при получении куплетов", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+	if ordinal > len(verses) {
+		newErrorFromErr(c, model.NewError(model.ErrNotFound, "Куплет %d не найден", ordinal))
+		return
+	}
+
+	slog.Info("Куплет успешно получен", "song_id", songID, "ordinal", ordinal)
+	setETag(c, song.Version)
+	c.AbortWithStatusJSON(200, verses[ordinal-1])
+}
+
+// @Summary Песни группы
+// @Description Получение песен группы по ID группы с пагинацией
+// @Tags v1
+// @Produce json
+// @Param id path int true "ID группы"
+// @Param page query int false "Номер страницы" default(1)
+// @Param limit query int false "Количество на странице" default(10)
+// @Success 200 {object} []songV1
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /v1/groups/{id}/songs [get]
+func (h *Handler) GetGroupSongs(c *gin.Context) {
+	slog.Info("Начало обработки запроса GetGroupSongs")
+	h.getGroupSongs(c, songCodecV1)
+}
+
+func (h *Handler) getGroupSongs(c *gin.Context, v songCodec) {
+	groupID, ok := intParam(c, "id")
+	if !ok {
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		slog.Error("Ошибка при парсинге page", "error", err)
+		newErrorFromErr(c, errParam("page", "ожидается положительное целое число, получено %q", c.Query("page")))
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		slog.Error("Ошибка при парсинге limit", "error", err)
+		newErrorFromErr(c, errParam("limit", "ожидается положительное целое число, получено %q", c.Query("limit")))
+		return
+	}
+
+	songs, err := h.service.GetSongs(model.Song{GroupId: &groupID}, model.SongSort{}, page, limit)
+	if err != nil {
+		slog.Error("Ошибка при получении песен", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+	res := make([]any, len(songs))
+	for i, song := range songs {
+		res[i] = v.encode(song)
+	}
+
+	slog.Info("Успешно получен список песен группы", "group_id", groupID, "count", len(songs))
+	c.AbortWithStatusJSON(200, res)
+}
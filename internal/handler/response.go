@@ -1,23 +1,313 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
 
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 )
 
+// mimeProblem is the media type of errorResponse (RFC 7807).
+const mimeProblem = "application/problem+json"
+
+// problemTypesPath prefixes the type URI of every problem; GET on it
+// describes the problem type.
+const problemTypesPath = "/problems/"
+
+// problemType is a kind of problem: its machine readable code, which is also
+// the last segment of the type URI, and a short summary that does not change
+// between occurrences.
+type problemType struct {
+	Code  string
+	Title string
+}
+
+// problemTypes are the problem types by HTTP status.
+var problemTypes = map[int]problemType{
+	http.StatusBadRequest:            {"bad_request", "Некорректный запрос"},
+	http.StatusUnauthorized:          {"unauthorized", "Требуется аутентификация"},
+	http.StatusForbidden:             {"forbidden", "Недостаточно прав"},
+	http.StatusNotFound:              {"not_found", "Ресурс не найден"},
+	http.StatusConflict:              {"conflict", "Конфликт с текущим состоянием"},
+	http.StatusPreconditionFailed:    {"precondition_failed", "Версия не совпадает"},
+	http.StatusRequestEntityTooLarge: {"payload_too_large", "Слишком большое тело запроса"},
+	http.StatusUnsupportedMediaType:  {"unsupported_media_type", "Неподдерживаемый формат тела запроса"},
+	http.StatusUnprocessableEntity:   {"validation_error", "Данные не прошли проверку"},
+	http.StatusPreconditionRequired:  {"precondition_required", "Требуется версия ресурса"},
+	http.StatusFailedDependency:      {"aborted", "Операция отменена"},
+	http.StatusTooManyRequests:       {"rate_limited", "Слишком много запросов"},
+	http.StatusInternalServerError:   {"internal_error", "Внутренняя ошибка сервера"},
+	http.StatusBadGateway:            {"upstream_unavailable", "Внешний сервис недоступен"},
+}
+
+func problemTypeOf(status int) problemType {
+	if t, ok := problemTypes[status]; ok {
+		return t
+	}
+	return problemTypes[http.StatusInternalServerError]
+}
+
+// errorResponse is an RFC 7807 problem details object. Code repeats the last
+// segment of Type, Errors lists the request fields that caused the problem.
 type errorResponse struct {
-	Message string `json:"message" example:"error description""`
-	Status  string `json:"status" example:"fail"`
+	Type     string         `json:"type" example:"/problems/validation_error"`
+	Title    string         `json:"title" example:"Данные не прошли проверку"`
+	Status   int            `json:"status" example:"422"`
+	Detail   string         `json:"detail" example:"Некорректная дата выпуска \"2006-19-07\", ожидается формат ГГГГ-ММ-ДД"`
+	Instance string         `json:"instance" example:"/v2/songs/1"`
+	Code     string         `json:"code" example:"validation_error"`
+	Errors   []problemField `json:"errors,omitempty"`
+}
+
+// problemField points at a body field or query or path parameter.
+type problemField struct {
+	Field   string `json:"field" example:"release_date"`
+	Message string `json:"message" example:"Некорректная дата выпуска \"2006-19-07\", ожидается формат ГГГГ-ММ-ДД"`
 }
 
 type resultResponse struct {
-	Id     int    `json:"id,omitempty" example:"1""`
+	Id     int    `json:"id,omitempty" example:"1"`
 	Text   string `json:"text,omitempty" example:"description"`
 	Status string `json:"status,omitempty" example:"success"`
 }
 
-func newErrorResponce(c *gin.Context, statusCode int, message string) {
-	c.AbortWithStatusJSON(statusCode, errorResponse{Message: message, Status: "fail"})
-	slog.Warn(message)
+// newErrorResponce answers with a problem whose detail is format translated
+// into the language of the response.
+func newErrorResponce(c *gin.Context, statusCode int, format string, args ...any) {
+	writeProblem(c, statusCode, tr(c, format, args...))
+}
+
+func writeProblem(c *gin.Context, statusCode int, detail string, fields ...problemField) {
+	c.Header("Content-Type", mimeProblem)
+	c.AbortWithStatusJSON(statusCode, newProblem(c, statusCode, detail, c.Request.URL.Path, fields...))
+	slog.Warn(detail)
+}
+
+// newProblem describes a problem with the given status that occurred at
+// instance.
+func newProblem(c *gin.Context, statusCode int, detail, instance string, fields ...problemField) errorResponse {
+	t := problemTypeOf(statusCode)
+	return errorResponse{
+		Type:     problemTypesPath + t.Code,
+		Title:    tr(c, t.Title),
+		Status:   statusCode,
+		Detail:   detail,
+		Instance: instance,
+		Code:     t.Code,
+		Errors:   fields,
+	}
+}
+
+// requestError is a malformed request answered with 400; param names the
+// query or path parameter or header at fault, if any.
+type requestError struct {
+	param  string
+	format string
+	args   []any
+}
+
+// errParam reports a malformed parameter.
+func errParam(name, format string, args ...any) error {
+	return &requestError{param: name, format: format, args: args}
+}
+
+// errRequest reports a malformed request.
+func errRequest(format string, args ...any) error {
+	return &requestError{format: format, args: args}
+}
+
+// statusError is a problem answered with a status that no domain error kind
+// stands for.
+type statusError struct {
+	status int
+	format string
+	args   []any
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf(e.format, e.args...)
+}
+
+func (e *requestError) Error() string {
+	if e.param == "" {
+		return fmt.Sprintf(e.format, e.args...)
+	}
+	return fmt.Sprintf("Некорректный параметр %s: %s", e.param, fmt.Sprintf(e.format, e.args...))
+}
+
+func (e *requestError) localize(c *gin.Context) string {
+	if e.param == "" {
+		return tr(c, e.format, e.args...)
+	}
+	return tr(c, "Некорректный параметр %s: %s", e.param, tr(c, e.format, e.args...))
+}
+
+// newErrorFromErr maps a domain error returned by the service layer onto the
+// matching HTTP status. Errors of unknown kind are reported as 500.
+func newErrorFromErr(c *gin.Context, err error) {
+	status, detail, fields := describeError(c, err)
+	writeProblem(c, status, detail, fields...)
+}
+
+// describeError is the status, translated detail and field errors of the
+// problem err is reported as.
+func describeError(c *gin.Context, err error) (int, string, []problemField) {
+	var rerr *requestError
+	if errors.As(err, &rerr) {
+		var fields []problemField
+		if rerr.param != "" {
+			fields = append(fields, problemField{Field: rerr.param, Message: tr(c, rerr.format, rerr.args...)})
+		}
+		return http.StatusBadRequest, rerr.localize(c), fields
+	}
+	var serr *statusError
+	if errors.As(err, &serr) {
+		return serr.status, tr(c, serr.format, serr.args...), nil
+	}
+
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, model.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, model.ErrConflict):
+		status = http.StatusConflict
+	case errors.Is(err, model.ErrValidation):
+		status = http.StatusUnprocessableEntity
+	case errors.Is(err, model.ErrUpstreamUnavailable):
+		status = http.StatusBadGateway
+	case errors.Is(err, model.ErrPreconditionFailed):
+		status = http.StatusPreconditionFailed
+	case errors.Is(err, model.ErrUnauthorized):
+		status = http.StatusUnauthorized
+	case errors.Is(err, model.ErrForbidden):
+		status = http.StatusForbidden
+	case errors.Is(err, model.ErrAborted):
+		status = http.StatusFailedDependency
+	}
+	if status == http.StatusInternalServerError {
+		slog.Error("Внутренняя ошибка", "error", err)
+		return status, tr(c, "Не удалось обработать запрос"), nil
+	}
+
+	var fields []problemField
+	var merr *model.Error
+	if errors.As(err, &merr) {
+		for _, f := range merr.Fields {
+			fields = append(fields, problemField{Field: f.Field, Message: tr(c, f.Format, f.Args...)})
+		}
+	}
+	return status, localizeErr(c, err), fields
+}
+
+// bindError answers for a request body that could not be decoded: 422 when
+// it is well-formed JSON with invalid fields, 400 otherwise.
+func bindError(c *gin.Context, err error) {
+	slog.Error("Ошибка при парсинге JSON", "error", err)
+	if errors.Is(err, model.ErrValidation) {
+		newErrorFromErr(c, err)
+		return
+	}
+	if verr := decodeError(err); verr != nil {
+		newErrorFromErr(c, verr)
+		return
+	}
+	newErrorResponce(c, http.StatusBadRequest, "Тело запроса не является корректным JSON")
+}
+
+// decodeError turns a JSON decoding or binding validation error into a
+// validation error pointing at the offending fields. It returns nil for
+// malformed JSON.
+func decodeError(err error) *model.Error {
+	var verrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &verrs):
+		fields := make([]string, len(verrs))
+		for i, fe := range verrs {
+			fields[i] = fe.Field()
+		}
+		res := model.NewError(model.ErrValidation, "Некорректные поля: %s", strings.Join(fields, ", "))
+		for _, fe := range verrs {
+			res.Fields = append(res.Fields, validationField(fe))
+		}
+		return res
+	case errors.As(err, &typeErr):
+		return model.NewError(model.ErrValidation, typeErrorFormat(typeErr.Type.Kind()), typeErr.Field).WithField(typeErr.Field)
+	}
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		field = strings.Trim(field, `"`)
+		return model.NewError(model.ErrValidation, "Неизвестное поле %s", field).WithField(field)
+	}
+	return nil
+}
+
+func validationField(fe validator.FieldError) model.FieldError {
+	format, args := "Не выполнено условие %s", []any{fe.Tag()}
+	if fe.Tag() == "required" {
+		format, args = "Обязательное поле", nil
+	}
+	return model.FieldError{Field: fe.Field(), Message: fmt.Sprintf(format, args...), Format: format, Args: args}
+}
+
+// typeErrorFormat describes a JSON value of the wrong type for a field of
+// the given kind.
+func typeErrorFormat(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "Поле %s должно быть строкой"
+	case reflect.Bool:
+		return "Поле %s должно быть логическим значением"
+	case reflect.Slice, reflect.Array:
+		return "Поле %s должно быть массивом"
+	case reflect.Map, reflect.Struct:
+		return "Поле %s должно быть объектом"
+	default:
+		return "Поле %s должно быть числом"
+	}
+}
+
+// jsonFieldName names struct fields in binding validation errors by their
+// JSON key.
+func jsonFieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// @Summary Тип ошибки
+// @Description Описание типа ошибки, на который ссылается поле type ответа application/problem+json
+// @Tags problems
+// @Produce json
+// @Param code path string true "Код ошибки" example(validation_error)
+// @Success 200 {object} problemTypeResponse
+// @Failure 404 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Router /problems/{code} [get]
+func (h *Handler) GetProblemType(c *gin.Context) {
+	code := c.Param("code")
+	for status, t := range problemTypes {
+		if t.Code == code {
+			c.AbortWithStatusJSON(http.StatusOK, problemTypeResponse{Type: problemTypesPath + t.Code, Title: tr(c, t.Title), Status: status})
+			return
+		}
+	}
+	newErrorResponce(c, http.StatusNotFound, "Тип ошибки %q не существует", code)
+}
+
+type problemTypeResponse struct {
+	Type   string `json:"type" example:"/problems/validation_error"`
+	Title  string `json:"title" example:"Данные не прошли проверку"`
+	Status int    `json:"status" example:"422"`
 }
@@ -1,9 +1,7 @@
 package handler
 
 import (
-	"fmt"
 	"log/slog"
-	"net/http"
 	"strconv"
 
 	"github.com/Xapsiel/EffectiveMobile/internal/model"
@@ -30,61 +28,41 @@ type Song struct {
 // @Param			limit	query		int		false	"Количество на странице"		default(10)
 // @Success		200		{object}	[]model.Song
 // @Failure		400		{object}	errorResponse
+// @Failure		401		{object}	errorResponse
+// @Failure		403		{object}	errorResponse
+// @Failure		422		{object}	errorResponse
+// @Failure		429		{object}	errorResponse
 // @Failure		500		{object}	errorResponse
+// @Security			ApiKeyAuth
 // @Router			/info [get]
 func (h *Handler) GetSongs(c *gin.Context) {
 	slog.Info("Начало обработки запроса GetSongs")
 
-	group := c.DefaultQuery("group", "")
-	group_id, err := strconv.Atoi(c.DefaultQuery("group_id", "-1"))
-	if err != nil {
-		slog.Error("Ошибка при парсинге group_id", "error", err)
-		newErrorResponce(c, http.StatusBadRequest, fmt.Sprintf("Invalid group id %v", group))
-		return
-	}
-
-	song := c.DefaultQuery("song", "")
-	id, err := strconv.Atoi(c.DefaultQuery("id", "-1"))
-	if err != nil {
-		slog.Error("Ошибка при парсинге id", "error", err)
-		newErrorResponce(c, http.StatusBadRequest, fmt.Sprintf("Invalid id %v", id))
+	filter, ok := songQueryFilter(c)
+	if !ok {
 		return
 	}
 
-	date := c.DefaultQuery("date", "0001-01-01")
-	link := c.DefaultQuery("link", "")
-	text := c.DefaultQuery("text", "")
-
 	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
 	if err != nil {
 		slog.Error("Ошибка при парсинге page", "error", err)
-		newErrorResponce(c, http.StatusBadRequest, fmt.Sprintf("Invalid page %v", page))
+		newErrorFromErr(c, errParam("page", "ожидается целое число, получено %q", c.Query("page")))
 		return
 	}
 
 	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
 	if err != nil {
 		slog.Error("Ошибка при парсинге limit", "error", err)
-		newErrorResponce(c, http.StatusBadRequest, fmt.Sprintf("Invalid limit %v", limit))
+		newErrorFromErr(c, errParam("limit", "ожидается целое число, получено %q", c.Query("limit")))
 		return
 	}
 
-	filter := model.Song{
-		ID:          &id,
-		SongName:    &song,
-		GroupId:     &group_id,
-		Group:       &group,
-		ReleaseDate: &date,
-		Link:        &link,
-		Text:        &text,
-	}
-
 	slog.Debug("Параметры фильтра", "filter", filter)
 
-	res, err := h.service.GetSongs(filter, page, limit)
+	res, err := h.service.GetSongs(filter, model.SongSort{}, page, limit)
 	if err != nil {
 		slog.Error("Ошибка при получении песен", "error", err)
-		newErrorResponce(c, http.StatusInternalServerError, err.Error())
+		newErrorFromErr(c, err)
 		return
 	}
 
@@ -99,6 +77,40 @@ func (h *Handler) GetSongs(c *gin.Context) {
 	c.AbortWithStatusJSON(200, res)
 }
 
+// songQueryFilter reads the song filter shared by the list and export
+// endpoints from the query string, answering 400 on malformed IDs.
+func songQueryFilter(c *gin.Context) (model.Song, bool) {
+	group := c.DefaultQuery("group", "")
+	group_id, err := strconv.Atoi(c.DefaultQuery("group_id", "-1"))
+	if err != nil {
+		slog.Error("Ошибка при парсинге group_id", "error", err)
+		newErrorFromErr(c, errParam("group_id", "ожидается целое число, получено %q", c.Query("group_id")))
+		return model.Song{}, false
+	}
+
+	song := c.DefaultQuery("song", "")
+	id, err := strconv.Atoi(c.DefaultQuery("id", "-1"))
+	if err != nil {
+		slog.Error("Ошибка при парсинге id", "error", err)
+		newErrorFromErr(c, errParam("id", "ожидается целое число, получено %q", c.Query("id")))
+		return model.Song{}, false
+	}
+
+	date := c.DefaultQuery("date", "0001-01-01")
+	link := c.DefaultQuery("link", "")
+	text := c.DefaultQuery("text", "")
+
+	return model.Song{
+		ID:          &id,
+		SongName:    &song,
+		GroupId:     &group_id,
+		Group:       &group,
+		ReleaseDate: &date,
+		Link:        &link,
+		Text:        &text,
+	}, true
+}
+
 // @Summary		Добавление новой песни
 // @Description	Добавление новой песни в базу данных (Обязательные параметры - song, group)
 // @Tags			songs
@@ -107,24 +119,31 @@ func (h *Handler) GetSongs(c *gin.Context) {
 // @Param song body Song true "Данные песни" default({ "group": "Muse", "song": "Supermassive Black Hole" })
 // @Success		200		{object}	resultResponse
 // @Failure		400		{object}	errorResponse
+// @Failure		401		{object}	errorResponse
+// @Failure		403		{object}	errorResponse
+// @Failure		404		{object}	errorResponse
+// @Failure		409		{object}	errorResponse
+// @Failure		422		{object}	errorResponse
+// @Failure		429		{object}	errorResponse
 // @Failure		500		{object}	errorResponse
+// @Failure		502		{object}	errorResponse
+// @Security			ApiKeyAuth
 // @Router			/songs [post]
 func (h *Handler) AddSong(c *gin.Context) {
 	slog.Info("Начало обработки запроса AddSong")
 
 	var song Song
 	if err := c.ShouldBindJSON(&song); err != nil {
-		slog.Error("Ошибка при парсинге JSON", "error", err)
-		newErrorResponce(c, http.StatusBadRequest, fmt.Sprintf("Parameter error: %v", err))
+		bindError(c, err)
 		return
 	}
 
 	slog.Debug("Данные песни", "song", song)
 
-	id, err := h.service.Add(song.SongName, song.Group)
+	id, err := h.as(c).Add(song.SongName, song.Group)
 	if err != nil {
 		slog.Error("Ошибка при добавлении песни", "error", err)
-		newErrorResponce(c, http.StatusInternalServerError, err.Error())
+		newErrorFromErr(c, err)
 		return
 	}
 
@@ -132,49 +151,55 @@ func (h *Handler) AddSong(c *gin.Context) {
 	c.AbortWithStatusJSON(200, resultResponse{
 		Status: "success",
 		Id:     id,
-		Text:   "Песня добавлена",
+		Text:   tr(c, "Песня добавлена"),
 	})
 }
 
-// @Summary Получение текста куплета песни
-// @Description Получение текста конкретного куплета
+// @Summary Получение куплетов песни
+// @Description Получение куплета с номером, общим числом куплетов, меткой раздела и ссылками на соседние куплеты. verse=2-4 возвращает диапазон куплетов, verse=all — все куплеты постранично (page, limit); в этих случаях ответ имеет вид versesResponse
 // @Tags songs
 // @Accept json
 // @Produce json
 // @Param song query string false "Название песни" default(Supermassive Black Hole)
 // @Param group query string false "Группа" default(Muse)
-// @Param verse query int false "Номер куплета" default(1)
-// @Success 200 {object} resultResponse
+// @Param verse query string false "Номер куплета, диапазон (2-4) или all" default(1)
+// @Param page query int false "Номер страницы для verse=all" default(1)
+// @Param limit query int false "Количество куплетов на странице для verse=all" default(10) minimum(1) maximum(100)
+// @Success 200 {object} verseResponse
+// @Header 200 {string} ETag "Версия песни"
 // @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 429 {object} errorResponse
 // @Failure 500 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 422 {object} errorResponse
+// @Security ApiKeyAuth
 // @Router /info/verse [get]
 func (h *Handler) GetSongVerse(c *gin.Context) {
 	slog.Info("Начало обработки запроса GetSongVerse")
 
 	group := c.DefaultQuery("group", "")
 	song_name := c.DefaultQuery("song", "")
-	verseNumber, err := strconv.Atoi(c.DefaultQuery("verse", "1"))
+	sel, err := parseVerseSelection(c)
 	if err != nil {
-		slog.Warn("Ошибка при парсинге verse, установлено значение по умолчанию", "verseNumber", 1)
-		verseNumber = 1
+		slog.Error("Ошибка при парсинге verse", "error", err)
+		newErrorFromErr(c, err)
+		return
 	}
 
-	slog.Debug("Параметры запроса", "group", group, "song_name", song_name, "verseNumber", verseNumber)
+	slog.Debug("Параметры запроса", "group", group, "song_name", song_name, "verse", c.Query("verse"))
 
 	song := model.Song{SongName: &song_name, Group: &group}
-	verse, id, err := h.service.GetSongVerse(song, verseNumber)
+	verses, found, err := h.service.GetSongVerses(song)
 	if err != nil {
 		slog.Error("Ошибка при получении куплета", "error", err)
-		newErrorResponce(c, http.StatusBadRequest, err.Error())
+		newErrorFromErr(c, err)
 		return
 	}
 
-	slog.Info("Куплет успешно получен", "id", id)
-	c.AbortWithStatusJSON(200, resultResponse{
-		Status: "success",
-		Id:     id,
-		Text:   verse,
-	})
+	slog.Info("Куплеты успешно получены", "id", *found.ID, "total", len(verses))
+	writeVerses(c, found, verses, sel)
 }
 
 // @Summary Удаление песни
@@ -182,34 +207,48 @@ func (h *Handler) GetSongVerse(c *gin.Context) {
 // @Tags songs
 // @Accept json
 // @Produce json
+// @Param If-Match header string false "Ожидаемая версия песни (ETag)"
 // @Param song body model.Song true "Данные песни для удаления"
 // @Success 200 {object} resultResponse
 // @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 429 {object} errorResponse
 // @Failure 500 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 412 {object} errorResponse
+// @Failure 422 {object} errorResponse
+// @Failure 428 {object} errorResponse
+// @Security ApiKeyAuth
 // @Router /songs [delete]
 func (h *Handler) DeleteSong(c *gin.Context) {
 	slog.Info("Начало обработки запроса DeleteSong")
 
 	var song model.Song
 	if err := c.ShouldBindJSON(&song); err != nil {
-		slog.Error("Ошибка при парсинге JSON", "error", err)
-		newErrorResponce(c, http.StatusUnprocessableEntity, "Ошибка парсинга структуры")
+		bindError(c, err)
 		return
 	}
 
 	slog.Debug("Данные песни для удаления", "song", song)
 
-	ok, err := h.service.DeleteSong(song)
-	if err != nil || !ok {
+	version, ok := requireVersion(c, song.Version)
+	if !ok {
+		return
+	}
+	song.Version = version
+
+	_, err := h.as(c).DeleteSong(song)
+	if err != nil {
 		slog.Error("Ошибка при удалении песни", "error", err)
-		newErrorResponce(c, http.StatusBadRequest, err.Error())
+		newErrorFromErr(c, err)
 		return
 	}
 
 	slog.Info("Песня успешно удалена", "song_name", *song.SongName)
 	c.AbortWithStatusJSON(200, resultResponse{
 		Status: "success",
-		Text:   "Удаление прошло успешно",
+		Text:   tr(c, "Удаление прошло успешно"),
 	})
 }
 
@@ -220,10 +259,21 @@ func (h *Handler) DeleteSong(c *gin.Context) {
 // @Produce json
 // @Param			song	query		string	false	"Название песни"			default(Supermassive Black Hole)
 // @Param			group	query		string	false	"Группа"			default(Muse)
+// @Param If-Match header string false "Ожидаемая версия песни (ETag)"
 // @Param song body model.Song true "Данные песни для обновления"
 // @Success 200 {object} resultResponse
+// @Header 200 {string} ETag "Новая версия песни"
 // @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 429 {object} errorResponse
 // @Failure 500 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 409 {object} errorResponse
+// @Failure 412 {object} errorResponse
+// @Failure 422 {object} errorResponse
+// @Failure 428 {object} errorResponse
+// @Security ApiKeyAuth
 // @Router /songs [put]
 func (h *Handler) UpdateSong(c *gin.Context) {
 	slog.Info("Начало обработки запроса UpdateSong")
@@ -235,23 +285,30 @@ func (h *Handler) UpdateSong(c *gin.Context) {
 
 	var song model.Song
 	if err := c.ShouldBindJSON(&song); err != nil {
-		slog.Error("Ошибка при парсинге JSON", "error", err)
-		newErrorResponce(c, http.StatusUnprocessableEntity, "Ошибка парсинга структуры")
+		bindError(c, err)
 		return
 	}
 
 	slog.Debug("Данные песни для обновления", "song", song)
 
-	ok, song, err := h.service.UpdateSong(song_name, group_name, song)
-	if err != nil || !ok {
+	version, ok := requireVersion(c, song.Version)
+	if !ok {
+		return
+	}
+	song.Version = version
+
+	_, song, err := h.as(c).UpdateSong(song_name, group_name, song)
+	if err != nil {
 		slog.Error("Ошибка при обновлении песни", "error", err)
-		newErrorResponce(c, http.StatusBadRequest, err.Error())
+		newErrorFromErr(c, err)
 		return
 	}
 
-	slog.Info("Информация о песне успешно обновлена", "song_name", *song.SongName, "id", *song.ID)
+	slog.Info("Информация о песне успешно обновлена", "song_name", song_name, "group_name", group_name, "version", *song.Version)
+	setETag(c, song.Version)
 	c.AbortWithStatusJSON(200, resultResponse{
 		Status: "success",
-		Text:   "Обновление прошло успешно",
+		Id:     *song.ID,
+		Text:   tr(c, "Обновление прошло успешно"),
 	})
 }
@@ -0,0 +1,315 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+// Songbook document formats.
+const (
+	songbookMarkdown = "markdown"
+	songbookHTML     = "html"
+)
+
+const defaultSongbookTitle = "Сборник песен"
+
+// sectionNames are the headings of unlabeled verses by type; plain verses get
+// none.
+var sectionNames = map[string]string{
+	model.VerseTypeChorus: "Припев",
+	model.VerseTypeBridge: "Бридж",
+	model.VerseTypeIntro:  "Вступление",
+}
+
+// songbookView is a songbook prepared for rendering.
+type songbookView struct {
+	Title  string
+	Groups []songbookGroupView
+}
+
+type songbookGroupView struct {
+	Anchor string
+	Name   string
+	Songs  []songbookSongView
+}
+
+type songbookSongView struct {
+	Anchor      string
+	Title       string
+	ReleaseDate string
+	Verses      []songbookVerseView
+}
+
+type songbookVerseView struct {
+	Label  string
+	Chorus bool
+	Repeat int
+	Lines  []string
+}
+
+func newSongbookView(title string, groups []model.SongbookGroup) songbookView {
+	view := songbookView{Title: title, Groups: make([]songbookGroupView, len(groups))}
+	for i, group := range groups {
+		g := songbookGroupView{Anchor: fmt.Sprintf("group-%d", i+1), Name: group.Name}
+		for _, song := range group.Songs {
+			s := songbookSongView{
+				Anchor:      "song-" + strconv.Itoa(*song.ID),
+				Title:       valueOrEmpty(song.SongName),
+				ReleaseDate: valueOrEmpty(song.ReleaseDate),
+			}
+			for _, verse := range song.Verses {
+				kind := valueOrEmpty(verse.Type)
+				label := valueOrEmpty(verse.Label)
+				if label == "" {
+					label = sectionNames[kind]
+				}
+				repeat := 1
+				if verse.Repeat != nil {
+					repeat = *verse.Repeat
+				}
+				s.Verses = append(s.Verses, songbookVerseView{
+					Label:  label,
+					Chorus: kind == model.VerseTypeChorus,
+					Repeat: repeat,
+					Lines:  strings.Split(valueOrEmpty(verse.Text), "\n"),
+				})
+			}
+			g.Songs = append(g.Songs, s)
+		}
+		view.Groups[i] = g
+	}
+	return view
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`,
+	`<`, `\<`, `>`, `\>`, `#`, `\#`, `|`, `\|`,
+)
+
+// markdownText escapes s so that it renders as plain text at the start of a
+// Markdown line.
+func markdownText(s string) string {
+	s = markdownEscaper.Replace(s)
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") || strings.HasPrefix(s, "=") {
+		return `\` + s
+	}
+	digits := len(s) - len(strings.TrimLeft(s, "0123456789"))
+	if digits > 0 && digits < len(s) && (s[digits] == '.' || s[digits] == ')') {
+		return s[:digits] + `\` + s[digits:]
+	}
+	return s
+}
+
+// renderSongbookMarkdown writes the songbook as CommonMark. Choruses are set
+// as block quotes; anchors are HTML so table of contents links do not depend
+// on the renderer's heading IDs.
+func renderSongbookMarkdown(w io.Writer, view songbookView) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n## Содержание\n\n", markdownText(view.Title))
+	for _, group := range view.Groups {
+		fmt.Fprintf(&b, "- [%s](#%s)\n", markdownText(group.Name), group.Anchor)
+		for _, song := range group.Songs {
+			fmt.Fprintf(&b, "  - [%s](#%s) — %s\n", markdownText(song.Title), song.Anchor, song.ReleaseDate)
+		}
+	}
+	for _, group := range view.Groups {
+		fmt.Fprintf(&b, "\n<a id=\"%s\"></a>\n\n## %s\n", group.Anchor, markdownText(group.Name))
+		for _, song := range group.Songs {
+			fmt.Fprintf(&b, "\n<a id=\"%s\"></a>\n\n### %s\n\n*Дата выпуска: %s*\n", song.Anchor, markdownText(song.Title), song.ReleaseDate)
+			for _, verse := range song.Verses {
+				prefix := ""
+				if verse.Chorus {
+					prefix = "> "
+				}
+				b.WriteString("\n")
+				label := markdownText(verse.Label)
+				if verse.Repeat > 1 {
+					label = strings.TrimSpace(fmt.Sprintf("%s ×%d", label, verse.Repeat))
+				}
+				if label != "" {
+					b.WriteString(prefix + "**" + label + "**\n" + strings.TrimSpace(prefix) + "\n")
+				}
+				for i, line := range verse.Lines {
+					b.WriteString(prefix + markdownText(line))
+					if i < len(verse.Lines)-1 {
+						b.WriteString(`\`)
+					}
+					b.WriteString("\n")
+				}
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var songbookTemplate = template.Must(template.New("songbook").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: Georgia, serif; max-width: 42em; margin: 2em auto; padding: 0 1em; line-height: 1.4; color: #222; }
+h1 { text-align: center; }
+nav ul { list-style: none; padding-left: 1em; }
+nav a { color: inherit; }
+h2 { border-bottom: 2px solid #444; margin-top: 2.5em; }
+.date { color: #666; font-style: italic; margin-top: -0.8em; }
+.verse { margin: 1em 0; }
+.verse p { margin: 0; }
+.label { display: block; font-weight: bold; }
+.chorus { border-left: 4px solid #c33; background: #fbf1f1; padding: 0.3em 0.8em; font-weight: 600; }
+.chorus .label { color: #c33; }
+@media print {
+  body { margin: 0; max-width: none; }
+  h2 { break-before: page; }
+  section.song { break-inside: avoid; }
+  .chorus { background: none; }
+}
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<nav>
+<h2 style="break-before: auto">Содержание</h2>
+<ul>
+{{- range .Groups}}
+<li><a href="#{{.Anchor}}">{{.Name}}</a>
+<ul>
+{{- range .Songs}}
+<li><a href="#{{.Anchor}}">{{.Title}}</a> — {{.ReleaseDate}}</li>
+{{- end}}
+</ul>
+</li>
+{{- end}}
+</ul>
+</nav>
+{{- range .Groups}}
+<h2 id="{{.Anchor}}">{{.Name}}</h2>
+{{- range .Songs}}
+<section class="song" id="{{.Anchor}}">
+<h3>{{.Title}}</h3>
+<p class="date">Дата выпуска: {{.ReleaseDate}}</p>
+{{- range .Verses}}
+<div class="verse{{if .Chorus}} chorus{{end}}">
+{{- if or .Label (gt .Repeat 1)}}
+<span class="label">{{.Label}}{{if gt .Repeat 1}} ×{{.Repeat}}{{end}}</span>
+{{- end}}
+<p>{{range $i, $line := .Lines}}{{if $i}}<br>
+{{end}}{{$line}}{{end}}</p>
+</div>
+{{- end}}
+</section>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+func renderSongbookHTML(w io.Writer, view songbookView) error {
+	return songbookTemplate.Execute(w, view)
+}
+
+// parseIDs reads a comma-separated list of positive IDs, dropping repeats.
+// An empty list yields nil.
+func parseIDs(raw string) ([]int, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var ids []int
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(raw, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || id < 1 {
+			return nil, errParam("ids", "ожидаются положительные целые числа через запятую, получено %q", part)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// @Summary Сборник песен
+// @Description Сборник выбранных песен одним документом Markdown или HTML (самостоятельная страница, готовая к печати): оглавление, разделы по группам, даты выпуска и куплеты по сохранённому разбиению, припевы выделены. Песни выбираются списком ids и/или фильтрами /info; в сборник входит не больше 500 песен
+// @Tags songbook
+// @Produce text/markdown,text/html
+// @Param ids query string false "ID песен через запятую" example(1,2,3)
+// @Param format query string false "Формат документа" Enums(markdown, html) default(markdown)
+// @Param title query string false "Заголовок сборника" default(Сборник песен)
+// @Param group_id query int false "ID группы"
+// @Param group query string false "Группа"
+// @Param song query string false "Название песни"
+// @Param link query string false "Ссылка на клип"
+// @Param text query string false "Фрагмент текста песни"
+// @Param date query string false "Выпущены после даты" example(19.07.2006)
+// @Success 200 {string} string "Документ сборника"
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 422 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /v1/songs/songbook [get]
+// @Router /v2/songs/songbook [get]
+func (h *Handler) GetSongbook(c *gin.Context) {
+	slog.Info("Начало обработки запроса GetSongbook")
+
+	filter, ok := songQueryFilter(c)
+	if !ok {
+		return
+	}
+	ids, err := parseIDs(c.Query("ids"))
+	if err != nil {
+		slog.Error("Ошибка при парсинге ids", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+	var render func(io.Writer, songbookView) error
+	var contentType, ext string
+	format := c.DefaultQuery("format", songbookMarkdown)
+	switch format {
+	case songbookMarkdown:
+		render, contentType, ext = renderSongbookMarkdown, "text/markdown; charset=utf-8", "md"
+	case songbookHTML:
+		render, contentType, ext = renderSongbookHTML, "text/html; charset=utf-8", "html"
+	default:
+		newErrorFromErr(c, errParam("format", "допустимы %s и %s, получено %q", songbookMarkdown, songbookHTML, format))
+		return
+	}
+	title := strings.TrimSpace(c.DefaultQuery("title", defaultSongbookTitle))
+	if title == "" {
+		title = defaultSongbookTitle
+	}
+
+	groups, err := h.service.Songbook(filter, ids)
+	if err != nil {
+		slog.Error("Ошибка при составлении сборника", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := render(&buf, newSongbookView(title, groups)); err != nil {
+		slog.Error("Ошибка при оформлении сборника", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+
+	slog.Info("Сборник успешно составлен", "format", format, "groups", len(groups))
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=\"songbook.%s\"", ext))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+	c.Abort()
+}
@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type credentialsRequest struct {
+	Login    string `json:"login" binding:"required" example:"editor"`
+	Password string `json:"password" binding:"required" example:"correct horse battery"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required" example:"Zk3ZC1hYjM0LTQ3N2EtYjQ0Ny0xN2Q2YzVmMzY4ZWE"`
+}
+
+type roleRequest struct {
+	Role string `json:"role" binding:"required" example:"editor"`
+}
+
+// @Summary Регистрация
+// @Description Создание учётной записи с ролью viewer. Более сильные роли выдаёт администратор
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param user body credentialsRequest true "Логин и пароль"
+// @Success 201 {object} model.User
+// @Failure 400 {object} errorResponse
+// @Failure 409 {object} errorResponse
+// @Failure 422 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Router /auth/register [post]
+func (h *Handler) Register(c *gin.Context) {
+	slog.Info("Начало обработки запроса Register")
+
+	var req credentialsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	user, err := h.service.Register(req.Login, req.Password)
+	if err != nil {
+		slog.Error("Ошибка при регистрации", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+
+	slog.Info("Пользователь зарегистрирован", "id", user.ID)
+	c.AbortWithStatusJSON(http.StatusCreated, user)
+}
+
+// @Summary Вход
+// @Description Выдача токена доступа (JWT) и refresh-токена по логину и паролю
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param user body credentialsRequest true "Логин и пароль"
+// @Success 200 {object} model.TokenPair
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Router /auth/login [post]
+func (h *Handler) Login(c *gin.Context) {
+	slog.Info("Начало обработки запроса Login")
+
+	var req credentialsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	tokens, err := h.service.Login(req.Login, req.Password)
+	if err != nil {
+		slog.Error("Ошибка при входе", "login", req.Login, "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+
+	slog.Info("Пользователь вошёл", "login", req.Login)
+	c.Header("Cache-Control", "no-store")
+	c.AbortWithStatusJSON(http.StatusOK, tokens)
+}
+
+// @Summary Обновление токенов
+// @Description Обмен refresh-токена на новую пару токенов. Старый refresh-токен перестаёт действовать; повторное его использование завершает все сессии пользователя
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body refreshRequest true "Refresh-токен"
+// @Success 200 {object} model.TokenPair
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Router /auth/refresh [post]
+func (h *Handler) Refresh(c *gin.Context) {
+	slog.Info("Начало обработки запроса Refresh")
+
+	var req refreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	tokens, err := h.service.Refresh(req.RefreshToken)
+	if err != nil {
+		slog.Error("Ошибка при обновлении токенов", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.AbortWithStatusJSON(http.StatusOK, tokens)
+}
+
+// @Summary Выход
+// @Description Отзыв refresh-токена. Выданный токен доступа действует до истечения срока
+// @Tags auth
+// @Accept json
+// @Param token body refreshRequest true "Refresh-токен"
+// @Success 204
+// @Failure 400 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Router /auth/logout [post]
+func (h *Handler) Logout(c *gin.Context) {
+	slog.Info("Начало обработки запроса Logout")
+
+	var req refreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	if err := h.service.Logout(req.RefreshToken); err != nil {
+		slog.Error("Ошибка при выходе", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+	c.AbortWithStatus(http.StatusNoContent)
+}
+
+// @Summary Текущий пользователь
+// @Description Кто выполняет запрос: пользователь или API-ключ и его роль
+// @Tags auth
+// @Produce json
+// @Success 200 {object} model.Principal
+// @Failure 401 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /auth/me [get]
+func (h *Handler) Me(c *gin.Context) {
+	c.AbortWithStatusJSON(http.StatusOK, principal(c))
+}
+
+// @Summary Список пользователей
+// @Tags users
+// @Produce json
+// @Success 200 {object} []model.User
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /users [get]
+func (h *Handler) ListUsers(c *gin.Context) {
+	slog.Info("Начало обработки запроса ListUsers")
+
+	users, err := h.service.ListUsers()
+	if err != nil {
+		slog.Error("Ошибка при получении пользователей", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusOK, users)
+}
+
+// @Summary Изменение роли
+// @Description Назначение пользователю роли viewer, editor или admin. Новая роль действует с выдачей следующего токена доступа
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "ID пользователя"
+// @Param role body roleRequest true "Роль"
+// @Success 200 {object} model.User
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 422 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /users/{id}/role [put]
+func (h *Handler) SetUserRole(c *gin.Context) {
+	slog.Info("Начало обработки запроса SetUserRole")
+
+	id, ok := intParam(c, "id")
+	if !ok {
+		return
+	}
+	var req roleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	user, err := h.service.SetUserRole(id, req.Role)
+	if err != nil {
+		slog.Error("Ошибка при изменении роли", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+
+	slog.Info("Роль пользователя изменена", "id", id, "role", user.Role, "by", principal(c).Actor())
+	c.AbortWithStatusJSON(http.StatusOK, user)
+}
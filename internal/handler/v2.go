@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Получение песни
+// @Description Получение всех данных песни по ID
+// @Tags v2
+// @Produce json
+// @Param id path int true "ID песни"
+// @Success 200 {object} songV2
+// @Header 200 {string} ETag "Версия песни"
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /v2/songs/{id} [get]
+func (h *Handler) GetSongV2(c *gin.Context) {
+	slog.Info("Начало обработки запроса GetSongV2")
+	h.getSong(c, songCodecV2)
+}
+
+// @Summary Добавление песни
+// @Description Добавление новой песни с обогащением из внешнего API. Возвращает созданную песню
+// @Tags v2
+// @Accept json
+// @Produce json
+// @Param song body newSongV2Request true "Название и исполнитель"
+// @Success 201 {object} songV2
+// @Header 201 {string} Location "Адрес созданной песни"
+// @Header 201 {string} ETag "Версия песни"
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 409 {object} errorResponse
+// @Failure 422 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure 502 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /v2/songs [post]
+func (h *Handler) CreateSongV2(c *gin.Context) {
+	slog.Info("Начало обработки запроса CreateSongV2")
+	h.createSong(c, songCodecV2)
+}
+
+// @Summary Замена песни
+// @Description Полная замена данных песни: нужно передать название, исполнителя, дату выпуска, текст и ссылку
+// @Tags v2
+// @Accept json
+// @Produce json
+// @Param id path int true "ID песни"
+// @Param If-Match header string false "Ожидаемая версия песни (ETag)"
+// @Param song body songV2Request true "Новые данные песни"
+// @Success 200 {object} songV2
+// @Header 200 {string} Location "Адрес песни"
+// @Header 200 {string} ETag "Новая версия песни"
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 409 {object} errorResponse
+// @Failure 412 {object} errorResponse
+// @Failure 422 {object} errorResponse
+// @Failure 428 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /v2/songs/{id} [put]
+func (h *Handler) ReplaceSongV2(c *gin.Context) {
+	slog.Info("Начало обработки запроса ReplaceSongV2")
+	h.writeSong(c, songCodecV2, h.as(c).ReplaceSong)
+}
+
+// @Summary Частичное обновление песни
+// @Description С application/json обновляются переданные поля песни. application/merge-patch+json (RFC 7396) и application/json-patch+json (RFC 6902) применяются к песне целиком: так можно очистить текст или ссылку (null / remove). Для патчей версия передаётся только в If-Match
+// @Tags v2
+// @Accept json,application/merge-patch+json,application/json-patch+json
+// @Produce json
+// @Param id path int true "ID песни"
+// @Param If-Match header string false "Ожидаемая версия песни (ETag)"
+// @Param song body songV2Request true "Изменяемые поля песни"
+// @Success 200 {object} songV2
+// @Header 200 {string} Location "Адрес песни"
+// @Header 200 {string} ETag "Новая версия песни"
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 409 {object} errorResponse
+// @Failure 412 {object} errorResponse
+// @Failure 422 {object} errorResponse
+// @Failure 415 {object} errorResponse
+// @Failure 428 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /v2/songs/{id} [patch]
+func (h *Handler) PatchSongV2(c *gin.Context) {
+	slog.Info("Начало обработки запроса PatchSongV2")
+	h.patchSong(c, songCodecV2)
+}
+
+// @Summary Песни группы
+// @Description Получение песен группы по ID группы с пагинацией
+// @Tags v2
+// @Produce json
+// @Param id path int true "ID группы"
+// @Param page query int false "Номер страницы" default(1)
+// @Param limit query int false "Количество на странице" default(10)
+// @Success 200 {object} []songV2
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /v2/groups/{id}/songs [get]
+func (h *Handler) GetGroupSongsV2(c *gin.Context) {
+	slog.Info("Начало обработки запроса GetGroupSongsV2")
+	h.getGroupSongs(c, songCodecV2)
+}
+
+// @Summary Экспорт песен
+// @Description Выгрузка всех песен, подходящих под фильтры (те же, что у /info), в формате NDJSON, CSV или JSON. Песни передаются потоком в порядке ID, поэтому выгрузка не зависит от размера каталога. При lyrics=false тексты песен не выгружаются
+// @Tags v2
+// @Produce json,text/csv,application/x-ndjson
+// @Param format query string false "Формат выгрузки" Enums(ndjson, csv, json) default(ndjson)
+// @Param lyrics query bool false "Выгружать тексты песен" default(true)
+// @Param id query int false "ID песни"
+// @Param group_id query int false "ID группы"
+// @Param song query string false "Название песни"
+// @Param group query string false "Группа"
+// @Param link query string false "Ссылка на клип"
+// @Param text query string false "Фрагмент текста песни"
+// @Param date query string false "Выпущены после даты" example(19.07.2006)
+// @Success 200 {array} songV2
+// @Header 200 {string} Content-Disposition "Имя файла выгрузки"
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /v2/songs/export [get]
+func (h *Handler) ExportSongsV2(c *gin.Context) {
+	slog.Info("Начало обработки запроса ExportSongsV2")
+	h.exportSongs(c, songCodecV2)
+}
+
+// @Summary Лента изменений песен
+// @Description Поток Server-Sent Events о добавлении (song.created), изменении (song.updated) и удалении (song.deleted) песен с изменёнными полями. События хранятся в журнале: после переподключения с Last-Event-ID поток продолжается с пропущенных событий, без него — с новых
+// @Tags v2
+// @Produce text/event-stream
+// @Param Last-Event-ID header int false "ID последнего полученного события"
+// @Param last_event_id query int false "ID последнего полученного события, если заголовок задать нельзя"
+// @Param group_id query int false "ID группы"
+// @Param group query string false "Группа"
+// @Success 200 {object} songEventResponse
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /v2/songs/events [get]
+func (h *Handler) SongEventsV2(c *gin.Context) {
+	slog.Info("Начало обработки запроса SongEventsV2")
+	h.songEvents(c, songCodecV2)
+}
+
+// @Summary Пакетное изменение песен
+// @Description Добавление (add), изменение (update) и удаление (delete) песен одним запросом, по порядку. В song передаётся то же тело, что и в POST /v2/songs для добавления или PATCH /v2/songs/{id} для изменения; изменение и удаление требуют версию песни в поле version, удаление — роль admin. С atomic=true операции фиксируются вместе или не фиксируются вовсе: если хотя бы одна завершилась ошибкой, остальные отменяются со статусом 424. Результат каждой операции содержит статус и песню или ошибку, которыми ответил бы соответствующий одиночный запрос
+// @Tags v2
+// @Accept json
+// @Produce json
+// @Param batch body batchRequest true "Операции"
+// @Success 200 {object} batchResponse
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 422 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /v2/songs/batch [post]
+func (h *Handler) SongsBatchV2(c *gin.Context) {
+	slog.Info("Начало обработки запроса SongsBatchV2")
+	h.songsBatch(c, songCodecV2)
+}
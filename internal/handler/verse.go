@@ -0,0 +1,414 @@
+package handler
+
+import (
+	"log/slog"
+	"strconv"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+type verseRequest struct {
+	Ordinal *int    `json:"ordinal,omitempty" example:"2"`
+	Type    *string `json:"type,omitempty" example:"chorus"`
+	Text    *string `json:"text,omitempty" example:"Ooh baby, don't you know I suffer?\nOoh baby, can you hear me moan?"`
+	Label   *string `json:"label,omitempty" example:"Chorus"`
+	Repeat  *int    `json:"repeat,omitempty" example:"2"`
+	Version *int    `json:"version,omitempty" example:"1"`
+}
+
+func (r verseRequest) verse() model.Verse {
+	return model.Verse{Ordinal: r.Ordinal, Type: r.Type, Text: r.Text, Label: r.Label, Repeat: r.Repeat}
+}
+
+type sectionsResponse struct {
+	SongID   int           `json:"song_id" example:"1"`
+	Parser   string        `json:"parser" example:"labeled"`
+	Sections []model.Verse `json:"sections"`
+}
+
+type phraseResponse struct {
+	Status  string              `json:"status" example:"success"`
+	Id      int                 `json:"id" example:"1"`
+	Phrase  string              `json:"phrase" example:"dont you know"`
+	Matches []model.PhraseMatch `json:"matches"`
+}
+
+type verseOrderRequest struct {
+	Order   []int `json:"order" binding:"required" example:"2,1,3"`
+	Version *int  `json:"version,omitempty" example:"1"`
+}
+
+// @Summary Получение куплетов песни
+// @Description Получение всех куплетов песни по порядку
+// @Tags verses
+// @Produce json
+// @Param id path int true "ID песни"
+// @Success 200 {object} []model.Verse
+// @Header 200 {string} ETag "Версия песни"
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /songs/{id}/verses [get]
+// @Router /v1/songs/{id}/verses [get]
+// @Router /v2/songs/{id}/verses [get]
+func (h *Handler) GetVerses(c *gin.Context) {
+	slog.Info("Начало обработки запроса GetVerses")
+
+	songID, ok := intParam(c, "id")
+	if !ok {
+		return
+	}
+
+	verses, song, err := h.service.GetVerses(songID)
+	if err != nil {
+		slog.Error("Ошибка при получении куплетов", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+
+	slog.Info("Куплеты успешно получены", "song_id", songID, "count", len(verses))
+	setETag(c, song.Version)
+	c.AbortWithStatusJSON(200, verses)
+}
+
+// @Summary Добавление куплета
+// @Description Вставка куплета на указанную позицию (по умолчанию в конец песни)
+// @Tags verses
+// @Accept json
+// @Produce json
+// @Param id path int true "ID песни"
+// @Param If-Match header string false "Ожидаемая версия песни (ETag)"
+// @Param verse body verseRequest true "Куплет"
+// @Success 200 {object} model.Verse
+// @Header 200 {string} ETag "Новая версия песни"
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 412 {object} errorResponse
+// @Failure 422 {object} errorResponse
+// @Failure 428 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /songs/{id}/verses [post]
+// @Router /v1/songs/{id}/verses [post]
+// @Router /v2/songs/{id}/verses [post]
+func (h *Handler) InsertVerse(c *gin.Context) {
+	slog.Info("Начало обработки запроса InsertVerse")
+
+	songID, ok := intParam(c, "id")
+	if !ok {
+		return
+	}
+	var req verseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	version, ok := requireVersion(c, req.Version)
+	if !ok {
+		return
+	}
+
+	verse, song, err := h.as(c).InsertVerse(songID, version, req.verse())
+	if err != nil {
+		slog.Error("Ошибка при добавлении куплета", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+
+	slog.Info("Куплет успешно добавлен", "song_id", songID, "ordinal", *verse.Ordinal)
+	setETag(c, song.Version)
+	c.AbortWithStatusJSON(200, verse)
+}
+
+// @Summary Изменение куплета
+// @Description Изменение типа, текста и/или метки куплета с указанным номером
+// @Tags verses
+// @Accept json
+// @Produce json
+// @Param id path int true "ID песни"
+// @Param n path int true "Номер куплета"
+// @Param If-Match header string false "Ожидаемая версия песни (ETag)"
+// @Param verse body verseRequest true "Новые данные куплета"
+// @Success 200 {object} model.Verse
+// @Header 200 {string} ETag "Новая версия песни"
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 412 {object} errorResponse
+// @Failure 422 {object} errorResponse
+// @Failure 428 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /songs/{id}/verses/{n} [put]
+// @Router /v1/songs/{id}/verses/{n} [put]
+// @Router /v2/songs/{id}/verses/{n} [put]
+func (h *Handler) UpdateVerse(c *gin.Context) {
+	slog.Info("Начало обработки запроса UpdateVerse")
+
+	songID, ok := intParam(c, "id")
+	if !ok {
+		return
+	}
+	ordinal, ok := intParam(c, "n")
+	if !ok {
+		return
+	}
+	var req verseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	version, ok := requireVersion(c, req.Version)
+	if !ok {
+		return
+	}
+
+	update := req.verse()
+	update.Ordinal = &ordinal
+	verse, song, err := h.as(c).UpdateVerse(songID, version, update)
+	if err != nil {
+		slog.Error("Ошибка при обновлении куплета", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+
+	slog.Info("Куплет успешно обновлён", "song_id", songID, "ordinal", ordinal)
+	setETag(c, song.Version)
+	c.AbortWithStatusJSON(200, verse)
+}
+
+// @Summary Удаление куплета
+// @Description Удаление куплета с указанным номером, следующие куплеты сдвигаются
+// @Tags verses
+// @Produce json
+// @Param id path int true "ID песни"
+// @Param n path int true "Номер куплета"
+// @Param If-Match header string true "Ожидаемая версия песни (ETag)"
+// @Success 200 {object} resultResponse
+// @Header 200 {string} ETag "Новая версия песни"
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 412 {object} errorResponse
+// @Failure 428 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /songs/{id}/verses/{n} [delete]
+// @Router /v1/songs/{id}/verses/{n} [delete]
+// @Router /v2/songs/{id}/verses/{n} [delete]
+func (h *Handler) DeleteVerse(c *gin.Context) {
+	slog.Info("Начало обработки запроса DeleteVerse")
+
+	songID, ok := intParam(c, "id")
+	if !ok {
+		return
+	}
+	ordinal, ok := intParam(c, "n")
+	if !ok {
+		return
+	}
+	version, ok := requireVersion(c, nil)
+	if !ok {
+		return
+	}
+
+	song, err := h.as(c).DeleteVerse(songID, version, ordinal)
+	if err != nil {
+		slog.Error("Ошибка при удалении куплета", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+
+	slog.Info("Куплет успешно удалён", "song_id", songID, "ordinal", ordinal)
+	setETag(c, song.Version)
+	c.AbortWithStatusJSON(200, resultResponse{
+		Status: "success",
+		Id:     songID,
+		Text:   tr(c, "Куплет удалён"),
+	})
+}
+
+// @Summary Изменение порядка куплетов
+// @Description Перестановка куплетов: order[i] — текущий номер куплета, который станет i+1-м
+// @Tags verses
+// @Accept json
+// @Produce json
+// @Param id path int true "ID песни"
+// @Param If-Match header string false "Ожидаемая версия песни (ETag)"
+// @Param order body verseOrderRequest true "Новый порядок куплетов"
+// @Success 200 {object} []model.Verse
+// @Header 200 {string} ETag "Новая версия песни"
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 412 {object} errorResponse
+// @Failure 422 {object} errorResponse
+// @Failure 428 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /songs/{id}/verses/order [put]
+// @Router /v1/songs/{id}/verses/order [put]
+// @Router /v2/songs/{id}/verses/order [put]
+func (h *Handler) ReorderVerses(c *gin.Context) {
+	slog.Info("Начало обработки запроса ReorderVerses")
+
+	songID, ok := intParam(c, "id")
+	if !ok {
+		return
+	}
+	var req verseOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+	version, ok := requireVersion(c, req.Version)
+	if !ok {
+		return
+	}
+
+	verses, song, err := h.as(c).ReorderVerses(songID, version, req.Order)
+	if err != nil {
+		slog.Error("Ошибка при изменении порядка куплетов", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+
+	slog.Info("Порядок куплетов успешно изменён", "song_id", songID)
+	setETag(c, song.Version)
+	c.AbortWithStatusJSON(200, verses)
+}
+
+// @Summary Получение песни по разделам
+// @Description Песня в виде списка размеченных разделов (припев, куплет, бридж...). Без parser возвращаются сохранённые разделы, с parser — текст разбирается указанным парсером без сохранения
+// @Tags verses
+// @Produce json
+// @Param id path int true "ID песни"
+// @Param parser query string false "Парсер разделов" Enums(plain, labeled)
+// @Success 200 {object} sectionsResponse
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 422 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /songs/{id}/sections [get]
+// @Router /v1/songs/{id}/sections [get]
+// @Router /v2/songs/{id}/sections [get]
+func (h *Handler) GetSections(c *gin.Context) {
+	slog.Info("Начало обработки запроса GetSections")
+
+	songID, ok := intParam(c, "id")
+	if !ok {
+		return
+	}
+
+	sections, parser, err := h.service.GetSections(songID, c.Query("parser"))
+	if err != nil {
+		slog.Error("Ошибка при получении разделов песни", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+
+	slog.Info("Разделы песни успешно получены", "song_id", songID, "parser", parser, "count", len(sections))
+	c.AbortWithStatusJSON(200, sectionsResponse{
+		SongID:   songID,
+		Parser:   parser,
+		Sections: sections,
+	})
+}
+
+// @Summary Поиск фразы в куплетах песни
+// @Description Поиск строки по фразе: возвращает номера куплетов, строк и смещения (в символах) совпадений. Песня задаётся ID или названием и группой
+// @Tags verses
+// @Produce json
+// @Param id query int false "ID песни"
+// @Param song query string false "Название песни" default(Supermassive Black Hole)
+// @Param group query string false "Группа" default(Muse)
+// @Param q query string true "Фраза"
+// @Param fold query bool false "Без учёта регистра и диакритики" default(false)
+// @Param fuzzy query int false "Допустимое число опечаток" default(0)
+// @Success 200 {object} phraseResponse
+// @Header 200 {string} ETag "Версия песни"
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 422 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /info/verse/search [get]
+func (h *Handler) FindPhrase(c *gin.Context) {
+	slog.Info("Начало обработки запроса FindPhrase")
+
+	var key model.Song
+	if raw := c.Query("id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			slog.Error("Ошибка при парсинге id", "error", err)
+			newErrorFromErr(c, errParam("id", "ожидается целое число, получено %q", raw))
+			return
+		}
+		key.ID = &id
+	} else if song_name, group := c.Query("song"), c.Query("group"); song_name != "" && group != "" {
+		key.SongName, key.Group = &song_name, &group
+	}
+
+	query := model.PhraseQuery{Phrase: c.Query("q")}
+	var err error
+	if query.Fold, err = strconv.ParseBool(c.DefaultQuery("fold", "false")); err != nil {
+		slog.Error("Ошибка при парсинге fold", "error", err)
+		newErrorFromErr(c, errParam("fold", "ожидается true или false, получено %q", c.Query("fold")))
+		return
+	}
+	if query.Fuzzy, err = strconv.Atoi(c.DefaultQuery("fuzzy", "0")); err != nil {
+		slog.Error("Ошибка при парсинге fuzzy", "error", err)
+		newErrorFromErr(c, errParam("fuzzy", "ожидается целое число, получено %q", c.Query("fuzzy")))
+		return
+	}
+
+	slog.Debug("Параметры запроса", "song", key, "query", query)
+
+	matches, song, err := h.service.FindPhrase(key, query)
+	if err != nil {
+		slog.Error("Ошибка при поиске фразы", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+
+	slog.Info("Поиск фразы выполнен", "id", *song.ID, "matches", len(matches))
+	setETag(c, song.Version)
+	c.AbortWithStatusJSON(200, phraseResponse{
+		Status:  "success",
+		Id:      *song.ID,
+		Phrase:  query.Phrase,
+		Matches: matches,
+	})
+}
+
+// intParam reads a positive integer path parameter, answering 400 otherwise.
+func intParam(c *gin.Context, name string) (int, bool) {
+	value, err := strconv.Atoi(c.Param(name))
+	if err != nil || value < 1 {
+		slog.Error("Ошибка при парсинге параметра пути", "param", name, "value", c.Param(name))
+		newErrorFromErr(c, errParam(name, "ожидается положительное целое число, получено %q", c.Param(name)))
+		return 0, false
+	}
+	return value, true
+}
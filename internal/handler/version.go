@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// apiVersion is a route set served under its own prefix. Every version talks to
+// the same service layer and differs only in its DTOs; setting deprecation
+// starts retiring the whole version.
+type apiVersion struct {
+	name        string
+	deprecation *deprecation
+	routes      func(h *Handler, g roleGroups)
+}
+
+func apiVersions() []apiVersion {
+	return []apiVersion{
+		{name: "v1", routes: (*Handler).v1Routes},
+		{name: "v2", routes: (*Handler).v2Routes},
+	}
+}
+
+// versionHeaders tells the client which API version answered and whether it
+// is being retired.
+func versionHeaders(v apiVersion) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("API-Version", v.name)
+		if v.deprecation != nil {
+			v.deprecation.setHeaders(c)
+		}
+		c.Next()
+	}
+}
+
+func (h *Handler) v1Routes(g roleGroups) {
+	g.editor.POST("/songs", h.CreateSong)
+	g.editor.POST("/songs/batch", h.SongsBatch)
+	g.admin.POST("/songs/import", h.ImportSongs)
+	g.viewer.GET("/songs/export", h.ExportSongs)
+	g.viewer.GET("/songs/songbook", h.GetSongbook)
+	g.viewer.GET("/songs/events", h.SongEvents)
+	g.viewer.GET("/songs/:id", h.GetSongByID)
+	g.editor.PUT("/songs/:id", h.ReplaceSong)
+	g.editor.PATCH("/songs/:id", h.PatchSong)
+	g.admin.DELETE("/songs/:id", h.DeleteSongByID)
+	g.viewer.GET("/songs/:id/sections", h.GetSections)
+	h.verseRoutes(g.group("/songs/:id/verses"))
+	g.viewer.GET("/groups/:id/songs", h.GetGroupSongs)
+}
+
+func (h *Handler) v2Routes(g roleGroups) {
+	g.editor.POST("/songs", h.CreateSongV2)
+	g.editor.POST("/songs/batch", h.SongsBatchV2)
+	g.admin.POST("/songs/import", h.ImportSongs)
+	g.viewer.GET("/songs/export", h.ExportSongsV2)
+	g.viewer.GET("/songs/songbook", h.GetSongbook)
+	g.viewer.GET("/songs/events", h.SongEventsV2)
+	g.viewer.GET("/songs/:id", h.GetSongV2)
+	g.editor.PUT("/songs/:id", h.ReplaceSongV2)
+	g.editor.PATCH("/songs/:id", h.PatchSongV2)
+	g.admin.DELETE("/songs/:id", h.DeleteSongByID)
+	g.viewer.GET("/songs/:id/sections", h.GetSections)
+	h.verseRoutes(g.group("/songs/:id/verses"))
+	g.viewer.GET("/groups/:id/songs", h.GetGroupSongsV2)
+}
+
+func (h *Handler) verseRoutes(g roleGroups) {
+	g.viewer.GET("", h.GetVerses)
+	g.editor.POST("", h.InsertVerse)
+	g.editor.PUT("/order", h.ReorderVerses)
+	g.viewer.GET("/:n", h.GetVerse)
+	g.editor.PUT("/:n", h.UpdateVerse)
+	g.editor.DELETE("/:n", h.DeleteVerse)
+}
@@ -0,0 +1,273 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+type webhookRequest struct {
+	URL    string   `json:"url" binding:"required" example:"https://example.com/hooks/songs"`
+	Events []string `json:"events" binding:"required" example:"song.created,song.updated,song.deleted"`
+	// Secret signs the deliveries; a random one is generated when it is empty.
+	Secret string `json:"secret,omitempty" example:"7c1f0e5b9a2d4c3e8f6a1b0d"`
+}
+
+type webhookUpdateRequest struct {
+	URL    string   `json:"url" binding:"required" example:"https://example.com/hooks/songs"`
+	Events []string `json:"events" binding:"required" example:"song.created,song.updated,song.deleted"`
+	Active *bool    `json:"active" binding:"required" example:"true"`
+}
+
+// webhookCreatedResponse is a new webhook with its secret, shown only once.
+type webhookCreatedResponse struct {
+	model.Webhook
+	Secret string `json:"secret" example:"whsec_Zk3ZC1hYjM0LTQ3N2EtYjQ0Ny0xN2Q2YzVmMzY4ZWE"`
+}
+
+// @Summary Создание вебхука
+// @Description Подписка URL на события песен song.created, song.updated и song.deleted. Каждая доставка — POST с JSON события и заголовками X-Webhook-Id, X-Webhook-Event, X-Webhook-Timestamp и X-Webhook-Signature: sha256=<HMAC-SHA256 строки "<timestamp>.<тело>" с секретом вебхука>. Секрет показывается только в ответе на создание
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body webhookRequest true "Вебхук"
+// @Success 201 {object} webhookCreatedResponse
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 422 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /webhooks [post]
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	slog.Info("Начало обработки запроса CreateWebhook")
+
+	var req webhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	hook, secret, err := h.service.CreateWebhook(model.Webhook{URL: req.URL, Events: req.Events}, req.Secret)
+	if err != nil {
+		slog.Error("Ошибка при создании вебхука", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+
+	slog.Info("Вебхук создан", "id", hook.ID, "url", hook.URL, "by", principal(c).Actor())
+	c.Header("Cache-Control", "no-store")
+	c.AbortWithStatusJSON(http.StatusCreated, webhookCreatedResponse{Webhook: hook, Secret: secret})
+}
+
+// @Summary Список вебхуков
+// @Tags webhooks
+// @Produce json
+// @Success 200 {object} []model.Webhook
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /webhooks [get]
+func (h *Handler) ListWebhooks(c *gin.Context) {
+	slog.Info("Начало обработки запроса ListWebhooks")
+
+	hooks, err := h.service.ListWebhooks()
+	if err != nil {
+		slog.Error("Ошибка при получении вебхуков", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusOK, hooks)
+}
+
+// @Summary Вебхук
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "ID вебхука"
+// @Success 200 {object} model.Webhook
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /webhooks/{id} [get]
+func (h *Handler) GetWebhook(c *gin.Context) {
+	slog.Info("Начало обработки запроса GetWebhook")
+
+	id, ok := intParam(c, "id")
+	if !ok {
+		return
+	}
+	hook, err := h.service.GetWebhook(id)
+	if err != nil {
+		slog.Error("Ошибка при получении вебхука", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusOK, hook)
+}
+
+// @Summary Изменение вебхука
+// @Description Замена URL, событий и активности вебхука. Неактивному вебхуку события не доставляются, в том числе после повторной активации
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path int true "ID вебхука"
+// @Param webhook body webhookUpdateRequest true "Вебхук"
+// @Success 200 {object} model.Webhook
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 422 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /webhooks/{id} [put]
+func (h *Handler) UpdateWebhook(c *gin.Context) {
+	slog.Info("Начало обработки запроса UpdateWebhook")
+
+	id, ok := intParam(c, "id")
+	if !ok {
+		return
+	}
+	var req webhookUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		bindError(c, err)
+		return
+	}
+
+	hook, err := h.service.UpdateWebhook(model.Webhook{ID: id, URL: req.URL, Events: req.Events, Active: *req.Active})
+	if err != nil {
+		slog.Error("Ошибка при изменении вебхука", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+
+	slog.Info("Вебхук изменён", "id", id, "by", principal(c).Actor())
+	c.AbortWithStatusJSON(http.StatusOK, hook)
+}
+
+// @Summary Удаление вебхука
+// @Description Удаление вебхука вместе с журналом его доставок
+// @Tags webhooks
+// @Param id path int true "ID вебхука"
+// @Success 204
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /webhooks/{id} [delete]
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	slog.Info("Начало обработки запроса DeleteWebhook")
+
+	id, ok := intParam(c, "id")
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteWebhook(id); err != nil {
+		slog.Error("Ошибка при удалении вебхука", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+
+	slog.Info("Вебхук удалён", "id", id, "by", principal(c).Actor())
+	c.AbortWithStatus(http.StatusNoContent)
+}
+
+// @Summary Журнал доставок вебхука
+// @Description Доставки событий вебхуку, начиная с новых: состояние (pending — ждёт попытки, delivered — доставлена, dead — попытки исчерпаны), число попыток, время следующей и результат последней
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "ID вебхука"
+// @Param status query string false "Состояние доставки" Enums(pending, delivered, dead)
+// @Param page query int false "Номер страницы" default(1)
+// @Param limit query int false "Количество на странице" default(20)
+// @Success 200 {object} []model.WebhookDelivery
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 422 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /webhooks/{id}/deliveries [get]
+func (h *Handler) GetWebhookDeliveries(c *gin.Context) {
+	slog.Info("Начало обработки запроса GetWebhookDeliveries")
+
+	id, ok := intParam(c, "id")
+	if !ok {
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		slog.Error("Ошибка при парсинге page", "error", err)
+		newErrorFromErr(c, errParam("page", "ожидается положительное целое число, получено %q", c.Query("page")))
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 {
+		slog.Error("Ошибка при парсинге limit", "error", err)
+		newErrorFromErr(c, errParam("limit", "ожидается положительное целое число, получено %q", c.Query("limit")))
+		return
+	}
+
+	deliveries, err := h.service.WebhookDeliveries(id, c.Query("status"), page, limit)
+	if err != nil {
+		slog.Error("Ошибка при получении доставок вебхука", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusOK, deliveries)
+}
+
+// @Summary Повтор доставки
+// @Description Повторная отправка доставки, которая ждёт попытки или исчерпала попытки: она отправляется при ближайшем проходе с полным числом попыток
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "ID вебхука"
+// @Param delivery path int true "ID доставки"
+// @Success 200 {object} model.WebhookDelivery
+// @Failure 400 {object} errorResponse
+// @Failure 401 {object} errorResponse
+// @Failure 403 {object} errorResponse
+// @Failure 404 {object} errorResponse
+// @Failure 409 {object} errorResponse
+// @Failure 429 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router /webhooks/{id}/deliveries/{delivery}/retry [post]
+func (h *Handler) RetryWebhookDelivery(c *gin.Context) {
+	slog.Info("Начало обработки запроса RetryWebhookDelivery")
+
+	id, ok := intParam(c, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := intParam(c, "delivery")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.service.RetryWebhookDelivery(id, int64(deliveryID))
+	if err != nil {
+		slog.Error("Ошибка при повторе доставки", "error", err)
+		newErrorFromErr(c, err)
+		return
+	}
+
+	slog.Info("Доставка вебхука поставлена на повтор", "id", delivery.ID, "webhook_id", id, "by", principal(c).Actor())
+	c.AbortWithStatusJSON(http.StatusOK, delivery)
+}
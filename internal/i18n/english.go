@@ -0,0 +1,178 @@
+package i18n
+
+// english translates the messages of the API into English.
+var english = map[string]string{
+	// Problem titles.
+	"Некорректный запрос":                  "Bad request",
+	"Требуется аутентификация":             "Authentication required",
+	"Недостаточно прав":                    "Insufficient permissions",
+	"Ресурс не найден":                     "Resource not found",
+	"Конфликт с текущим состоянием":        "Conflict with the current state",
+	"Версия не совпадает":                  "Version mismatch",
+	"Неподдерживаемый формат тела запроса": "Unsupported request body format",
+	"Данные не прошли проверку":            "Validation failed",
+	"Требуется версия ресурса":             "Resource version required",
+	"Слишком большое тело запроса":         "Request body too large",
+	"Слишком много запросов":               "Too many requests",
+	"Внутренняя ошибка сервера":            "Internal server error",
+	"Внешний сервис недоступен":            "Upstream service unavailable",
+
+	// Success messages.
+	"Песня добавлена":           "Song added",
+	"Удаление прошло успешно":   "Deleted successfully",
+	"Обновление прошло успешно": "Updated successfully",
+	"Куплет удалён":             "Verse deleted",
+
+	// Requests.
+	"Не удалось обработать запрос":                                   "The request could not be processed",
+	"Маршрут %s %s не существует":                                    "Route %s %s does not exist",
+	"Тип ошибки %q не существует":                                    "Problem type %q does not exist",
+	"Некорректный параметр %s: %s":                                   "Invalid parameter %s: %s",
+	"Тело запроса не является корректным JSON":                       "The request body is not valid JSON",
+	"Не удалось прочитать тело запроса":                              "The request body could not be read",
+	"Некорректные поля: %s":                                          "Invalid fields: %s",
+	"Неизвестное поле %s":                                            "Unknown field %s",
+	"Не выполнено условие %s":                                        "Constraint %s is not met",
+	"Обязательное поле":                                              "Required field",
+	"Поле %s должно быть строкой":                                    "Field %s must be a string",
+	"Поле %s должно быть логическим значением":                       "Field %s must be a boolean",
+	"Поле %s должно быть массивом":                                   "Field %s must be an array",
+	"Поле %s должно быть объектом":                                   "Field %s must be an object",
+	"Поле %s должно быть числом":                                     "Field %s must be a number",
+	"ожидается целое число, получено %q":                             "expected an integer, got %q",
+	"ожидается положительное целое число, получено %q":               "expected a positive integer, got %q",
+	"ожидается целое число от 1 до %d, получено %q":                  "expected an integer from 1 to %d, got %q",
+	"ожидаются положительные целые числа через запятую, получено %q": "expected comma-separated positive integers, got %q",
+	"ожидается true или false, получено %q":                          "expected true or false, got %q",
+	"ожидается JSON-объект, получено %q":                             "expected a JSON object, got %q",
+	"ожидается один символ, получено %q":                             "expected a single character, got %q",
+	"ожидается номер куплета, диапазон A-B или all, получено %q":     "expected a verse number, an A-B range or all, got %q",
+	"конец диапазона должен быть не меньше начала, получено %q":      "the end of the range must not be less than its start, got %q",
+	"ожидается версия в кавычках или *, получено %s":                 "expected a quoted version or *, got %s",
+	"допустимы %s и %s, получено %q":                                 "allowed are %s and %s, got %q",
+	"допустимы %s, %s и %s, получено %q":                             "allowed are %s, %s and %s, got %q",
+	"допустимы skip и update, получено %q":                           "allowed are skip and update, got %q",
+	"Слишком много запросов, повторите через %d с":                   "Too many requests, retry in %d s",
+
+	// Authentication.
+	"Нужен API-ключ или токен доступа":              "An API key or access token is required",
+	"Недостаточно прав: нужна роль %s":              "Insufficient permissions: role %s is required",
+	"Неверный API-ключ":                             "Invalid API key",
+	"API-ключ отозван":                              "The API key has been revoked",
+	"API-ключ с префиксом %s уже существует":        "An API key with prefix %s already exists",
+	"API-ключ %s не найден":                         "API key %s not found",
+	"API-ключ %d не найден":                         "API key %d not found",
+	"Не указано имя API-ключа":                      "The API key name is missing",
+	"Не указаны права API-ключа":                    "The API key scopes are missing",
+	"Неизвестное право %q, допустимы %s":            "Unknown scope %q, allowed are %s",
+	"Неверный логин или пароль":                     "Invalid login or password",
+	"Неверный refresh-токен":                        "Invalid refresh token",
+	"Refresh-токен уже использован, войдите заново": "The refresh token has already been used, log in again",
+	"Срок действия refresh-токена истёк":            "The refresh token has expired",
+	"Срок действия токена истёк":                    "The token has expired",
+	"Недействительный токен":                        "Invalid token",
+	"Пользователь %s уже существует":                "User %s already exists",
+	"Пользователь %s не найден":                     "User %s not found",
+	"Пользователь %d не найден":                     "User %d not found",
+	"Неизвестная роль %q, допустимы %s":             "Unknown role %q, allowed are %s",
+	"Пароль должен быть длиной от %d до %d байт":    "The password must be %d to %d bytes long",
+	"Логин должен состоять из 3–64 латинских букв, цифр, точек, дефисов или подчёркиваний": "The login must consist of 3 to 64 Latin letters, digits, dots, hyphens or underscores",
+
+	// Songs.
+	"Песня %d не найдена":                                                   "Song %d not found",
+	"Песня %q группы %q не найдена":                                         "Song %q by %q not found",
+	"Песня %q группы %q уже существует":                                     "Song %q by %q already exists",
+	"Песня с таким названием у группы уже существует":                       "The group already has a song with this title",
+	"Группа %d не найдена":                                                  "Group %d not found",
+	"Неизвестное поле сортировки %q, допустимы %s":                          "Unknown sort field %q, allowed are %s",
+	"Песни не найдены: %s":                                                  "Songs not found: %s",
+	"Не указан идентификатор или название песни и группа":                   "Neither the song ID nor the song title and group are given",
+	"Не указано название песни или группа":                                  "The song title or group is missing",
+	"Не указана дата выпуска":                                               "The release date is missing",
+	"Некорректная дата выпуска %q, ожидается формат ДД.ММ.ГГГГ":             "Invalid release date %q, expected DD.MM.YYYY",
+	"Некорректная дата выпуска %q, ожидается формат ГГГГ-ММ-ДД":             "Invalid release date %q, expected YYYY-MM-DD",
+	"Нет данных для обновления":                                             "Nothing to update",
+	"Версия песни изменилась: ожидалась %d, текущая %d":                     "The song version has changed: expected %d, current %d",
+	"Укажите версию песни в заголовке If-Match или в поле version":          "Give the song version in the If-Match header or the version field",
+	"Укажите версию песни в поле version":                                   "Give the song version in the version field",
+	"Для замены песни нужны название, группа, дата выпуска, текст и ссылка": "Replacing a song requires the title, group, release date, lyrics and link",
+	"Название, группа и дата выпуска песни обязательны":                     "The song title, group and release date are required",
+	"ID песни нельзя изменить":                                              "The song ID cannot be changed",
+	"Версию песни нельзя изменить патчем":                                   "The song version cannot be changed by a patch",
+	"Песня не соответствует схеме: некорректный JSON":                       "The song does not match the schema: invalid JSON",
+	"Песня не соответствует схеме: лишние данные после объекта":             "The song does not match the schema: data after the object",
+	"Не удалось дополнить данные песни: %v":                                 "Could not complete the song data: %v",
+	"Неизвестный парсер разделов %q":                                        "Unknown section parser %q",
+
+	// Patches.
+	"Документ merge patch не является корректным JSON": "The merge patch document is not valid JSON",
+	"Некорректный документ JSON Patch: %v":             "Invalid JSON Patch document: %v",
+	"Неподдерживаемый формат патча %q":                 "Unsupported patch format %q",
+	"Неподдерживаемый формат патча %q, допустимы %s":   "Unsupported patch format %q, allowed are %s",
+	"Не удалось применить патч: %v":                    "The patch could not be applied: %v",
+	"Операция test не выполнена: %v":                   "The test operation failed: %v",
+
+	// Verses.
+	"Куплет %d не найден":                    "Verse %d not found",
+	"Куплеты %d-%d не найдены":               "Verses %d-%d not found",
+	"Страница %d не найдена":                 "Page %d not found",
+	"Позиция куплета должна быть от 1 до %d": "The verse position must be between 1 and %d",
+	"Порядок должен содержать каждый номер куплета от 1 до %d ровно один раз": "The order must contain every verse number from 1 to %d exactly once",
+	"Текст куплета пуст":                              "The verse text is empty",
+	"Текст куплета не должен содержать пустых строк":  "The verse text must not contain blank lines",
+	"Неизвестный тип куплета %q":                      "Unknown verse type %q",
+	"Некорректная метка раздела %q":                   "Invalid section label %q",
+	"Число повторов задаётся только вместе с меткой":  "The repeat count can only be set together with a label",
+	"Число повторов должно быть положительным":        "The repeat count must be positive",
+	"Фраза для поиска пуста":                          "The search phrase is empty",
+	"Фраза для поиска должна умещаться в одну строку": "The search phrase must fit on one line",
+	"Число опечаток должно быть от 0 до %d":           "The number of typos must be between 0 and %d",
+
+	// Import and songbook.
+	"Ошибка разбора CSV: %v":                                                              "CSV parse error: %v",
+	"Ошибка разбора JSON: %v":                                                             "JSON parse error: %v",
+	"Не удалось прочитать заголовок CSV: %v":                                              "Could not read the CSV header: %v",
+	"Не удалось прочитать форму: %v":                                                      "Could not read the form: %v",
+	"В форме нет файла file":                                                              "The form has no file part",
+	"Файл импорта больше %d байт":                                                         "The import file is larger than %d bytes",
+	"Не удалось прочитать файл импорта: %v":                                               "Could not read the import file: %v",
+	"Не удалось определить формат файла, укажите format=csv или format=ndjson":            "Could not detect the file format, set format=csv or format=ndjson",
+	"Файл содержит больше %d строк":                                                       "The file has more than %d rows",
+	"Песня уже встречалась в строке %d":                                                   "The song already appeared on line %d",
+	"Песня уже существует":                                                                "The song already exists",
+	"ожидается поле=столбец, где поле одно из group, song, date, text, link, получено %q": "expected field=column with field one of group, song, date, text, link, got %q",
+	"В сборник можно включить не больше %d песен, уточните выборку":                       "A songbook can hold at most %d songs, narrow the selection",
+	"Не найдено ни одной песни для сборника":                                              "No songs found for the songbook",
+
+	// Song info API.
+	"Сервис информации о песнях недоступен: %v":                  "The song info service is unavailable: %v",
+	"Сервис информации о песнях не знает песню %q группы %q":     "The song info service does not know song %q by %q",
+	"Сервис информации о песнях отклонил песню %q группы %q":     "The song info service rejected song %q by %q",
+	"Сервис информации о песнях ответил статусом %d":             "The song info service answered with status %d",
+	"Не удалось прочитать ответ сервиса информации о песнях: %v": "Could not read the song info service response: %v",
+	"Сервис информации о песнях вернул некорректный ответ: %v":   "The song info service returned a malformed response: %v",
+
+	// Webhooks.
+	"Вебхук %d не найден": "Webhook %d not found",
+	"Некорректный URL вебхука %q: нужен абсолютный адрес http или https": "Invalid webhook URL %q: an absolute http or https address is required",
+	"Не указаны события вебхука":                                         "The webhook events are missing",
+	"Неизвестное событие %q, допустимы %s":                               "Unknown event %q, allowed are %s",
+	"Секрет вебхука должен быть не короче %d символов":                   "The webhook secret must be at least %d characters long",
+	"Неизвестное состояние доставки %q, допустимы %s":                    "Unknown delivery status %q, allowed are %s",
+	"Доставка %d вебхука %d не найдена":                                  "Delivery %d of webhook %d not found",
+	"Доставка %d уже выполнена":                                          "Delivery %d has already been delivered",
+
+	// Batches.
+	"Операция отменена": "Operation aborted",
+	"Операция отменена: другие операции пакета завершились ошибкой": "Operation aborted: other operations of the batch failed",
+	"Пакет не содержит операций":                                    "The batch has no operations",
+	"В пакете может быть не больше %d операций":                     "A batch can hold at most %d operations",
+	"Неизвестная операция %q":                                       "Unknown operation %q",
+	"Не указана песня":                                              "The song is missing",
+	"ожидается add, update или delete, получено %q":                 "expected add, update or delete, got %q",
+
+	// GraphQL.
+	"Глубина запроса %d больше допустимой %d":              "The query depth %d exceeds the allowed %d",
+	"Аргумент page должен быть положительным целым числом": "The page argument must be a positive integer",
+	"Аргумент limit должен быть целым числом от 1 до %d":   "The limit argument must be an integer from 1 to %d",
+}
@@ -0,0 +1,84 @@
+// Package i18n translates API messages. Messages are written in Russian in the
+// code and are the keys of the catalogue; translations to other languages are
+// looked up by them, a message without a translation is left in Russian.
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/message/catalog"
+)
+
+// Languages are the languages messages are available in.
+var Languages = []language.Tag{language.Russian, language.English}
+
+// Localizer picks the language of a response and formats messages in it.
+type Localizer struct {
+	tags    []language.Tag
+	matcher language.Matcher
+	catalog catalog.Catalog
+}
+
+// New returns a Localizer answering in defaultLanguage when the client
+// accepts none of Languages.
+func New(defaultLanguage string) (*Localizer, error) {
+	def, err := language.Parse(defaultLanguage)
+	if err != nil {
+		return nil, fmt.Errorf("invalid default language %q: %w", defaultLanguage, err)
+	}
+	tags := []language.Tag{def}
+	supported := false
+	for _, tag := range Languages {
+		if tag == def {
+			supported = true
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	if !supported {
+		return nil, fmt.Errorf("unsupported default language %q, supported are %v", defaultLanguage, Languages)
+	}
+
+	b := catalog.NewBuilder(catalog.Fallback(language.Russian))
+	for key, msg := range english {
+		if err := b.SetString(language.English, key, msg); err != nil {
+			return nil, fmt.Errorf("invalid translation of %q: %w", key, err)
+		}
+	}
+	return &Localizer{tags: tags, matcher: language.NewMatcher(tags), catalog: b}, nil
+}
+
+// Printer returns the printer for the best match of an Accept-Language header
+// value and the language it prints in.
+func (l *Localizer) Printer(acceptLanguage string) (*message.Printer, language.Tag) {
+	_, i := language.MatchStrings(l.matcher, acceptLanguage)
+	tag := l.tags[i]
+	return message.NewPrinter(tag, message.Catalog(l.catalog)), tag
+}
+
+// Sprintf formats a message written in Russian with p, translating the
+// domain errors among args as well.
+func Sprintf(p *message.Printer, format string, args ...any) string {
+	args = slices.Clone(args)
+	for i, arg := range args {
+		if err, ok := arg.(error); ok {
+			args[i] = Error(p, err)
+		}
+	}
+	return p.Sprintf(format, args...)
+}
+
+// Error returns the message of err in the language of p. Only domain errors
+// are translated.
+func Error(p *message.Printer, err error) string {
+	var merr *model.Error
+	if errors.As(err, &merr) && merr.Format != "" {
+		return Sprintf(p, merr.Format, merr.Args...)
+	}
+	return err.Error()
+}
@@ -0,0 +1,50 @@
+package model
+
+import "time"
+
+// API key scopes. Each scope includes the ones before it: write can also read,
+// admin can do everything.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+// Scopes lists the API key scopes from the weakest to the strongest.
+var Scopes = []string{ScopeRead, ScopeWrite, ScopeAdmin}
+
+// APIKey describes an API key. The secret itself is only known when the key is
+// minted; the database keeps its hash.
+type APIKey struct {
+	ID         int        `json:"id" example:"1"`
+	Name       string     `json:"name" example:"music-night"`
+	Prefix     string     `json:"prefix" example:"3f9a1c0b7d2e"`
+	Scopes     []string   `json:"scopes" example:"read,write"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Role is the user role matching the strongest scope of the key.
+func (k APIKey) Role() string {
+	role := ""
+	for _, s := range k.Scopes {
+		if r := scopeRoles[s]; role == "" || RoleAllows(r, role) {
+			role = r
+		}
+	}
+	return role
+}
+
+// scopeRoles maps API key scopes onto the user roles granting the same access.
+var scopeRoles = map[string]string{
+	ScopeRead:  RoleViewer,
+	ScopeWrite: RoleEditor,
+	ScopeAdmin: RoleAdmin,
+}
+
+// ValidScope reports whether scope is a known API key scope.
+func ValidScope(scope string) bool {
+	_, ok := scopeRoles[scope]
+	return ok
+}
@@ -0,0 +1,28 @@
+package model
+
+// Batch operations.
+const (
+	BatchAdd    = "add"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// BatchOp is one write of a batch. Add creates the song named by
+// Song.SongName and Song.Group, update applies the set fields of Song to the
+// song ID and delete removes it; both are conditioned on Version when it is
+// set. Err is set when the operation could not even be read: it then fails
+// without running.
+type BatchOp struct {
+	Op      string
+	ID      int
+	Version *int
+	Song    Song
+	Err     error
+}
+
+// BatchResult is the outcome of a batch operation: the song as stored after
+// it, with no fields for a deletion, or the error it failed with.
+type BatchResult struct {
+	Song Song
+	Err  error
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of domain errors. Repository and service return them wrapped in Error,
+// handlers match them with errors.Is to pick the HTTP status.
+var (
+	ErrNotFound            = errors.New("not found")
+	ErrConflict            = errors.New("conflict")
+	ErrValidation          = errors.New("validation failed")
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
+	ErrPreconditionFailed  = errors.New("precondition failed")
+	ErrUnauthorized        = errors.New("unauthorized")
+	ErrForbidden           = errors.New("forbidden")
+	// ErrAborted is the outcome of an operation undone or never run because
+	// another operation of the same atomic batch failed.
+	ErrAborted = errors.New("aborted")
+)
+
+// Error is a domain error: a human readable message tagged with its kind.
+// Format and Args are what the message was made of, so that handlers can
+// translate it. Fields point validation errors at the request fields that
+// caused them.
+type Error struct {
+	Kind    error
+	Message string
+	Format  string
+	Args    []any
+	Fields  []FieldError
+}
+
+// FieldError is a problem with a single request field. Song fields are named
+// as in the JSON of model.Song; handlers rename them for their API version.
+type FieldError struct {
+	Field   string
+	Message string
+	Format  string
+	Args    []any
+}
+
+func NewError(kind error, format string, args ...any) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...), Format: format, Args: args}
+}
+
+// WithField attributes the error to field and returns e.
+func (e *Error) WithField(field string) *Error {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: e.Message, Format: e.Format, Args: e.Args})
+	return e
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
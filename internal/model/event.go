@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// Song event types.
+const (
+	SongCreated = "created"
+	SongUpdated = "updated"
+	SongDeleted = "deleted"
+)
+
+// SongEvent is a change of a song in the catalogue's change feed. IDs grow
+// with every event, so a reader resumes after the last ID it has seen.
+type SongEvent struct {
+	ID      int64
+	Type    string
+	SongID  int
+	GroupID int
+	Version int
+	Actor   *string
+	At      time.Time
+	// Changes holds the song fields the change set: all of them for a new
+	// song, the changed ones for an update and none for a deletion.
+	Changes Song
+}
+
+// SongEventFilter narrows the feed to the songs of a group, given by ID or
+// by name.
+type SongEventFilter struct {
+	GroupID *int
+	Group   *string
+}
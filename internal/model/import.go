@@ -0,0 +1,44 @@
+package model
+
+// Outcomes of importing a single row.
+const (
+	ImportCreated = "created"
+	ImportUpdated = "updated"
+	ImportSkipped = "skipped"
+	ImportFailed  = "failed"
+)
+
+// ImportOptions controls a bulk import.
+type ImportOptions struct {
+	// DryRun validates the rows and reports what would happen without writing.
+	DryRun bool
+	// Enrich fills a missing release date, text or link from the external API.
+	Enrich bool
+	// Update overwrites songs that already exist instead of skipping them.
+	Update bool
+}
+
+// ImportRow is one parsed row of an import file. Err is set when the row could
+// not be read at all.
+type ImportRow struct {
+	Line int
+	Song Song
+	Err  error
+}
+
+// ImportResult reports what happened to one import row.
+type ImportResult struct {
+	Line     int    `json:"line" example:"2"`
+	Status   string `json:"status" example:"created"`
+	ID       int    `json:"id,omitempty" example:"1"`
+	Group    string `json:"group_name,omitempty" example:"Muse"`
+	SongName string `json:"song_name,omitempty" example:"Supermassive Black Hole"`
+	Reason   string `json:"reason,omitempty" example:"Песня уже существует"`
+	// Err is the error Reason describes.
+	Err error `json:"-"`
+}
+
+// Fail sets the outcome of the row and the error that caused it.
+func (r *ImportResult) Fail(status string, err error) {
+	r.Status, r.Err, r.Reason = status, err, err.Error()
+}
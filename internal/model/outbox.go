@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// OutboxCursor is where the outbox relay stands in the song event feed for a
+// sink: Sink has been handed every event up to EventID, or holds it in its
+// retries. LeasedUntil is when the relay holding the sink lets it go.
+type OutboxCursor struct {
+	Sink        string
+	EventID     int64
+	LeasedUntil time.Time
+}
+
+// OutboxRetry is a song event a sink has not taken yet. The first retry of a
+// song is attempted at NextAttemptAt, the later ones wait behind it.
+type OutboxRetry struct {
+	Event    SongEvent
+	Attempts int
+	// LastError is why the last attempt to publish the event failed.
+	LastError     *string
+	NextAttemptAt time.Time
+}
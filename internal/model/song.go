@@ -8,4 +8,46 @@ type Song struct {
 	ReleaseDate *string `json:"releaseDate,omitempty" example:"19.07.2006" db:"release_date"`
 	Link        *string `json:"link,omitempty" example:"https://www.youtube.com/watch?v=Xsp3_a-PMTw" db:"link"`
 	Text        *string `json:"text,omitempty" example:"Ooh baby, don't you know I suffer?\nOoh baby, can you hear me moan?" db:"text"`
+	Version     *int    `json:"version,omitempty" example:"1" db:"version"`
+	// SectionParser overrides the default parser used to split Text into
+	// verses; an empty string resets it to the default.
+	SectionParser *string `json:"section_parser,omitempty" example:"labeled" db:"section_parser"`
+	// Verses is Text split by the service; repositories store them and derive
+	// the text column from them.
+	Verses []Verse `json:"-"`
+}
+
+// Fields song lists can be sorted by.
+const (
+	SortByID          = "id"
+	SortBySongName    = "song_name"
+	SortByGroup       = "group"
+	SortByReleaseDate = "release_date"
+)
+
+// SongSortFields lists the fields song lists can be sorted by.
+var SongSortFields = []string{SortByID, SortBySongName, SortByGroup, SortByReleaseDate}
+
+// SongSort orders a song list. The zero value orders by ID; songs equal in
+// Field are ordered by ID.
+type SongSort struct {
+	Field string
+	Desc  bool
+}
+
+// RenormalizeResult describes a song whose stored text changed after
+// normalization.
+type RenormalizeResult struct {
+	ID           int    `json:"id"`
+	Group        string `json:"group_name"`
+	SongName     string `json:"song_name"`
+	VersesBefore int    `json:"verses_before"`
+	VersesAfter  int    `json:"verses_after"`
+}
+
+// AddResult is the outcome of adding a single song in a batch: either the
+// assigned ID or the error that prevented the row from being stored.
+type AddResult struct {
+	ID  int   `json:"id,omitempty"`
+	Err error `json:"-"`
 }
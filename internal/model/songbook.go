@@ -0,0 +1,8 @@
+package model
+
+// SongbookGroup is a section of a songbook: the songs of one group, each with
+// its verses.
+type SongbookGroup struct {
+	Name  string
+	Songs []Song
+}
@@ -0,0 +1,73 @@
+package model
+
+import "time"
+
+// User roles. Each role includes the ones before it: editors can also read,
+// admins can do everything.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// Roles lists the user roles from the weakest to the strongest.
+var Roles = []string{RoleViewer, RoleEditor, RoleAdmin}
+
+// ValidRole reports whether role is a known user role.
+func ValidRole(role string) bool {
+	return roleRank(role) >= 0
+}
+
+// RoleAllows reports whether role grants what need grants.
+func RoleAllows(role, need string) bool {
+	rank, needRank := roleRank(role), roleRank(need)
+	return rank >= 0 && needRank >= 0 && rank >= needRank
+}
+
+func roleRank(role string) int {
+	for i, r := range Roles {
+		if r == role {
+			return i
+		}
+	}
+	return -1
+}
+
+type User struct {
+	ID        int       `json:"id" example:"1"`
+	Login     string    `json:"login" example:"editor"`
+	Role      string    `json:"role" example:"viewer"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TokenPair is what a successful login or refresh returns.
+type TokenPair struct {
+	AccessToken  string `json:"access_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	ExpiresIn    int    `json:"expires_in" example:"900"`
+	RefreshToken string `json:"refresh_token" example:"Zk3ZC1hYjM0LTQ3N2EtYjQ0Ny0xN2Q2YzVmMzY4ZWE"`
+}
+
+// Principal kinds.
+const (
+	PrincipalUser   = "user"
+	PrincipalAPIKey = "api_key"
+)
+
+// Principal is the authenticated caller of a request: a user or an API key.
+type Principal struct {
+	Kind string `json:"kind" example:"user"`
+	ID   int    `json:"id" example:"1"`
+	Name string `json:"name" example:"editor"`
+	Role string `json:"role" example:"editor"`
+}
+
+// Actor names the principal in the song audit log.
+func (p Principal) Actor() string {
+	return p.Kind + ":" + p.Name
+}
+
+// Allows reports whether the principal's role includes role.
+func (p Principal) Allows(role string) bool {
+	return RoleAllows(p.Role, role)
+}
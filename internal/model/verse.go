@@ -0,0 +1,48 @@
+package model
+
+// Verse types a song section can have.
+const (
+	VerseTypeVerse  = "verse"
+	VerseTypeChorus = "chorus"
+	VerseTypeBridge = "bridge"
+	VerseTypeIntro  = "intro"
+)
+
+// VerseSeparator separates verses in the derived songs.text column.
+const VerseSeparator = "\n\n"
+
+type Verse struct {
+	ID      *int    `json:"id,omitempty" example:"1" db:"id"`
+	SongID  *int    `json:"song_id,omitempty" example:"1" db:"song_id"`
+	Ordinal *int    `json:"ordinal,omitempty" example:"1" db:"ordinal"`
+	Type    *string `json:"type,omitempty" example:"chorus" db:"type"`
+	Text    *string `json:"text,omitempty" example:"Ooh baby, don't you know I suffer?\nOoh baby, can you hear me moan?" db:"text"`
+	Label   *string `json:"label,omitempty" example:"Chorus" db:"label"`
+	Repeat  *int    `json:"repeat,omitempty" example:"2" db:"repeat"`
+	// Header is the source line the label was parsed from; it is written back
+	// in front of Text when the song text is rebuilt.
+	Header *string `json:"-" db:"header"`
+}
+
+// PhraseQuery describes a phrase lookup inside a song's verses.
+type PhraseQuery struct {
+	Phrase string
+	// Fold makes the match case and diacritic insensitive.
+	Fold bool
+	// Fuzzy is the number of edits (insertions, deletions, substitutions)
+	// a match may differ from the phrase by.
+	Fuzzy int
+}
+
+// PhraseMatch is one occurrence of a phrase. Line is counted from 1 inside the
+// verse text; Offset and Length are in characters within that line.
+type PhraseMatch struct {
+	Verse    int    `json:"verse" example:"2"`
+	Label    string `json:"label,omitempty" example:"Chorus"`
+	Line     int    `json:"line" example:"1"`
+	Offset   int    `json:"offset" example:"11"`
+	Length   int    `json:"length" example:"14"`
+	Text     string `json:"text" example:"don't you know"`
+	LineText string `json:"line_text" example:"Ooh baby, don't you know I suffer?"`
+	Distance int    `json:"distance" example:"0"`
+}
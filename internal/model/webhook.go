@@ -0,0 +1,70 @@
+package model
+
+import "time"
+
+// Webhook events, the song event types a webhook can subscribe to.
+const (
+	WebhookSongCreated = "song." + SongCreated
+	WebhookSongUpdated = "song." + SongUpdated
+	WebhookSongDeleted = "song." + SongDeleted
+)
+
+// WebhookEvents lists the webhook events.
+var WebhookEvents = []string{WebhookSongCreated, WebhookSongUpdated, WebhookSongDeleted}
+
+// ValidWebhookEvent reports whether event is a known webhook event.
+func ValidWebhookEvent(event string) bool {
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook is a subscription of a URL to song events. Its secret signs the
+// deliveries and is only shown when the webhook is created.
+type Webhook struct {
+	ID        int       `json:"id" example:"1"`
+	URL       string    `json:"url" example:"https://example.com/hooks/songs"`
+	Events    []string  `json:"events" example:"song.created,song.updated"`
+	Active    bool      `json:"active" example:"true"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Webhook delivery states. A pending delivery waits for its next attempt, a
+// dead one ran out of attempts and is only sent again when retried by hand.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// DeliveryStatuses lists the webhook delivery states.
+var DeliveryStatuses = []string{DeliveryPending, DeliveryDelivered, DeliveryDead}
+
+// WebhookDelivery is a song event sent, or to be sent, to a webhook, with
+// the outcome of its last attempt.
+type WebhookDelivery struct {
+	ID            int64      `json:"id" example:"1"`
+	WebhookID     int        `json:"webhook_id" example:"1"`
+	EventID       int64      `json:"event_id" example:"42"`
+	Event         string     `json:"event" example:"song.updated"`
+	SongID        int        `json:"song_id" example:"1"`
+	Status        string     `json:"status" example:"pending"`
+	Attempts      int        `json:"attempts" example:"1"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LastStatus    *int       `json:"last_status,omitempty" example:"503"`
+	LastError     *string    `json:"last_error,omitempty" example:"unexpected status 503 Service Unavailable"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+}
+
+// WebhookJob is a delivery due to be sent, with where to send it and the
+// event it carries.
+type WebhookJob struct {
+	Delivery WebhookDelivery
+	URL      string
+	Secret   string
+	Event    SongEvent
+}
@@ -0,0 +1,183 @@
+// Package outbox publishes the song events of the song_events table to sinks:
+// the log, a file of JSON lines or an HTTP endpoint.
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/config"
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+)
+
+// Sink publishes events. Publish returns once the sink has taken the event:
+// the relay moves past it then. Delivery is at least once, an event is
+// published again when the relay stops before recording that, so consumers
+// drop the events whose ID they have already seen. Name identifies the sink,
+// whose place in the feed is kept under it.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, event model.SongEvent) error
+}
+
+// Message is the JSON form of an event published by the sinks. Changes has
+// the song fields the change set, named as the first API version does.
+type Message struct {
+	ID      int64      `json:"id"`
+	Type    string     `json:"type"`
+	SongID  int        `json:"song_id"`
+	GroupID int        `json:"group_id"`
+	Version int        `json:"version"`
+	Actor   *string    `json:"actor,omitempty"`
+	At      time.Time  `json:"at"`
+	Changes model.Song `json:"changes"`
+}
+
+func newMessage(event model.SongEvent) Message {
+	return Message{
+		ID:      event.ID,
+		Type:    "song." + event.Type,
+		SongID:  event.SongID,
+		GroupID: event.GroupID,
+		Version: event.Version,
+		Actor:   event.Actor,
+		At:      event.At,
+		Changes: event.Changes,
+	}
+}
+
+// Sink names.
+const (
+	SinkLog  = "log"
+	SinkFile = "file"
+	SinkHTTP = "http"
+)
+
+// New returns the sinks listed in cfg.
+func New(cfg config.OutboxConfig) ([]Sink, error) {
+	if len(cfg.Sinks) == 0 {
+		return nil, fmt.Errorf("outbox_sinks lists no sinks")
+	}
+	var sinks []Sink
+	for _, name := range cfg.Sinks {
+		switch name {
+		case SinkLog:
+			sinks = append(sinks, LogSink{})
+		case SinkFile:
+			sink, err := NewFileSink(cfg.File)
+			if err != nil {
+				return nil, err
+			}
+			sinks = append(sinks, sink)
+		case SinkHTTP:
+			if cfg.URL == "" {
+				return nil, fmt.Errorf("outbox_url is required by the http sink")
+			}
+			sinks = append(sinks, NewHTTPSink(cfg.URL, cfg.Timeout))
+		default:
+			return nil, fmt.Errorf("unknown outbox sink %q, allowed are %s, %s, %s", name, SinkLog, SinkFile, SinkHTTP)
+		}
+	}
+	return sinks, nil
+}
+
+// LogSink writes events to the log.
+type LogSink struct{}
+
+func (LogSink) Name() string {
+	return SinkLog
+}
+
+func (LogSink) Publish(_ context.Context, event model.SongEvent) error {
+	data, err := json.Marshal(newMessage(event))
+	if err != nil {
+		return err
+	}
+	slog.Info("Событие песни", "id", event.ID, "type", event.Type, "song_id", event.SongID, "event", json.RawMessage(data))
+	return nil
+}
+
+// FileSink appends events to a file, one JSON object per line, and syncs
+// the file before reporting an event published.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+	path string
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open outbox file: %w", err)
+	}
+	return &FileSink{file: file, path: path}, nil
+}
+
+func (s *FileSink) Name() string {
+	return SinkFile + ":" + s.path
+}
+
+func (s *FileSink) Publish(_ context.Context, event model.SongEvent) error {
+	data, err := json.Marshal(newMessage(event))
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// HTTPSink posts each event to a URL. A 2xx answer means published; the
+// Idempotency-Key header carries the event ID.
+type HTTPSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPSink(url string, timeout time.Duration) *HTTPSink {
+	return &HTTPSink{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (s *HTTPSink) Name() string {
+	return SinkHTTP + ":" + s.url
+}
+
+func (s *HTTPSink) Publish(ctx context.Context, event model.SongEvent) error {
+	data, err := json.Marshal(newMessage(event))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", strconv.FormatInt(event.ID, 10))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// conn is what a repository runs its queries on: the pool or a transaction,
+// in which begin opens a savepoint.
+type conn interface {
+	querier
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// begin opens a transaction that records actor as the author of the song
+// changes made in it (see the songs_audit trigger).
+func begin(ctx context.Context, db conn, actor string) (pgx.Tx, error) {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if actor != "" {
+		if _, err := tx.Exec(ctx, `SELECT set_config('app.actor', $1, true)`, actor); err != nil {
+			tx.Rollback(ctx)
+			return nil, err
+		}
+	}
+	return tx, nil
+}
+
+// beginRead opens a read-only transaction seeing a single snapshot of the
+// database. Inside a transaction it opens a savepoint, which already does.
+func beginRead(ctx context.Context, db conn) (pgx.Tx, error) {
+	if pool, ok := db.(*pgxpool.Pool); ok {
+		return pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	}
+	return db.Begin(ctx)
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type apiKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAPIKeyRepository(db *pgxpool.Pool) *apiKeyRepository {
+	return &apiKeyRepository{
+		db: db,
+	}
+}
+
+const apiKeyColumns = `id, name, prefix, scopes, created_at, last_used_at, revoked_at`
+
+func scanAPIKey(row pgx.Row, extra ...any) (model.APIKey, error) {
+	var key model.APIKey
+	dest := append([]any{&key.ID, &key.Name, &key.Prefix, &key.Scopes, &key.CreatedAt, &key.LastUsedAt, &key.RevokedAt}, extra...)
+	err := row.Scan(dest...)
+	return key, err
+}
+
+// CreateAPIKey stores a new key under its public prefix and secret hash.
+func (r *apiKeyRepository) CreateAPIKey(name, prefix string, hash []byte, scopes []string) (model.APIKey, error) {
+	slog.Info("Начало выполнения CreateAPIKey", "name", name, "scopes", scopes)
+
+	query := `INSERT INTO api_keys (name, prefix, hash, scopes) VALUES ($1, $2, $3, $4) RETURNING ` + apiKeyColumns
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{name, prefix, scopes})
+
+	key, err := scanAPIKey(r.db.QueryRow(context.Background(), query, name, prefix, hash, scopes))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return model.APIKey{}, model.NewError(model.ErrConflict, "API-ключ с префиксом %s уже существует", prefix)
+		}
+		slog.Error("Ошибка при добавлении API-ключа", "error", err)
+		return model.APIKey{}, err
+	}
+
+	slog.Info("API-ключ добавлен", "id", key.ID)
+	return key, nil
+}
+
+// GetAPIKey returns the key with the given prefix together with its secret
+// hash.
+func (r *apiKeyRepository) GetAPIKey(prefix string) (model.APIKey, []byte, error) {
+	query := `SELECT ` + apiKeyColumns + `, hash FROM api_keys WHERE prefix = $1`
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{prefix})
+
+	var hash []byte
+	key, err := scanAPIKey(r.db.QueryRow(context.Background(), query, prefix), &hash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.APIKey{}, nil, model.NewError(model.ErrNotFound, "API-ключ %s не найден", prefix)
+		}
+		slog.Error("Ошибка при выполнении запроса", "error", err)
+		return model.APIKey{}, nil, err
+	}
+	return key, hash, nil
+}
+
+// TouchAPIKey records that the key was used. The column is only written once
+// a minute per key so that busy clients do not turn every read into a write.
+func (r *apiKeyRepository) TouchAPIKey(id int) error {
+	query := `UPDATE api_keys SET last_used_at = now()
+			  WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - INTERVAL '1 minute')`
+	_, err := r.db.Exec(context.Background(), query, id)
+	return err
+}
+
+func (r *apiKeyRepository) ListAPIKeys() ([]model.APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY id`
+	slog.Debug("Сформированный SQL-запрос", "query", query)
+
+	rows, err := r.db.Query(context.Background(), query)
+	if err != nil {
+		slog.Error("Ошибка при выполнении запроса", "error", err)
+		return nil, err
+	}
+	keys, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.APIKey, error) {
+		return scanAPIKey(row)
+	})
+	if err != nil {
+		slog.Error("Ошибка при сканировании строки", "error", err)
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey marks the key revoked; revoking it again keeps the original
+// revocation time.
+func (r *apiKeyRepository) RevokeAPIKey(id int) (model.APIKey, error) {
+	slog.Info("Начало выполнения RevokeAPIKey", "id", id)
+
+	query := `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, now()) WHERE id = $1 RETURNING ` + apiKeyColumns
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{id})
+
+	key, err := scanAPIKey(r.db.QueryRow(context.Background(), query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.APIKey{}, model.NewError(model.ErrNotFound, "API-ключ %d не найден", id)
+		}
+		slog.Error("Ошибка при отзыве API-ключа", "error", err)
+		return model.APIKey{}, err
+	}
+	return key, nil
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// songEventsChannel is where the songs_events trigger announces new events.
+const songEventsChannel = "song_events"
+
+type eventRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewEventRepository(db *pgxpool.Pool) *eventRepository {
+	return &eventRepository{db: db}
+}
+
+// songEventColumns are the columns scanSongEvent reads, of song_events
+// aliased as e.
+const songEventColumns = `e.id, e.type, e.song_id, e.group_id, e.version, e.actor, e.at, e.changes`
+
+// scanSongEvent reads the columns given by dest, then songEventColumns.
+func scanSongEvent(row pgx.CollectableRow, dest ...any) (model.SongEvent, error) {
+	var e model.SongEvent
+	var changes []byte
+	if err := row.Scan(append(dest, &e.ID, &e.Type, &e.SongID, &e.GroupID, &e.Version, &e.Actor, &e.At, &changes)...); err != nil {
+		return model.SongEvent{}, err
+	}
+	if err := json.Unmarshal(changes, &e.Changes); err != nil {
+		return model.SongEvent{}, fmt.Errorf("event %d: %w", e.ID, err)
+	}
+	return e, nil
+}
+
+// settledEvents keeps the events whose transaction ordering them ended
+// before the oldest running one: every event committed later orders after
+// them (see the song_events_order migration).
+const settledEvents = `e.xact_order < pg_snapshot_xmin(pg_current_snapshot())`
+
+// lastSettledEvent is the ID of the last event of the feed that is not held
+// back, or 0.
+const lastSettledEvent = `COALESCE((SELECT e.id FROM song_events AS e
+								  WHERE ` + settledEvents + `
+								  ORDER BY e.xact_order DESC, e.id DESC LIMIT 1), 0)`
+
+// GetSongEvents returns up to limit events after the event with ID after, in
+// the order of the feed. Events of transactions still running, and those
+// that would order after them, are held back until they end.
+func (r *eventRepository) GetSongEvents(after int64, filter model.SongEventFilter, limit int) ([]model.SongEvent, error) {
+	slog.Debug("Начало выполнения GetSongEvents", "after", after, "limit", limit)
+
+	// An ID that is not in the log resumes after the last event before it.
+	query := `SELECT ` + songEventColumns + `
+			  FROM song_events AS e
+			  WHERE (e.xact_order, e.id) > (COALESCE((SELECT c.xact_order FROM song_events AS c
+													  WHERE c.id <= $1 ORDER BY c.id DESC LIMIT 1), '0'), $1)
+				AND ` + settledEvents
+	args := []interface{}{after}
+	argIndex := 2
+	if filter.GroupID != nil {
+		query += fmt.Sprintf(" AND e.group_id = $%d", argIndex)
+		args = append(args, *filter.GroupID)
+		argIndex++
+	}
+	if filter.Group != nil {
+		query += fmt.Sprintf(" AND e.group_id IN (SELECT id FROM groups WHERE name = $%d)", argIndex)
+		args = append(args, *filter.Group)
+		argIndex++
+	}
+	query += fmt.Sprintf(" ORDER BY e.xact_order, e.id LIMIT $%d", argIndex)
+	args = append(args, limit)
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", args)
+
+	rows, err := r.db.Query(context.Background(), query, args...)
+	if err != nil {
+		slog.Error("Ошибка при выполнении запроса", "error", err)
+		return nil, err
+	}
+	events, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.SongEvent, error) {
+		return scanSongEvent(row)
+	})
+	if err != nil {
+		slog.Error("Ошибка при сканировании строки", "error", err)
+		return nil, err
+	}
+	return events, nil
+}
+
+// LastSongEventID returns the ID of the last event of the feed that is not
+// held back, or 0 when there are none.
+func (r *eventRepository) LastSongEventID() (int64, error) {
+	var id int64
+	if err := r.db.QueryRow(context.Background(), `SELECT `+lastSettledEvent).Scan(&id); err != nil {
+		slog.Error("Ошибка при выполнении запроса", "error", err)
+		return 0, err
+	}
+	return id, nil
+}
+
+// ListenSongEvents calls notify whenever a song event is recorded, until ctx
+// is done or the connection breaks. It holds one connection of the pool.
+func (r *eventRepository) ListenSongEvents(ctx context.Context, notify func()) error {
+	conn, err := r.db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	if _, err := conn.Exec(ctx, "LISTEN "+songEventsChannel); err != nil {
+		return err
+	}
+	defer conn.Exec(context.Background(), "UNLISTEN "+songEventsChannel)
+
+	slog.Info("Начато прослушивание событий песен")
+	for {
+		if _, err := conn.Conn().WaitForNotification(ctx); err != nil {
+			return err
+		}
+		notify()
+	}
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type groupRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewGroupRepository(db *pgxpool.Pool) *groupRepository {
+	return &groupRepository{db: db}
+}
+
+// GetGroups returns a page of the groups matching filter, ordered by name:
+// filter.ID selects a single group, filter.Name matches a substring.
+func (r *groupRepository) GetGroups(filter model.Group, page int, limit int) ([]model.Group, error) {
+	slog.Info("Начало выполнения GetGroups", "page", page, "limit", limit)
+
+	query := `SELECT id, name FROM groups WHERE 1=1`
+	var args []interface{}
+	argIndex := 1
+	if filter.ID != nil && *filter.ID > 0 {
+		query += fmt.Sprintf(" AND id = $%d", argIndex)
+		args = append(args, *filter.ID)
+		argIndex++
+	}
+	if filter.Name != nil && *filter.Name != "" {
+		query += fmt.Sprintf(" AND name LIKE $%d", argIndex)
+		args = append(args, "%"+*filter.Name+"%")
+		argIndex++
+	}
+	query += fmt.Sprintf(" ORDER BY name, id LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
+	args = append(args, limit, (page-1)*limit)
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", args)
+
+	rows, err := r.db.Query(context.Background(), query, args...)
+	if err != nil {
+		slog.Error("Ошибка при выполнении запроса", "error", err)
+		return nil, err
+	}
+	groups, err := pgx.CollectRows(rows, pgx.RowToStructByPos[model.Group])
+	if err != nil {
+		slog.Error("Ошибка при сканировании строки", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Успешно получены группы", "count", len(groups))
+	return groups, nil
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type outboxRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewOutboxRepository(db *pgxpool.Pool) *outboxRepository {
+	return &outboxRepository{db: db}
+}
+
+// ClaimOutboxCursor leases the cursor of sink for lease, so that no other
+// relay publishes to the sink meanwhile. A sink met for the first time starts
+// after the last event of the feed. It reports false when another relay holds
+// the lease.
+func (r *outboxRepository) ClaimOutboxCursor(sink string, lease time.Duration) (model.OutboxCursor, bool, error) {
+	ctx := context.Background()
+	if _, err := r.db.Exec(ctx, `INSERT INTO outbox_cursors (sink, event_id) SELECT $1, `+lastSettledEvent+`
+								 ON CONFLICT (sink) DO NOTHING`, sink); err != nil {
+		slog.Error("Ошибка при создании курсора outbox", "sink", sink, "error", err)
+		return model.OutboxCursor{}, false, err
+	}
+
+	query := `UPDATE outbox_cursors
+			  SET leased_until = now() + $2 * INTERVAL '1 second'
+			  WHERE sink = $1 AND leased_until <= now()
+			  RETURNING event_id, leased_until`
+	cursor := model.OutboxCursor{Sink: sink}
+	err := r.db.QueryRow(ctx, query, sink, lease.Seconds()).Scan(&cursor.EventID, &cursor.LeasedUntil)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.OutboxCursor{}, false, nil
+	}
+	if err != nil {
+		slog.Error("Ошибка при выполнении запроса", "error", err)
+		return model.OutboxCursor{}, false, err
+	}
+	return cursor, true, nil
+}
+
+// GetOutboxRetries returns up to limit retries of sink of the songs whose
+// first retry is due, oldest event first.
+func (r *outboxRepository) GetOutboxRetries(sink string, limit int) ([]model.OutboxRetry, error) {
+	query := `SELECT r.attempts, r.last_error, r.next_attempt_at, ` + songEventColumns + `
+			  FROM outbox_retries AS r
+			  JOIN song_events AS e ON e.id = r.event_id
+			  WHERE r.sink = $1
+				AND r.song_id IN (SELECT heads.song_id
+								  FROM (SELECT DISTINCT ON (q.song_id) q.song_id, q.next_attempt_at
+										FROM outbox_retries AS q
+										WHERE q.sink = $1
+										ORDER BY q.song_id, q.event_id) AS heads
+								  WHERE heads.next_attempt_at <= now())
+			  ORDER BY r.event_id
+			  LIMIT $2`
+	rows, err := r.db.Query(context.Background(), query, sink, limit)
+	if err != nil {
+		slog.Error("Ошибка при выполнении запроса", "error", err)
+		return nil, err
+	}
+	retries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.OutboxRetry, error) {
+		var retry model.OutboxRetry
+		event, err := scanSongEvent(row, &retry.Attempts, &retry.LastError, &retry.NextAttemptAt)
+		retry.Event = event
+		return retry, err
+	})
+	if err != nil {
+		slog.Error("Ошибка при сканировании строки", "error", err)
+		return nil, err
+	}
+	return retries, nil
+}
+
+// GetOutboxRetrySongs reports which of songIDs have retries of sink.
+func (r *outboxRepository) GetOutboxRetrySongs(sink string, songIDs []int) (map[int]bool, error) {
+	rows, err := r.db.Query(context.Background(),
+		`SELECT DISTINCT song_id FROM outbox_retries WHERE sink = $1 AND song_id = ANY ($2)`, sink, songIDs)
+	if err != nil {
+		slog.Error("Ошибка при выполнении запроса", "error", err)
+		return nil, err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		slog.Error("Ошибка при сканировании строки", "error", err)
+		return nil, err
+	}
+	songs := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		songs[id] = true
+	}
+	return songs, nil
+}
+
+// SaveOutbox moves the cursor on and releases its lease, drops the retries of
+// the published events and stores the others. It fails with a conflict when
+// the lease was lost to another relay: the work is then done again.
+func (r *outboxRepository) SaveOutbox(cursor model.OutboxCursor, published []int64, retries []model.OutboxRetry) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		slog.Error("Ошибка при открытии транзакции", "error", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `UPDATE outbox_cursors SET event_id = $2, leased_until = now() WHERE sink = $1 AND leased_until = $3`,
+		cursor.Sink, cursor.EventID, cursor.LeasedUntil)
+	if err != nil {
+		slog.Error("Ошибка при сохранении курсора outbox", "error", err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.NewError(model.ErrConflict, "Аренда приёмника %s истекла", cursor.Sink)
+	}
+
+	batch := &pgx.Batch{}
+	if len(published) > 0 {
+		batch.Queue(`DELETE FROM outbox_retries WHERE sink = $1 AND event_id = ANY ($2)`, cursor.Sink, published)
+	}
+	for _, retry := range retries {
+		batch.Queue(`INSERT INTO outbox_retries (sink, event_id, song_id, attempts, last_error, next_attempt_at)
+					 VALUES ($1, $2, $3, $4, $5, $6)
+					 ON CONFLICT (sink, event_id) DO UPDATE
+					 SET attempts = EXCLUDED.attempts, last_error = EXCLUDED.last_error, next_attempt_at = EXCLUDED.next_attempt_at`,
+			cursor.Sink, retry.Event.ID, retry.Event.SongID, retry.Attempts, retry.LastError, retry.NextAttemptAt)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		slog.Error("Ошибка при сохранении повторов outbox", "error", err)
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		slog.Error("Ошибка при фиксации транзакции", "error", err)
+		return err
+	}
+	return nil
+}
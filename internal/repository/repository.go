@@ -1,23 +1,111 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"github.com/Xapsiel/EffectiveMobile/internal/model"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Song interface {
-	GetSongs(filter model.Song, page int, limit int) ([]model.Song, error)
-	GetSongVerse(song model.Song, verse int) (string, int, error)
+	GetSongs(filter model.Song, sort model.SongSort, page int, limit int) ([]model.Song, error)
+	GetGroupsSongs(groupIDs []int, sort model.SongSort, page int, limit int) (map[int][]model.Song, error)
+	ExportSongs(filter model.Song, withText bool, fn func(model.Song) error) error
+	GetSongbookSongs(filter model.Song, ids []int, limit int) ([]model.Song, error)
+	GetSongVerses(song model.Song) ([]model.Verse, model.Song, error)
+	GetSong(key model.Song) (model.Song, error)
 	DeleteSong(song model.Song) (bool, error)
+	DeleteSongByID(id int, version *int) (bool, error)
 	UpdateSong(song_name, group_name string, song model.Song) (bool, model.Song, error)
+	UpdateSongByID(id int, song model.Song) (bool, model.Song, error)
+	PatchSong(id int, expected *int, apply func(current model.Song) (model.Song, error)) (model.Song, error)
 	Add(song model.Song) (int, error)
+	AddBatch(songs []model.Song) ([]model.AddResult, error)
+	FindSongIDs(keys []model.Song) ([]int, error)
+	GetSongTexts() ([]model.Song, error)
+	SetSongText(id int, verses []model.Verse) (model.Song, error)
+	Atomically(fn func(Song) error) error
+}
+type Verse interface {
+	GetVerses(songID int) ([]model.Verse, model.Song, error)
+	GetSongsVerses(songIDs []int) (map[int][]model.Verse, error)
+	InsertVerse(songID int, version *int, verse model.Verse) (model.Verse, model.Song, error)
+	UpdateVerse(songID int, version *int, verse model.Verse) (model.Verse, model.Song, error)
+	DeleteVerse(songID int, version *int, ordinal int) (model.Song, error)
+	ReorderVerses(songID int, version *int, order []int) ([]model.Verse, model.Song, error)
+}
+type Group interface {
+	GetGroups(filter model.Group, page int, limit int) ([]model.Group, error)
+}
+type Event interface {
+	GetSongEvents(after int64, filter model.SongEventFilter, limit int) ([]model.SongEvent, error)
+	LastSongEventID() (int64, error)
+	ListenSongEvents(ctx context.Context, notify func()) error
+}
+type Webhook interface {
+	CreateWebhook(hook model.Webhook, secret string) (model.Webhook, error)
+	ListWebhooks() ([]model.Webhook, error)
+	GetWebhook(id int) (model.Webhook, error)
+	UpdateWebhook(hook model.Webhook) (model.Webhook, error)
+	DeleteWebhook(id int) error
+	GetWebhookDeliveries(webhookID int, status string, page int, limit int) ([]model.WebhookDelivery, error)
+	RetryWebhookDelivery(webhookID int, id int64) (model.WebhookDelivery, error)
+	ClaimWebhookDeliveries(limit int, lease time.Duration) ([]model.WebhookJob, error)
+	FinishWebhookDelivery(d model.WebhookDelivery) error
+}
+type Outbox interface {
+	ClaimOutboxCursor(sink string, lease time.Duration) (model.OutboxCursor, bool, error)
+	GetOutboxRetries(sink string, limit int) ([]model.OutboxRetry, error)
+	GetOutboxRetrySongs(sink string, songIDs []int) (map[int]bool, error)
+	SaveOutbox(cursor model.OutboxCursor, published []int64, retries []model.OutboxRetry) error
+}
+type APIKey interface {
+	CreateAPIKey(name, prefix string, hash []byte, scopes []string) (model.APIKey, error)
+	GetAPIKey(prefix string) (model.APIKey, []byte, error)
+	TouchAPIKey(id int) error
+	ListAPIKeys() ([]model.APIKey, error)
+	RevokeAPIKey(id int) (model.APIKey, error)
+}
+type User interface {
+	CreateUser(login, passwordHash, role string) (model.User, error)
+	GetUserByLogin(login string) (model.User, string, error)
+	ListUsers() ([]model.User, error)
+	SetUserRole(id int, role string) (model.User, error)
+	CreateRefreshToken(userID int, hash []byte, expiresAt time.Time) error
+	RotateRefreshToken(hash, nextHash []byte, expiresAt time.Time) (model.User, error)
+	RevokeRefreshToken(hash []byte) error
 }
 type Repository struct {
 	Song
+	Verse
+	Group
+	Event
+	Webhook
+	Outbox
+	APIKey
+	User
+	db *pgxpool.Pool
 }
 
 func NewRepository(db *pgxpool.Pool) Repository {
 	return Repository{
-		Song: NewSongRepository(db),
+		Song:    NewSongRepository(db),
+		Verse:   NewVerseRepository(db),
+		Group:   NewGroupRepository(db),
+		Event:   NewEventRepository(db),
+		Webhook: NewWebhookRepository(db),
+		Outbox:  NewOutboxRepository(db),
+		APIKey:  NewAPIKeyRepository(db),
+		User:    NewUserRepository(db),
+		db:      db,
 	}
 }
+
+// As returns the repositories recording actor as the author of the song
+// changes made through them.
+func (r Repository) As(actor string) Repository {
+	r.Song = &songRepository{db: r.db, actor: actor}
+	r.Verse = &verseRepository{db: r.db, actor: actor}
+	return r
+}
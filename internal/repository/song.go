@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -9,11 +10,14 @@ import (
 
 	"github.com/Xapsiel/EffectiveMobile/internal/model"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type songRepository struct {
-	db *pgxpool.Pool
+	db conn
+	// actor is recorded in song_audit for the changes made through r.
+	actor string
 }
 
 func NewSongRepository(db *pgxpool.Pool) *songRepository {
@@ -22,53 +26,22 @@ func NewSongRepository(db *pgxpool.Pool) *songRepository {
 	}
 }
 
-func (r *songRepository) GetSongs(filter model.Song, page int, limit int) ([]model.Song, error) {
-	slog.Info("Начало выполнения GetSongs", "page", page, "limit", limit)
+func (r *songRepository) GetSongs(filter model.Song, sort model.SongSort, page int, limit int) ([]model.Song, error) {
+	slog.Info("Начало выполнения GetSongs", "page", page, "limit", limit, "sort", sort)
 
 	offset := (page - 1) * limit
 	query := `SELECT 
-			s.id, g.name, s.song_name, 
-			s.release_date, s.link, s.text
+			s.id, s.group_id, g.name, s.song_name, 
+			s.release_date, s.link, s.text, s.version
 			FROM songs as s
 			JOIN public.groups g on g.id = s.group_id
 			WHERE 1=1`
 
-	var args []interface{}
-	argIndex := 1
-
-	if *filter.SongName != "" {
-		query += fmt.Sprintf(" AND s.song_name LIKE $%d", argIndex)
-		args = append(args, "%"+*filter.SongName+"%")
-		argIndex++
-	}
-	if *filter.Group != "" {
-		query += fmt.Sprintf(" AND g.name LIKE $%d", argIndex)
-		args = append(args, "%"+*filter.Group+"%")
-		argIndex++
-	}
-	if *filter.Text != "" {
-		query += fmt.Sprintf(" AND s.text LIKE $%d", argIndex)
-		args = append(args, "%"+*filter.Text+"%")
-		argIndex++
-	}
-	if *filter.Link != "" {
-		query += fmt.Sprintf(" AND s.link LIKE $%d", argIndex)
-		args = append(args, "%"+*filter.Link+"%")
-		argIndex++
-	}
-	if !(*filter.ReleaseDate == "01.01.0001") {
-		date, err := time.Parse("02.01.2006", *filter.ReleaseDate)
-		if err == nil || *filter.ReleaseDate == "" {
-			query += fmt.Sprintf(" AND s.release_date > $%d", argIndex)
-			args = append(args, date)
-			argIndex++
-		} else {
-			slog.Warn(err.Error())
-		}
-
-	}
+	where, args := songFilter(filter)
+	query += where
+	argIndex := len(args) + 1
 
-	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
+	query += fmt.Sprintf(" ORDER BY %s LIMIT $%d OFFSET $%d", songOrder(sort), argIndex, argIndex+1)
 	args = append(args, limit, offset)
 
 	slog.Debug("Сформированный SQL-запрос", "query", query, "args", args)
@@ -85,18 +58,20 @@ func (r *songRepository) GetSongs(filter model.Song, page int, limit int) ([]mod
 		var song model.Song
 		var group, songName, link, text string
 		var releaseDate time.Time
-		var id int
-		if err := rows.Scan(&id, &group, &songName, &releaseDate, &link, &text); err != nil {
+		var id, groupID, version int
+		if err := rows.Scan(&id, &groupID, &group, &songName, &releaseDate, &link, &text, &version); err != nil {
 			slog.Error("Ошибка при сканировании строки", "error", err)
 			return nil, err
 		}
 		song.ID = &id
+		song.GroupId = &groupID
 		song.Group = &group
 		song.SongName = &songName
 		var tmp = (fmt.Sprintf("%02d.%02d.%d", releaseDate.Day(), releaseDate.Month(), releaseDate.Year()))
 		song.ReleaseDate = &tmp
 		song.Link = &link
 		song.Text = &text
+		song.Version = &version
 		songs = append(songs, song)
 	}
 
@@ -104,41 +79,394 @@ func (r *songRepository) GetSongs(filter model.Song, page int, limit int) ([]mod
 	return songs, nil
 }
 
-func (r *songRepository) GetSongVerse(song model.Song, verse int) (string, int, error) {
-	slog.Info("Начало выполнения GetSongVerse", "song", song, "verse", verse)
+// songSortColumns are the columns behind the model.SongSort fields.
+var songSortColumns = map[string]string{
+	model.SortByID:          "s.id",
+	model.SortBySongName:    "s.song_name",
+	model.SortByGroup:       "g.name",
+	model.SortByReleaseDate: "s.release_date",
+}
+
+// songOrder returns the ORDER BY list of sort over songs s joined with groups
+// g; unknown fields order by ID.
+func songOrder(sort model.SongSort) string {
+	column, ok := songSortColumns[sort.Field]
+	if !ok {
+		column = "s.id"
+	}
+	direction := "ASC"
+	if sort.Desc {
+		direction = "DESC"
+	}
+	if column == "s.id" {
+		return "s.id " + direction
+	}
+	return column + " " + direction + ", s.id"
+}
+
+// GetGroupsSongs returns a page of the songs of every group in groupIDs with
+// one query, keyed by group ID. Pages are counted per group.
+func (r *songRepository) GetGroupsSongs(groupIDs []int, sort model.SongSort, page int, limit int) (map[int][]model.Song, error) {
+	slog.Info("Начало выполнения GetGroupsSongs", "groups", len(groupIDs), "page", page, "limit", limit, "sort", sort)
+
+	offset := (page - 1) * limit
+	query := fmt.Sprintf(`SELECT id, group_id, group_name, song_name, release_date, link, text, version
+			  FROM (
+				  SELECT s.id, s.group_id, g.name AS group_name, s.song_name, s.release_date, s.link, s.text, s.version,
+						 ROW_NUMBER() OVER (PARTITION BY s.group_id ORDER BY %s) AS n
+				  FROM songs AS s
+				  JOIN groups g ON g.id = s.group_id
+				  WHERE s.group_id = ANY($1)
+			  ) AS ranked
+			  WHERE n > $2 AND n <= $3
+			  ORDER BY group_id, n`, songOrder(sort))
+	args := []interface{}{groupIDs, offset, offset + limit}
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", args)
+
+	rows, err := r.db.Query(context.Background(), query, args...)
+	if err != nil {
+		slog.Error("Ошибка при выполнении запроса", "error", err)
+		return nil, err
+	}
+	songs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Song, error) {
+		var id, groupID, version int
+		var group, songName, link, text string
+		var releaseDate time.Time
+		if err := row.Scan(&id, &groupID, &group, &songName, &releaseDate, &link, &text, &version); err != nil {
+			return model.Song{}, err
+		}
+		date := releaseDate.Format("02.01.2006")
+		return model.Song{ID: &id, GroupId: &groupID, Group: &group, SongName: &songName, ReleaseDate: &date, Link: &link, Text: &text, Version: &version}, nil
+	})
+	if err != nil {
+		slog.Error("Ошибка при сканировании строки", "error", err)
+		return nil, err
+	}
+
+	res := make(map[int][]model.Song, len(groupIDs))
+	for _, song := range songs {
+		res[*song.GroupId] = append(res[*song.GroupId], song)
+	}
+	slog.Info("Успешно получены песни групп", "groups", len(groupIDs), "count", len(songs))
+	return res, nil
+}
+
+// exportFetchSize is the number of rows fetched from the export cursor at once.
+const exportFetchSize = 500
+
+// ExportSongs streams the songs matching filter, ordered by ID, into fn through
+// a server-side cursor, so memory use does not grow with the catalogue. With
+// withText unset the lyrics are not read at all. Iteration stops at the first
+// error returned by fn.
+func (r *songRepository) ExportSongs(filter model.Song, withText bool, fn func(model.Song) error) error {
+	slog.Info("Начало выполнения ExportSongs", "with_text", withText)
+
+	ctx := context.Background()
+	tx, err := beginRead(ctx, r.db)
+	if err != nil {
+		slog.Error("Ошибка при открытии транзакции", "error", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	textColumn := "s.text"
+	if !withText {
+		textColumn = "NULL::text"
+	}
+	where, args := songFilter(filter)
+	query := `DECLARE song_export NO SCROLL CURSOR FOR
+			  SELECT s.id, g.name, s.song_name, s.release_date, s.link, ` + textColumn + `, s.version, s.section_parser
+			  FROM songs AS s
+			  JOIN groups g ON g.id = s.group_id
+			  WHERE 1=1` + where + `
+			  ORDER BY s.id`
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", args)
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		slog.Error("Ошибка при открытии курсора", "error", err)
+		return err
+	}
+
+	fetch := fmt.Sprintf(`FETCH FORWARD %d FROM song_export`, exportFetchSize)
+	total := 0
+	for {
+		rows, err := tx.Query(ctx, fetch)
+		if err != nil {
+			slog.Error("Ошибка при чтении курсора", "error", err)
+			return err
+		}
+		songs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Song, error) {
+			var song model.Song
+			var id, version int
+			var group, songName, link string
+			var releaseDate time.Time
+			if err := row.Scan(&id, &group, &songName, &releaseDate, &link, &song.Text, &version, &song.SectionParser); err != nil {
+				return model.Song{}, err
+			}
+			date := releaseDate.Format("02.01.2006")
+			song.ID, song.Group, song.SongName, song.ReleaseDate = &id, &group, &songName, &date
+			song.Link, song.Version = &link, &version
+			return song, nil
+		})
+		if err != nil {
+			slog.Error("Ошибка при сканировании строки", "error", err)
+			return err
+		}
+		for _, song := range songs {
+			if err := fn(song); err != nil {
+				return err
+			}
+		}
+		total += len(songs)
+		if len(songs) < exportFetchSize {
+			break
+		}
+	}
+
+	slog.Info("Экспорт песен завершён", "count", total)
+	return nil
+}
+
+// GetSongbookSongs returns up to limit songs matching filter, and with ids set
+// only those songs, each with its verses. Songs are ordered by group, release
+// date and name.
+func (r *songRepository) GetSongbookSongs(filter model.Song, ids []int, limit int) ([]model.Song, error) {
+	slog.Info("Начало выполнения GetSongbookSongs", "ids", len(ids), "limit", limit)
 
-	var result struct {
-		Text string `json:"text" db:"text"`
-		ID   int    `json:"id" db:"id"`
+	ctx := context.Background()
+	tx, err := beginRead(ctx, r.db)
+	if err != nil {
+		slog.Error("Ошибка при открытии транзакции", "error", err)
+		return nil, err
 	}
+	defer tx.Rollback(ctx)
 
-	query := `SELECT s.text, s.id 
+	where, args := songFilter(filter)
+	argIndex := len(args) + 1
+	if ids != nil {
+		where += fmt.Sprintf(" AND s.id = ANY($%d)", argIndex)
+		args = append(args, ids)
+		argIndex++
+	}
+	query := `SELECT s.id, g.name, s.song_name, s.release_date, s.link, s.version
+			  FROM songs AS s
+			  JOIN groups g ON g.id = s.group_id
+			  WHERE 1=1` + where + fmt.Sprintf(`
+			  ORDER BY g.name, s.release_date, s.song_name
+			  LIMIT $%d`, argIndex)
+	args = append(args, limit)
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", args)
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		slog.Error("Ошибка при выполнении запроса", "error", err)
+		return nil, err
+	}
+	songs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Song, error) {
+		var id, version int
+		var group, songName, link string
+		var releaseDate time.Time
+		if err := row.Scan(&id, &group, &songName, &releaseDate, &link, &version); err != nil {
+			return model.Song{}, err
+		}
+		date := releaseDate.Format("02.01.2006")
+		return model.Song{ID: &id, Group: &group, SongName: &songName, ReleaseDate: &date, Link: &link, Version: &version}, nil
+	})
+	if err != nil {
+		slog.Error("Ошибка при сканировании строки", "error", err)
+		return nil, err
+	}
+	if len(songs) == 0 {
+		return songs, nil
+	}
+
+	songIDs := make([]int, len(songs))
+	index := make(map[int]int, len(songs))
+	for i, song := range songs {
+		songIDs[i], index[*song.ID] = *song.ID, i
+		songs[i].Verses = make([]model.Verse, 0)
+	}
+	query = `SELECT id, song_id, ordinal, type, text, label, repeat, header FROM verses WHERE song_id = ANY($1) ORDER BY song_id, ordinal`
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{songIDs})
+
+	rows, err = tx.Query(ctx, query, songIDs)
+	if err != nil {
+		slog.Error("Ошибка при получении куплетов", "error", err)
+		return nil, err
+	}
+	verses, err := pgx.CollectRows(rows, pgx.RowToStructByPos[model.Verse])
+	if err != nil {
+		slog.Error("Ошибка при получении куплетов", "error", err)
+		return nil, err
+	}
+	for _, verse := range verses {
+		i := index[*verse.SongID]
+		songs[i].Verses = append(songs[i].Verses, verse)
+	}
+
+	slog.Info("Успешно получены песни сборника", "count", len(songs), "verses", len(verses))
+	return songs, nil
+}
+
+// songFilter turns the set fields of filter into " AND ..." conditions on
+// songs s joined with groups g, the way GET /info filters.
+func songFilter(filter model.Song) (string, []interface{}) {
+	var query string
+	var args []interface{}
+	argIndex := 1
+
+	if filter.ID != nil && *filter.ID > 0 {
+		query += fmt.Sprintf(" AND s.id = $%d", argIndex)
+		args = append(args, *filter.ID)
+		argIndex++
+	}
+	if filter.GroupId != nil && *filter.GroupId > 0 {
+		query += fmt.Sprintf(" AND s.group_id = $%d", argIndex)
+		args = append(args, *filter.GroupId)
+		argIndex++
+	}
+	if filter.SongName != nil && *filter.SongName != "" {
+		query += fmt.Sprintf(" AND s.song_name LIKE $%d", argIndex)
+		args = append(args, "%"+*filter.SongName+"%")
+		argIndex++
+	}
+	if filter.Group != nil && *filter.Group != "" {
+		query += fmt.Sprintf(" AND g.name LIKE $%d", argIndex)
+		args = append(args, "%"+*filter.Group+"%")
+		argIndex++
+	}
+	if filter.Text != nil && *filter.Text != "" {
+		query += fmt.Sprintf(" AND s.text LIKE $%d", argIndex)
+		args = append(args, "%"+*filter.Text+"%")
+		argIndex++
+	}
+	if filter.Link != nil && *filter.Link != "" {
+		query += fmt.Sprintf(" AND s.link LIKE $%d", argIndex)
+		args = append(args, "%"+*filter.Link+"%")
+		argIndex++
+	}
+	if filter.ReleaseDate != nil && *filter.ReleaseDate != "01.01.0001" {
+		date, err := time.Parse("02.01.2006", *filter.ReleaseDate)
+		if err == nil || *filter.ReleaseDate == "" {
+			query += fmt.Sprintf(" AND s.release_date > $%d", argIndex)
+			args = append(args, date)
+			argIndex++
+		} else {
+			slog.Warn(err.Error())
+		}
+
+	}
+	return query, args
+}
+
+// GetSongVerses returns all verses of the song found by name and group, in
+// order, together with the song ID and version.
+func (r *songRepository) GetSongVerses(song model.Song) ([]model.Verse, model.Song, error) {
+	slog.Info("Начало выполнения GetSongVerses", "song", song)
+
+	ctx := context.Background()
+	// The version and the verses are read from one snapshot, so the ETag
+	// matches the verses returned.
+	tx, err := beginRead(ctx, r.db)
+	if err != nil {
+		slog.Error("Ошибка при открытии транзакции", "error", err)
+		return nil, model.Song{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `SELECT s.id, s.version
 			  FROM songs AS s
 			  JOIN groups g ON g.id = s.group_id
 			  WHERE s.song_name = $1 AND g.name = $2`
 
 	slog.Debug("Сформированный SQL-запрос", "query", query, "song_name", *song.SongName, "group", *song.Group)
 
-	row := r.db.QueryRow(context.Background(), query, song.SongName, song.Group)
-	err := row.Scan(&result.Text, &result.ID)
+	var id, version int
+	err = tx.QueryRow(ctx, query, song.SongName, song.Group).Scan(&id, &version)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			slog.Warn("Песня не найдена", "song_name", *song.SongName, "group", *song.Group)
+			return nil, model.Song{}, model.NewError(model.ErrNotFound, "Песня %q группы %q не найдена", *song.SongName, *song.Group)
+		}
 		slog.Error("Ошибка при выполнении запроса", "error", err)
-		return "", 0, err
+		return nil, model.Song{}, err
 	}
 
-	verses := strings.Split(result.Text, "\n\n")
-	if verse < 1 || verse > len(verses) {
-		slog.Error("Куплет не найден", "verse", verse, "total_verses", len(verses))
-		return "", 0, fmt.Errorf("Куплет %d не найден", verse)
+	verses, err := selectVerses(ctx, tx, id)
+	if err != nil {
+		slog.Error("Ошибка при получении куплетов", "error", err)
+		return nil, model.Song{}, err
 	}
 
-	slog.Info("Успешно получен куплет", "id", result.ID, "verse", verse)
-	return verses[verse-1], result.ID, nil
+	slog.Info("Успешно получены куплеты", "id", id, "count", len(verses))
+	return verses, model.Song{ID: &id, Version: &version}, nil
+}
+
+// GetSong looks a single song up by key.ID or, when it is unset, by
+// key.SongName and key.Group.
+func (r *songRepository) GetSong(key model.Song) (model.Song, error) {
+	slog.Info("Начало выполнения GetSong", "song", key)
+	return selectSong(context.Background(), r.db, key, false)
+}
+
+// selectSong reads the song found by key; with lock set the row stays locked
+// until the end of the transaction db belongs to.
+func selectSong(ctx context.Context, db querier, key model.Song, lock bool) (model.Song, error) {
+	query := `SELECT s.id, s.group_id, g.name, s.song_name, s.release_date, s.link, s.text, s.version, s.section_parser
+			  FROM songs AS s
+			  JOIN groups g ON g.id = s.group_id`
+	var args []interface{}
+	var notFound *model.Error
+	switch {
+	case key.ID != nil:
+		query += ` WHERE s.id = $1`
+		args = append(args, *key.ID)
+		notFound = model.NewError(model.ErrNotFound, "Песня %d не найдена", *key.ID)
+	case key.SongName != nil && key.Group != nil:
+		query += ` WHERE s.song_name = $1 AND g.name = $2`
+		args = append(args, *key.SongName, *key.Group)
+		notFound = model.NewError(model.ErrNotFound, "Песня %q группы %q не найдена", *key.SongName, *key.Group)
+	default:
+		return model.Song{}, model.NewError(model.ErrValidation, "Не указан идентификатор или название песни и группа")
+	}
+	if lock {
+		query += ` FOR UPDATE OF s`
+	}
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", args)
+
+	var song model.Song
+	var group, songName, link, text string
+	var releaseDate time.Time
+	var id, groupID, version int
+	err := db.QueryRow(ctx, query, args...).Scan(&id, &groupID, &group, &songName, &releaseDate, &link, &text, &version, &song.SectionParser)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			slog.Warn("Песня не найдена", "song", key)
+			return model.Song{}, notFound
+		}
+		slog.Error("Ошибка при выполнении запроса", "error", err)
+		return model.Song{}, err
+	}
+	date := releaseDate.Format("02.01.2006")
+	song.ID, song.GroupId, song.Group, song.SongName, song.ReleaseDate = &id, &groupID, &group, &songName, &date
+	song.Link, song.Text, song.Version = &link, &text, &version
+
+	slog.Info("Песня успешно получена", "id", id)
+	return song, nil
 }
 
 func (r *songRepository) UpdateSong(song_name, group_name string, song model.Song) (bool, model.Song, error) {
 	slog.Info("Начало выполнения UpdateSong", "song_name", song_name, "group_name", group_name)
+	return r.update(model.Song{SongName: &song_name, Group: &group_name}, song)
+}
 
+func (r *songRepository) UpdateSongByID(id int, song model.Song) (bool, model.Song, error) {
+	slog.Info("Начало выполнения UpdateSongByID", "id", id)
+	return r.update(model.Song{ID: &id}, song)
+}
+
+// update applies the set fields of song to the song found by key (see
+// songKey), bumping its version.
+func (r *songRepository) update(key model.Song, song model.Song) (bool, model.Song, error) {
 	var args []interface{}
 	argIndex := 1
 	query := `UPDATE songs SET `
@@ -150,7 +478,7 @@ func (r *songRepository) UpdateSong(song_name, group_name string, song model.Son
 	}
 	if song.Text != nil {
 		setClauses = append(setClauses, fmt.Sprintf("text = $%d", argIndex))
-		args = append(args, *song.Text)
+		args = append(args, joinVerses(song.Verses))
 		argIndex++
 	}
 	if song.Link != nil {
@@ -158,16 +486,20 @@ func (r *songRepository) UpdateSong(song_name, group_name string, song model.Son
 		args = append(args, *song.Link)
 		argIndex++
 	}
+	if song.SectionParser != nil {
+		setClauses = append(setClauses, fmt.Sprintf("section_parser = $%d", argIndex))
+		args = append(args, nullIfEmpty(song.SectionParser))
+		argIndex++
+	}
 	if song.ReleaseDate != nil {
 		date, err := time.Parse("02.01.2006", *song.ReleaseDate)
-		if err == nil || *song.ReleaseDate == "" {
-			setClauses = append(setClauses, fmt.Sprintf("release_date = $%d", argIndex))
-			args = append(args, date)
-			argIndex++
-		} else {
-			slog.Warn(err.Error())
+		if err != nil {
+			slog.Warn("Ошибка при парсинге даты", "error", err)
+			return false, song, model.NewError(model.ErrValidation, "Некорректная дата выпуска %q, ожидается формат ДД.ММ.ГГГГ", *song.ReleaseDate).WithField("releaseDate")
 		}
-
+		setClauses = append(setClauses, fmt.Sprintf("release_date = $%d", argIndex))
+		args = append(args, date)
+		argIndex++
 	}
 
 	if song.Group != nil {
@@ -184,78 +516,473 @@ func (r *songRepository) UpdateSong(song_name, group_name string, song model.Son
 
 	if len(setClauses) == 0 {
 		slog.Error("Нет данных для обновления")
-		return false, song, fmt.Errorf("нет данных для обновления")
+		return false, song, model.NewError(model.ErrValidation, "Нет данных для обновления")
 	}
 
-	query += strings.Join(setClauses, ", ") + fmt.Sprintf(" WHERE song_name = $%d AND EXISTS (SELECT 1 FROM groups WHERE name = $%d)", argIndex, argIndex+1)
-	args = append(args, song_name, group_name)
+	setClauses = append(setClauses, "version = version + 1", "updated_at = NOW()")
+	where, keyArgs := songKey(key, argIndex)
+	query += strings.Join(setClauses, ", ") + " WHERE " + where
+	args = append(args, keyArgs...)
+	argIndex += len(keyArgs)
+	if song.Version != nil {
+		query += fmt.Sprintf(" AND version = $%d", argIndex)
+		args = append(args, *song.Version)
+	}
+	query += " RETURNING id, version"
 
 	slog.Debug("Сформированный SQL-запрос", "query", query, "args", args)
 
-	_, err := r.db.Exec(context.Background(), query, args...)
+	ctx := context.Background()
+	tx, err := begin(ctx, r.db, r.actor)
 	if err != nil {
+		slog.Error("Ошибка при открытии транзакции", "error", err)
+		return false, song, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id, version int
+	err = tx.QueryRow(ctx, query, args...).Scan(&id, &version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, song, r.versionMismatch(key, song.Version)
+		}
+		if isUniqueViolation(err) {
+			slog.Warn("Песня с таким названием уже существует", "error", err)
+			return false, song, model.NewError(model.ErrConflict, "Песня с таким названием у группы уже существует")
+		}
 		slog.Error("Ошибка при обновлении песни", "error", err)
 		return false, song, fmt.Errorf("ошибка обновления песни: %w", err)
 	}
+	if song.Text != nil {
+		if err := replaceVerses(ctx, tx, id, song.Verses); err != nil {
+			slog.Error("Ошибка при сохранении куплетов", "error", err)
+			return false, song, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		slog.Error("Ошибка при фиксации транзакции", "error", err)
+		return false, song, err
+	}
+	song.ID = &id
+	song.Version = &version
 
-	slog.Info("Песня успешно обновлена", "song_name", song_name, "group_name", group_name)
+	slog.Info("Песня успешно обновлена", "id", id, "version", version)
 	return true, song, nil
 }
 
 func (r *songRepository) Add(song model.Song) (int, error) {
 	slog.Info("Начало выполнения Add", "song name", *song.SongName, "group name", *song.Group)
 
+	if song.ReleaseDate == nil {
+		return 0, model.NewError(model.ErrValidation, "Не указана дата выпуска").WithField("releaseDate")
+	}
+	date, err := time.Parse("02.01.2006", *song.ReleaseDate)
+	if err != nil {
+		slog.Error("Ошибка при парсинге даты", "error", err)
+		return 0, model.NewError(model.ErrValidation, "Некорректная дата выпуска %q, ожидается формат ДД.ММ.ГГГГ", *song.ReleaseDate).WithField("releaseDate")
+	}
+
 	group, err := r.selectGroup(*song.Group)
 	if err != nil {
 		slog.Error("Ошибка при выборе группы", "error", err)
 		return 0, err
 	}
 
-	date, err := time.Parse("02.01.2006", *song.ReleaseDate)
+	query := `INSERT INTO songs (group_id, song_name,release_date,text,link,section_parser)
+    		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	args := []interface{}{*group.ID, *song.SongName, date, joinVerses(song.Verses), valueOrEmpty(song.Link), nullIfEmpty(song.SectionParser)}
+
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", args)
+
+	ctx := context.Background()
+	tx, err := begin(ctx, r.db, r.actor)
 	if err != nil {
-		slog.Error("Ошибка при парсинге даты", "error", err)
+		slog.Error("Ошибка при открытии транзакции", "error", err)
 		return 0, err
 	}
+	defer tx.Rollback(ctx)
 
-	query := `INSERT INTO songs (group_id, song_name,release_date,text,link)
-    		VALUES ($1, $2, $3, $4, $5) RETURNING id`
-
-	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{*group.ID, *song.SongName, date, *song.Text, *song.Link})
-
-	row := r.db.QueryRow(context.Background(), query, group.ID, song.SongName, date, song.Text, song.Link)
+	row := tx.QueryRow(ctx, query, args...)
 
 	var id int
 	err = row.Scan(&id)
 	if err != nil {
+		if isUniqueViolation(err) {
+			slog.Warn("Песня уже существует", "song_name", *song.SongName, "group_name", *song.Group)
+			return 0, model.NewError(model.ErrConflict, "Песня %q группы %q уже существует", *song.SongName, *song.Group)
+		}
 		slog.Error("Ошибка при добавлении песни", "error", err)
 		return 0, err
 	}
+	if err := insertVerses(ctx, tx, id, song.Verses); err != nil {
+		slog.Error("Ошибка при сохранении куплетов", "error", err)
+		return 0, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		slog.Error("Ошибка при фиксации транзакции", "error", err)
+		return 0, err
+	}
 
 	slog.Info("Песня успешно добавлена", "id", id)
 	return id, nil
 }
+
+// AddBatch stores many songs at once: groups are resolved in a single pass and
+// rows are loaded with COPY inside one transaction. Rows that fail validation
+// get their error in the corresponding result and are skipped.
+func (r *songRepository) AddBatch(songs []model.Song) ([]model.AddResult, error) {
+	slog.Info("Начало выполнения AddBatch", "count", len(songs))
+
+	results := make([]model.AddResult, len(songs))
+	dates := make([]time.Time, len(songs))
+	valid := make([]int, 0, len(songs))
+	var groupNames []string
+	seen := make(map[string]bool)
+
+	for i, song := range songs {
+		if song.SongName == nil || *song.SongName == "" || song.Group == nil || *song.Group == "" {
+			results[i].Err = model.NewError(model.ErrValidation, "Не указано название песни или группа")
+			continue
+		}
+		if song.ReleaseDate == nil {
+			results[i].Err = model.NewError(model.ErrValidation, "Не указана дата выпуска").WithField("releaseDate")
+			continue
+		}
+		date, err := time.Parse("02.01.2006", *song.ReleaseDate)
+		if err != nil {
+			results[i].Err = model.NewError(model.ErrValidation, "Некорректная дата выпуска %q, ожидается формат ДД.ММ.ГГГГ", *song.ReleaseDate).WithField("releaseDate")
+			continue
+		}
+		dates[i] = date
+		valid = append(valid, i)
+		if !seen[*song.Group] {
+			seen[*song.Group] = true
+			groupNames = append(groupNames, *song.Group)
+		}
+	}
+	if len(valid) == 0 {
+		slog.Warn("Нет корректных песен для добавления")
+		return results, nil
+	}
+
+	ctx := context.Background()
+	tx, err := begin(ctx, r.db, r.actor)
+	if err != nil {
+		slog.Error("Ошибка при открытии транзакции", "error", err)
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	groups, err := r.selectGroups(ctx, tx, groupNames)
+	if err != nil {
+		slog.Error("Ошибка при выборе групп", "error", err)
+		return nil, err
+	}
+
+	valid, err = r.skipExisting(ctx, tx, songs, valid, groups, results)
+	if err != nil {
+		slog.Error("Ошибка при проверке существующих песен", "error", err)
+		return nil, err
+	}
+	if len(valid) == 0 {
+		slog.Warn("Все песни уже существуют")
+		return results, nil
+	}
+
+	query := `SELECT nextval(pg_get_serial_sequence('songs', 'id')) FROM generate_series(1, $1)`
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{len(valid)})
+
+	rows, err := tx.Query(ctx, query, len(valid))
+	if err != nil {
+		slog.Error("Ошибка при резервировании идентификаторов", "error", err)
+		return nil, err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		slog.Error("Ошибка при резервировании идентификаторов", "error", err)
+		return nil, err
+	}
+
+	columns := []string{"id", "group_id", "song_name", "release_date", "text", "link", "section_parser"}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"songs"}, columns, pgx.CopyFromSlice(len(valid), func(i int) ([]any, error) {
+		song := songs[valid[i]]
+		return []any{ids[i], groups[*song.Group], *song.SongName, dates[valid[i]], joinVerses(song.Verses), valueOrEmpty(song.Link), nullIfEmpty(song.SectionParser)}, nil
+	}))
+	if err != nil {
+		slog.Error("Ошибка при копировании песен", "error", err)
+		return nil, err
+	}
+
+	var verseRows [][]any
+	for i, idx := range valid {
+		for n, verse := range songs[idx].Verses {
+			verseRows = append(verseRows, []any{ids[i], n + 1, verseType(verse), valueOrEmpty(verse.Text), verse.Label, verse.Header, verseRepeat(verse)})
+		}
+	}
+	verseColumns := []string{"song_id", "ordinal", "type", "text", "label", "header", "repeat"}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"verses"}, verseColumns, pgx.CopyFromRows(verseRows))
+	if err != nil {
+		slog.Error("Ошибка при копировании куплетов", "error", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		slog.Error("Ошибка при фиксации транзакции", "error", err)
+		return nil, err
+	}
+	for i, idx := range valid {
+		results[idx].ID = ids[i]
+	}
+
+	slog.Info("Песни успешно добавлены", "count", len(valid), "skipped", len(songs)-len(valid))
+	return results, nil
+}
 func (r *songRepository) DeleteSong(song model.Song) (bool, error) {
 	slog.Info("Начало выполнения DeleteSong", "song name", *song.SongName, "group name", *song.Group)
+	return r.delete(model.Song{SongName: song.SongName, Group: song.Group}, song.Version)
+}
 
-	group, err := r.selectGroup(*song.Group)
+func (r *songRepository) DeleteSongByID(id int, version *int) (bool, error) {
+	slog.Info("Начало выполнения DeleteSongByID", "id", id)
+	return r.delete(model.Song{ID: &id}, version)
+}
+
+func (r *songRepository) delete(key model.Song, version *int) (bool, error) {
+	where, args := songKey(key, 1)
+	query := `DELETE FROM songs WHERE ` + where
+	if version != nil {
+		query += fmt.Sprintf(` AND version = $%d`, len(args)+1)
+		args = append(args, *version)
+	}
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", args)
+
+	ctx := context.Background()
+	tx, err := begin(ctx, r.db, r.actor)
 	if err != nil {
-		slog.Error("Ошибка при выборе группы", "error", err)
+		slog.Error("Ошибка при открытии транзакции", "error", err)
 		return false, err
 	}
+	defer tx.Rollback(ctx)
 
-	query := `DELETE FROM songs WHERE group_id = $1 AND song_name = $2`
-	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{group.ID, song.SongName})
-
-	_, err = r.db.Exec(context.Background(), query, group.ID, song.SongName)
+	tag, err := tx.Exec(ctx, query, args...)
 	if err != nil {
 		slog.Error("Ошибка при удалении песни", "error", err)
 		return false, err
 	}
+	if tag.RowsAffected() == 0 {
+		return false, r.versionMismatch(key, version)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		slog.Error("Ошибка при фиксации транзакции", "error", err)
+		return false, err
+	}
 
-	slog.Info("Песня успешно удалена", "song_name", *song.SongName, "group_name", *song.Group)
+	slog.Info("Песня успешно удалена", "key", key)
 	return true, nil
 }
 
+// Atomically runs fn with a song repository making all its changes in one
+// transaction, committed when fn returns nil. Called on the repository fn
+// gets, it opens a savepoint: a failed fn then undoes only its own changes
+// and leaves the transaction usable.
+func (r *songRepository) Atomically(fn func(Song) error) error {
+	ctx := context.Background()
+	tx, err := begin(ctx, r.db, r.actor)
+	if err != nil {
+		slog.Error("Ошибка при открытии транзакции", "error", err)
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&songRepository{db: tx, actor: r.actor}); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		slog.Error("Ошибка при фиксации транзакции", "error", err)
+		return err
+	}
+	return nil
+}
+
+// GetSongTexts returns the ID, group, name and text of every stored song.
+func (r *songRepository) GetSongTexts() ([]model.Song, error) {
+	slog.Info("Начало выполнения GetSongTexts")
+
+	query := `SELECT s.id, g.name, s.song_name, s.text, s.section_parser
+			  FROM songs AS s
+			  JOIN groups g ON g.id = s.group_id
+			  ORDER BY s.id`
+	slog.Debug("Сформированный SQL-запрос", "query", query)
+
+	rows, err := r.db.Query(context.Background(), query)
+	if err != nil {
+		slog.Error("Ошибка при выполнении запроса", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var songs []model.Song
+	for rows.Next() {
+		var id int
+		var group, songName, text string
+		var parser *string
+		if err := rows.Scan(&id, &group, &songName, &text, &parser); err != nil {
+			slog.Error("Ошибка при сканировании строки", "error", err)
+			return nil, err
+		}
+		songs = append(songs, model.Song{ID: &id, Group: &group, SongName: &songName, Text: &text, SectionParser: parser})
+	}
+	if err := rows.Err(); err != nil {
+		slog.Error("Ошибка при чтении строк", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Успешно получены тексты песен", "count", len(songs))
+	return songs, nil
+}
+
+// PatchSong locks the song, checks its version against expected (if set) and
+// stores the song apply derives from the current one, all in one transaction.
+// The verses are replaced when apply fills next.Verses.
+func (r *songRepository) PatchSong(id int, expected *int, apply func(current model.Song) (model.Song, error)) (model.Song, error) {
+	slog.Info("Начало выполнения PatchSong", "id", id)
+
+	ctx := context.Background()
+	tx, err := begin(ctx, r.db, r.actor)
+	if err != nil {
+		slog.Error("Ошибка при открытии транзакции", "error", err)
+		return model.Song{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockSong(ctx, tx, id, expected); err != nil {
+		return model.Song{}, err
+	}
+	current, err := selectSong(ctx, tx, model.Song{ID: &id}, true)
+	if err != nil {
+		return model.Song{}, err
+	}
+	next, err := apply(current)
+	if err != nil {
+		return model.Song{}, err
+	}
+
+	date, err := time.Parse("02.01.2006", *next.ReleaseDate)
+	if err != nil {
+		return model.Song{}, model.NewError(model.ErrValidation, "Некорректная дата выпуска %q, ожидается формат ДД.ММ.ГГГГ", *next.ReleaseDate).WithField("releaseDate")
+	}
+	groups, err := r.selectGroups(ctx, tx, []string{*next.Group})
+	if err != nil {
+		slog.Error("Ошибка при выборе группы", "error", err)
+		return model.Song{}, err
+	}
+	text := *next.Text
+	if next.Verses != nil {
+		text = joinVerses(next.Verses)
+	}
+
+	query := `UPDATE songs
+			  SET group_id = $1, song_name = $2, release_date = $3, link = $4, text = $5, section_parser = $6,
+			      version = version + 1, updated_at = NOW()
+			  WHERE id = $7
+			  RETURNING version`
+	args := []interface{}{groups[*next.Group], *next.SongName, date, valueOrEmpty(next.Link), text, nullIfEmpty(next.SectionParser), id}
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", args)
+
+	var version int
+	if err := tx.QueryRow(ctx, query, args...).Scan(&version); err != nil {
+		if isUniqueViolation(err) {
+			slog.Warn("Песня с таким названием уже существует", "error", err)
+			return model.Song{}, model.NewError(model.ErrConflict, "Песня с таким названием у группы уже существует")
+		}
+		slog.Error("Ошибка при обновлении песни", "error", err)
+		return model.Song{}, fmt.Errorf("ошибка обновления песни: %w", err)
+	}
+	if next.Verses != nil {
+		if err := replaceVerses(ctx, tx, id, next.Verses); err != nil {
+			slog.Error("Ошибка при сохранении куплетов", "error", err)
+			return model.Song{}, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		slog.Error("Ошибка при фиксации транзакции", "error", err)
+		return model.Song{}, err
+	}
+
+	next.ID, next.Version, next.Text = &id, &version, &text
+	slog.Info("Песня успешно изменена", "id", id, "version", version)
+	return next, nil
+}
+
+// FindSongIDs returns, for every key (song name and group), the ID of the
+// stored song or 0 when there is none.
+func (r *songRepository) FindSongIDs(keys []model.Song) ([]int, error) {
+	slog.Info("Начало выполнения FindSongIDs", "count", len(keys))
+
+	groups := make([]string, len(keys))
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		groups[i], names[i] = *key.Group, *key.SongName
+	}
+
+	query := `SELECT k.n, s.id
+			  FROM unnest($1::text[], $2::text[]) WITH ORDINALITY AS k(group_name, song_name, n)
+			  JOIN groups g ON g.name = k.group_name
+			  JOIN songs s ON s.group_id = g.id AND s.song_name = k.song_name`
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{groups, names})
+
+	rows, err := r.db.Query(context.Background(), query, groups, names)
+	if err != nil {
+		slog.Error("Ошибка при выполнении запроса", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int, len(keys))
+	for rows.Next() {
+		var n int64
+		var id int
+		if err := rows.Scan(&n, &id); err != nil {
+			return nil, err
+		}
+		ids[n-1] = id
+	}
+	return ids, rows.Err()
+}
+
+// SetSongText replaces the verses of the song and rebuilds its text.
+func (r *songRepository) SetSongText(id int, verses []model.Verse) (model.Song, error) {
+	slog.Info("Начало выполнения SetSongText", "id", id, "verses", len(verses))
+
+	ctx := context.Background()
+	tx, err := begin(ctx, r.db, r.actor)
+	if err != nil {
+		slog.Error("Ошибка при открытии транзакции", "error", err)
+		return model.Song{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockSong(ctx, tx, id, nil); err != nil {
+		return model.Song{}, err
+	}
+	if err := replaceVerses(ctx, tx, id, verses); err != nil {
+		slog.Error("Ошибка при сохранении куплетов", "error", err)
+		return model.Song{}, err
+	}
+	song, err := syncSongText(ctx, tx, id)
+	if err != nil {
+		slog.Error("Ошибка при обновлении текста песни", "error", err)
+		return model.Song{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		slog.Error("Ошибка при фиксации транзакции", "error", err)
+		return model.Song{}, err
+	}
+
+	slog.Info("Текст песни успешно обновлён", "id", id, "version", *song.Version)
+	return song, nil
+}
+
 func (r *songRepository) selectGroup(groupName string) (model.Group, error) {
 	slog.Info("Начало выполнения selectGroup", "groupName", groupName)
 
@@ -266,7 +993,7 @@ func (r *songRepository) selectGroup(groupName string) (model.Group, error) {
 	var group model.Group
 	err := row.Scan(&group.ID, &group.Name)
 	if err != nil {
-		if err == pgx.ErrNoRows {
+		if errors.Is(err, pgx.ErrNoRows) {
 			slog.Info("Группа не найдена, создание новой группы", "groupName", groupName)
 
 			query = `INSERT INTO groups(name) VALUES ($1) RETURNING id`
@@ -291,3 +1018,143 @@ func (r *songRepository) selectGroup(groupName string) (model.Group, error) {
 	slog.Info("Группа успешно найдена", "group", group)
 	return group, nil
 }
+
+// selectGroups returns the IDs of the named groups, creating the missing ones.
+func (r *songRepository) selectGroups(ctx context.Context, tx pgx.Tx, names []string) (map[string]int, error) {
+	slog.Info("Начало выполнения selectGroups", "count", len(names))
+
+	query := `INSERT INTO groups(name) SELECT unnest($1::text[]) ON CONFLICT (name) DO NOTHING`
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{names})
+	if _, err := tx.Exec(ctx, query, names); err != nil {
+		return nil, err
+	}
+
+	query = `SELECT id, name FROM groups WHERE name = ANY($1)`
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{names})
+	rows, err := tx.Query(ctx, query, names)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := make(map[string]int, len(names))
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		groups[name] = id
+	}
+	return groups, rows.Err()
+}
+
+// versionMismatch explains why a conditional write touched no rows: either the
+// song does not exist or its stored version differs from the expected one.
+func (r *songRepository) versionMismatch(key model.Song, expected *int) error {
+	where, args := songKey(key, 1)
+	query := `SELECT version FROM songs WHERE ` + where
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", args)
+
+	var current int
+	err := r.db.QueryRow(context.Background(), query, args...).Scan(&current)
+	if errors.Is(err, pgx.ErrNoRows) || expected == nil {
+		slog.Warn("Песня не найдена", "key", key)
+		return songNotFound(key)
+	}
+	if err != nil {
+		slog.Error("Ошибка при получении версии песни", "error", err)
+		return err
+	}
+
+	slog.Warn("Версия песни изменилась", "expected", *expected, "current", current)
+	return model.NewError(model.ErrPreconditionFailed, "Версия песни изменилась: ожидалась %d, текущая %d", *expected, current)
+}
+
+// songKey builds the WHERE condition on the songs table for key: its ID when
+// set, otherwise its name and group. Placeholders start at argIndex.
+func songKey(key model.Song, argIndex int) (string, []interface{}) {
+	if key.ID != nil {
+		return fmt.Sprintf("id = $%d", argIndex), []interface{}{*key.ID}
+	}
+	return fmt.Sprintf("song_name = $%d AND group_id = (SELECT id FROM groups WHERE name = $%d)", argIndex, argIndex+1),
+		[]interface{}{*key.SongName, *key.Group}
+}
+
+func songNotFound(key model.Song) error {
+	if key.ID != nil {
+		return model.NewError(model.ErrNotFound, "Песня %d не найдена", *key.ID)
+	}
+	return model.NewError(model.ErrNotFound, "Песня %q группы %q не найдена", *key.SongName, *key.Group)
+}
+
+// skipExisting marks rows that duplicate a stored song or an earlier row of the
+// same batch as conflicts and returns the indexes that are still insertable.
+func (r *songRepository) skipExisting(ctx context.Context, tx pgx.Tx, songs []model.Song, valid []int, groups map[string]int, results []model.AddResult) ([]int, error) {
+	groupIDs := make([]int, len(valid))
+	names := make([]string, len(valid))
+	for i, idx := range valid {
+		groupIDs[i] = groups[*songs[idx].Group]
+		names[i] = *songs[idx].SongName
+	}
+
+	query := `SELECT s.group_id, s.song_name FROM songs AS s
+			  JOIN unnest($1::int[], $2::text[]) AS k(group_id, song_name)
+			  ON k.group_id = s.group_id AND k.song_name = s.song_name`
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{groupIDs, names})
+	rows, err := tx.Query(ctx, query, groupIDs, names)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type key struct {
+		groupID int
+		name    string
+	}
+	taken := make(map[key]bool)
+	for rows.Next() {
+		var k key
+		if err := rows.Scan(&k.groupID, &k.name); err != nil {
+			return nil, err
+		}
+		taken[k] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	insertable := valid[:0]
+	for i, idx := range valid {
+		k := key{groupIDs[i], names[i]}
+		if taken[k] {
+			results[idx].Err = model.NewError(model.ErrConflict, "Песня %q группы %q уже существует", names[i], *songs[idx].Group)
+			continue
+		}
+		taken[k] = true
+		insertable = append(insertable, idx)
+	}
+	return insertable, nil
+}
+
+// uniqueViolation is the SQLSTATE Postgres reports for unique constraint errors.
+const uniqueViolation = "23505"
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+func nullIfEmpty(s *string) *string {
+	if s == nil || *s == "" {
+		return nil
+	}
+	return s
+}
+
+func valueOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
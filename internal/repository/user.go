@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type userRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewUserRepository(db *pgxpool.Pool) *userRepository {
+	return &userRepository{
+		db: db,
+	}
+}
+
+const userColumns = `id, login, role, created_at`
+
+func scanUser(row pgx.Row, extra ...any) (model.User, error) {
+	var user model.User
+	err := row.Scan(append([]any{&user.ID, &user.Login, &user.Role, &user.CreatedAt}, extra...)...)
+	return user, err
+}
+
+func (r *userRepository) CreateUser(login, passwordHash, role string) (model.User, error) {
+	slog.Info("Начало выполнения CreateUser", "login", login, "role", role)
+
+	query := `INSERT INTO users (login, password_hash, role) VALUES ($1, $2, $3) RETURNING ` + userColumns
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{login, role})
+
+	user, err := scanUser(r.db.QueryRow(context.Background(), query, login, passwordHash, role))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return model.User{}, model.NewError(model.ErrConflict, "Пользователь %s уже существует", login)
+		}
+		slog.Error("Ошибка при добавлении пользователя", "error", err)
+		return model.User{}, err
+	}
+
+	slog.Info("Пользователь добавлен", "id", user.ID)
+	return user, nil
+}
+
+// GetUserByLogin returns the user together with the password hash.
+func (r *userRepository) GetUserByLogin(login string) (model.User, string, error) {
+	query := `SELECT ` + userColumns + `, password_hash FROM users WHERE login = $1`
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{login})
+
+	var hash string
+	user, err := scanUser(r.db.QueryRow(context.Background(), query, login), &hash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.User{}, "", model.NewError(model.ErrNotFound, "Пользователь %s не найден", login)
+		}
+		slog.Error("Ошибка при выполнении запроса", "error", err)
+		return model.User{}, "", err
+	}
+	return user, hash, nil
+}
+
+func (r *userRepository) ListUsers() ([]model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users ORDER BY id`
+	slog.Debug("Сформированный SQL-запрос", "query", query)
+
+	rows, err := r.db.Query(context.Background(), query)
+	if err != nil {
+		slog.Error("Ошибка при выполнении запроса", "error", err)
+		return nil, err
+	}
+	users, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.User, error) {
+		return scanUser(row)
+	})
+	if err != nil {
+		slog.Error("Ошибка при сканировании строки", "error", err)
+		return nil, err
+	}
+	if users == nil {
+		users = make([]model.User, 0)
+	}
+	return users, nil
+}
+
+func (r *userRepository) SetUserRole(id int, role string) (model.User, error) {
+	slog.Info("Начало выполнения SetUserRole", "id", id, "role", role)
+
+	query := `UPDATE users SET role = $2 WHERE id = $1 RETURNING ` + userColumns
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{id, role})
+
+	user, err := scanUser(r.db.QueryRow(context.Background(), query, id, role))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.User{}, model.NewError(model.ErrNotFound, "Пользователь %d не найден", id)
+		}
+		slog.Error("Ошибка при изменении роли", "error", err)
+		return model.User{}, err
+	}
+	return user, nil
+}
+
+func (r *userRepository) CreateRefreshToken(userID int, hash []byte, expiresAt time.Time) error {
+	query := `INSERT INTO refresh_tokens (user_id, hash, expires_at) VALUES ($1, $2, $3)`
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{userID, expiresAt})
+
+	if _, err := r.db.Exec(context.Background(), query, userID, hash, expiresAt); err != nil {
+		slog.Error("Ошибка при сохранении refresh-токена", "error", err)
+		return err
+	}
+	return nil
+}
+
+// RotateRefreshToken exchanges the refresh token with hash for a new one with
+// nextHash and returns its user. Presenting a token that was already exchanged
+// means it leaked, so every session of the user is ended.
+func (r *userRepository) RotateRefreshToken(hash, nextHash []byte, expiresAt time.Time) (model.User, error) {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		slog.Error("Ошибка при открытии транзакции", "error", err)
+		return model.User{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `SELECT id, user_id, expires_at, revoked_at FROM refresh_tokens WHERE hash = $1 FOR UPDATE`
+	var id, userID int
+	var expires time.Time
+	var revoked *time.Time
+	if err := tx.QueryRow(ctx, query, hash).Scan(&id, &userID, &expires, &revoked); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.User{}, model.NewError(model.ErrUnauthorized, "Неверный refresh-токен")
+		}
+		slog.Error("Ошибка при выполнении запроса", "error", err)
+		return model.User{}, err
+	}
+	if revoked != nil {
+		slog.Warn("Повторное использование refresh-токена, все сессии пользователя завершены", "user_id", userID)
+		query = `UPDATE refresh_tokens SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL`
+		if _, err := tx.Exec(ctx, query, userID); err != nil {
+			slog.Error("Ошибка при отзыве refresh-токенов", "error", err)
+			return model.User{}, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return model.User{}, err
+		}
+		return model.User{}, model.NewError(model.ErrUnauthorized, "Refresh-токен уже использован, войдите заново")
+	}
+	if time.Now().After(expires) {
+		return model.User{}, model.NewError(model.ErrUnauthorized, "Срок действия refresh-токена истёк")
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE refresh_tokens SET revoked_at = now() WHERE id = $1`, id); err != nil {
+		slog.Error("Ошибка при отзыве refresh-токена", "error", err)
+		return model.User{}, err
+	}
+	query = `INSERT INTO refresh_tokens (user_id, hash, expires_at) VALUES ($1, $2, $3)`
+	if _, err := tx.Exec(ctx, query, userID, nextHash, expiresAt); err != nil {
+		slog.Error("Ошибка при сохранении refresh-токена", "error", err)
+		return model.User{}, err
+	}
+	user, err := scanUser(tx.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, userID))
+	if err != nil {
+		slog.Error("Ошибка при получении пользователя", "error", err)
+		return model.User{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		slog.Error("Ошибка при фиксации транзакции", "error", err)
+		return model.User{}, err
+	}
+	return user, nil
+}
+
+// RevokeRefreshToken ends the session of the refresh token; unknown tokens
+// are ignored.
+func (r *userRepository) RevokeRefreshToken(hash []byte) error {
+	query := `UPDATE refresh_tokens SET revoked_at = now() WHERE hash = $1 AND revoked_at IS NULL`
+	if _, err := r.db.Exec(context.Background(), query, hash); err != nil {
+		slog.Error("Ошибка при отзыве refresh-токена", "error", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,444 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type verseRepository struct {
+	db    *pgxpool.Pool
+	actor string
+}
+
+func NewVerseRepository(db *pgxpool.Pool) *verseRepository {
+	return &verseRepository{
+		db: db,
+	}
+}
+
+func (r *verseRepository) GetVerses(songID int) ([]model.Verse, model.Song, error) {
+	slog.Info("Начало выполнения GetVerses", "song_id", songID)
+
+	ctx := context.Background()
+	// The version and the verses are read from one snapshot, so the ETag
+	// matches the verses returned.
+	tx, err := beginRead(ctx, r.db)
+	if err != nil {
+		slog.Error("Ошибка при открытии транзакции", "error", err)
+		return nil, model.Song{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `SELECT version FROM songs WHERE id = $1`
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{songID})
+
+	var version int
+	if err := tx.QueryRow(ctx, query, songID).Scan(&version); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, model.Song{}, model.NewError(model.ErrNotFound, "Песня %d не найдена", songID)
+		}
+		slog.Error("Ошибка при выполнении запроса", "error", err)
+		return nil, model.Song{}, err
+	}
+
+	verses, err := selectVerses(ctx, tx, songID)
+	if err != nil {
+		slog.Error("Ошибка при получении куплетов", "error", err)
+		return nil, model.Song{}, err
+	}
+
+	slog.Info("Успешно получены куплеты", "song_id", songID, "count", len(verses))
+	return verses, model.Song{ID: &songID, Version: &version}, nil
+}
+
+// InsertVerse puts the verse at its ordinal, shifting the following verses
+// down, or appends it when no ordinal is given.
+func (r *verseRepository) InsertVerse(songID int, version *int, verse model.Verse) (model.Verse, model.Song, error) {
+	slog.Info("Начало выполнения InsertVerse", "song_id", songID, "ordinal", verse.Ordinal)
+
+	ctx := context.Background()
+	tx, err := begin(ctx, r.db, r.actor)
+	if err != nil {
+		slog.Error("Ошибка при открытии транзакции", "error", err)
+		return model.Verse{}, model.Song{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockSong(ctx, tx, songID, version); err != nil {
+		return model.Verse{}, model.Song{}, err
+	}
+
+	var count int
+	if err := tx.QueryRow(ctx, `SELECT count(*) FROM verses WHERE song_id = $1`, songID).Scan(&count); err != nil {
+		slog.Error("Ошибка при подсчёте куплетов", "error", err)
+		return model.Verse{}, model.Song{}, err
+	}
+	ordinal := count + 1
+	if verse.Ordinal != nil {
+		if *verse.Ordinal < 1 || *verse.Ordinal > count+1 {
+			return model.Verse{}, model.Song{}, model.NewError(model.ErrValidation, "Позиция куплета должна быть от 1 до %d", count+1).WithField("ordinal")
+		}
+		ordinal = *verse.Ordinal
+	}
+
+	query := `UPDATE verses SET ordinal = ordinal + 1 WHERE song_id = $1 AND ordinal >= $2`
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{songID, ordinal})
+	if _, err := tx.Exec(ctx, query, songID, ordinal); err != nil {
+		slog.Error("Ошибка при сдвиге куплетов", "error", err)
+		return model.Verse{}, model.Song{}, err
+	}
+
+	query = `INSERT INTO verses (song_id, ordinal, type, text, label, header, repeat)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+	args := []interface{}{songID, ordinal, verseType(verse), valueOrEmpty(verse.Text), verse.Label, verse.Header, verseRepeat(verse)}
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", args)
+	var id int
+	if err := tx.QueryRow(ctx, query, args...).Scan(&id); err != nil {
+		slog.Error("Ошибка при добавлении куплета", "error", err)
+		return model.Verse{}, model.Song{}, err
+	}
+
+	song, err := syncSongText(ctx, tx, songID)
+	if err != nil {
+		slog.Error("Ошибка при обновлении текста песни", "error", err)
+		return model.Verse{}, model.Song{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		slog.Error("Ошибка при фиксации транзакции", "error", err)
+		return model.Verse{}, model.Song{}, err
+	}
+
+	typ, repeat := verseType(verse), verseRepeat(verse)
+	verse.ID, verse.SongID, verse.Ordinal, verse.Type, verse.Repeat = &id, &songID, &ordinal, &typ, &repeat
+	slog.Info("Куплет успешно добавлен", "song_id", songID, "ordinal", ordinal)
+	return verse, song, nil
+}
+
+// GetSongsVerses returns the verses of every song in songIDs with one query,
+// keyed by song ID and in order. Songs without verses are left out.
+func (r *verseRepository) GetSongsVerses(songIDs []int) (map[int][]model.Verse, error) {
+	slog.Info("Начало выполнения GetSongsVerses", "songs", len(songIDs))
+
+	query := `SELECT id, song_id, ordinal, type, text, label, repeat, header FROM verses WHERE song_id = ANY($1) ORDER BY song_id, ordinal`
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{songIDs})
+
+	rows, err := r.db.Query(context.Background(), query, songIDs)
+	if err != nil {
+		slog.Error("Ошибка при получении куплетов", "error", err)
+		return nil, err
+	}
+	verses, err := pgx.CollectRows(rows, pgx.RowToStructByPos[model.Verse])
+	if err != nil {
+		slog.Error("Ошибка при получении куплетов", "error", err)
+		return nil, err
+	}
+
+	res := make(map[int][]model.Verse, len(songIDs))
+	for _, verse := range verses {
+		res[*verse.SongID] = append(res[*verse.SongID], verse)
+	}
+	slog.Info("Успешно получены куплеты песен", "songs", len(songIDs), "count", len(verses))
+	return res, nil
+}
+
+// UpdateVerse changes the fields set in verse of the verse at verse.Ordinal.
+func (r *verseRepository) UpdateVerse(songID int, version *int, verse model.Verse) (model.Verse, model.Song, error) {
+	slog.Info("Начало выполнения UpdateVerse", "song_id", songID, "ordinal", *verse.Ordinal)
+
+	ctx := context.Background()
+	tx, err := begin(ctx, r.db, r.actor)
+	if err != nil {
+		slog.Error("Ошибка при открытии транзакции", "error", err)
+		return model.Verse{}, model.Song{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockSong(ctx, tx, songID, version); err != nil {
+		return model.Verse{}, model.Song{}, err
+	}
+
+	query := `UPDATE verses SET type = COALESCE($3, type), text = COALESCE($4, text),
+			  label = COALESCE($5, label), header = COALESCE($6, header), repeat = COALESCE($7, repeat)
+			  WHERE song_id = $1 AND ordinal = $2
+			  RETURNING id, type, text, label, repeat`
+	args := []interface{}{songID, *verse.Ordinal, verse.Type, verse.Text, verse.Label, verse.Header, verse.Repeat}
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", args)
+
+	var updated model.Verse
+	err = tx.QueryRow(ctx, query, args...).Scan(&updated.ID, &updated.Type, &updated.Text, &updated.Label, &updated.Repeat)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Verse{}, model.Song{}, model.NewError(model.ErrNotFound, "Куплет %d не найден", *verse.Ordinal)
+		}
+		slog.Error("Ошибка при обновлении куплета", "error", err)
+		return model.Verse{}, model.Song{}, err
+	}
+	updated.SongID, updated.Ordinal = &songID, verse.Ordinal
+
+	song, err := syncSongText(ctx, tx, songID)
+	if err != nil {
+		slog.Error("Ошибка при обновлении текста песни", "error", err)
+		return model.Verse{}, model.Song{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		slog.Error("Ошибка при фиксации транзакции", "error", err)
+		return model.Verse{}, model.Song{}, err
+	}
+
+	slog.Info("Куплет успешно обновлён", "song_id", songID, "ordinal", *verse.Ordinal)
+	return updated, song, nil
+}
+
+// DeleteVerse removes the verse and closes the gap in the numbering.
+func (r *verseRepository) DeleteVerse(songID int, version *int, ordinal int) (model.Song, error) {
+	slog.Info("Начало выполнения DeleteVerse", "song_id", songID, "ordinal", ordinal)
+
+	ctx := context.Background()
+	tx, err := begin(ctx, r.db, r.actor)
+	if err != nil {
+		slog.Error("Ошибка при открытии транзакции", "error", err)
+		return model.Song{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockSong(ctx, tx, songID, version); err != nil {
+		return model.Song{}, err
+	}
+
+	query := `DELETE FROM verses WHERE song_id = $1 AND ordinal = $2`
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{songID, ordinal})
+	tag, err := tx.Exec(ctx, query, songID, ordinal)
+	if err != nil {
+		slog.Error("Ошибка при удалении куплета", "error", err)
+		return model.Song{}, err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.Song{}, model.NewError(model.ErrNotFound, "Куплет %d не найден", ordinal)
+	}
+
+	query = `UPDATE verses SET ordinal = ordinal - 1 WHERE song_id = $1 AND ordinal > $2`
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{songID, ordinal})
+	if _, err := tx.Exec(ctx, query, songID, ordinal); err != nil {
+		slog.Error("Ошибка при сдвиге куплетов", "error", err)
+		return model.Song{}, err
+	}
+
+	song, err := syncSongText(ctx, tx, songID)
+	if err != nil {
+		slog.Error("Ошибка при обновлении текста песни", "error", err)
+		return model.Song{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		slog.Error("Ошибка при фиксации транзакции", "error", err)
+		return model.Song{}, err
+	}
+
+	slog.Info("Куплет успешно удалён", "song_id", songID, "ordinal", ordinal)
+	return song, nil
+}
+
+// ReorderVerses renumbers the verses so that order[i] becomes verse i+1.
+// order must be a permutation of the current ordinals.
+func (r *verseRepository) ReorderVerses(songID int, version *int, order []int) ([]model.Verse, model.Song, error) {
+	slog.Info("Начало выполнения ReorderVerses", "song_id", songID, "order", order)
+
+	ctx := context.Background()
+	tx, err := begin(ctx, r.db, r.actor)
+	if err != nil {
+		slog.Error("Ошибка при открытии транзакции", "error", err)
+		return nil, model.Song{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockSong(ctx, tx, songID, version); err != nil {
+		return nil, model.Song{}, err
+	}
+
+	var count int
+	if err := tx.QueryRow(ctx, `SELECT count(*) FROM verses WHERE song_id = $1`, songID).Scan(&count); err != nil {
+		slog.Error("Ошибка при подсчёте куплетов", "error", err)
+		return nil, model.Song{}, err
+	}
+	if !isPermutation(order, count) {
+		return nil, model.Song{}, model.NewError(model.ErrValidation, "Порядок должен содержать каждый номер куплета от 1 до %d ровно один раз", count).WithField("order")
+	}
+
+	query := `UPDATE verses AS v SET ordinal = o.ordinal
+			  FROM unnest($2::int[]) WITH ORDINALITY AS o(old, ordinal)
+			  WHERE v.song_id = $1 AND v.ordinal = o.old`
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{songID, order})
+	if _, err := tx.Exec(ctx, query, songID, order); err != nil {
+		slog.Error("Ошибка при изменении порядка куплетов", "error", err)
+		return nil, model.Song{}, err
+	}
+
+	song, err := syncSongText(ctx, tx, songID)
+	if err != nil {
+		slog.Error("Ошибка при обновлении текста песни", "error", err)
+		return nil, model.Song{}, err
+	}
+	verses, err := selectVerses(ctx, tx, songID)
+	if err != nil {
+		slog.Error("Ошибка при получении куплетов", "error", err)
+		return nil, model.Song{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		slog.Error("Ошибка при фиксации транзакции", "error", err)
+		return nil, model.Song{}, err
+	}
+
+	slog.Info("Порядок куплетов успешно изменён", "song_id", songID)
+	return verses, song, nil
+}
+
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func selectVerses(ctx context.Context, db querier, songID int) ([]model.Verse, error) {
+	query := `SELECT id, song_id, ordinal, type, text, label, repeat, header FROM verses WHERE song_id = $1 ORDER BY ordinal`
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{songID})
+
+	rows, err := db.Query(ctx, query, songID)
+	if err != nil {
+		return nil, err
+	}
+	verses, err := pgx.CollectRows(rows, pgx.RowToStructByPos[model.Verse])
+	if err != nil {
+		return nil, err
+	}
+	if verses == nil {
+		verses = make([]model.Verse, 0)
+	}
+	return verses, nil
+}
+
+// lockSong locks the song row for the rest of the transaction and checks that
+// its version still matches the expected one, if any.
+func lockSong(ctx context.Context, tx pgx.Tx, songID int, expected *int) error {
+	query := `SELECT version FROM songs WHERE id = $1 FOR UPDATE`
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{songID})
+
+	var current int
+	if err := tx.QueryRow(ctx, query, songID).Scan(&current); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			slog.Warn("Песня не найдена", "song_id", songID)
+			return model.NewError(model.ErrNotFound, "Песня %d не найдена", songID)
+		}
+		slog.Error("Ошибка при блокировке песни", "error", err)
+		return err
+	}
+	if expected != nil && *expected != current {
+		slog.Warn("Версия песни изменилась", "expected", *expected, "current", current)
+		return model.NewError(model.ErrPreconditionFailed, "Версия песни изменилась: ожидалась %d, текущая %d", *expected, current)
+	}
+	return nil
+}
+
+// syncSongText rebuilds songs.text from the verses table and bumps the song
+// version. It must agree with joinVerses.
+func syncSongText(ctx context.Context, tx pgx.Tx, songID int) (model.Song, error) {
+	query := `UPDATE songs SET
+			  text = COALESCE((SELECT string_agg(concat_ws(E'\n', header, NULLIF(text, '')), E'\n\n' ORDER BY ordinal)
+			                   FROM verses WHERE song_id = $1), ''),
+			  version = version + 1, updated_at = NOW()
+			  WHERE id = $1
+			  RETURNING version`
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{songID})
+
+	var version int
+	if err := tx.QueryRow(ctx, query, songID).Scan(&version); err != nil {
+		return model.Song{}, err
+	}
+	return model.Song{ID: &songID, Version: &version}, nil
+}
+
+// replaceVerses swaps all verses of the song for the given ones.
+func replaceVerses(ctx context.Context, tx pgx.Tx, songID int, verses []model.Verse) error {
+	query := `DELETE FROM verses WHERE song_id = $1`
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{songID})
+	if _, err := tx.Exec(ctx, query, songID); err != nil {
+		return err
+	}
+	return insertVerses(ctx, tx, songID, verses)
+}
+
+// insertVerses stores verses numbered from 1 in slice order.
+func insertVerses(ctx context.Context, tx pgx.Tx, songID int, verses []model.Verse) error {
+	if len(verses) == 0 {
+		return nil
+	}
+	types := make([]string, len(verses))
+	texts := make([]string, len(verses))
+	labels := make([]*string, len(verses))
+	headers := make([]*string, len(verses))
+	repeats := make([]int, len(verses))
+	for i, verse := range verses {
+		types[i] = verseType(verse)
+		texts[i] = valueOrEmpty(verse.Text)
+		labels[i] = verse.Label
+		headers[i] = verse.Header
+		repeats[i] = verseRepeat(verse)
+	}
+
+	query := `INSERT INTO verses (song_id, ordinal, type, text, label, header, repeat)
+			  SELECT $1, v.n, v.type, v.text, v.label, v.header, v.repeat
+			  FROM unnest($2::text[], $3::text[], $4::text[], $5::text[], $6::int[])
+			       WITH ORDINALITY AS v(type, text, label, header, repeat, n)`
+	args := []interface{}{songID, types, texts, labels, headers, repeats}
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", args)
+	_, err := tx.Exec(ctx, query, args...)
+	return err
+}
+
+// joinVerses builds the song text from its verses, putting each header line
+// back in front of its verse.
+func joinVerses(verses []model.Verse) string {
+	blocks := make([]string, len(verses))
+	for i, verse := range verses {
+		switch {
+		case verse.Header == nil:
+			blocks[i] = valueOrEmpty(verse.Text)
+		case valueOrEmpty(verse.Text) == "":
+			blocks[i] = *verse.Header
+		default:
+			blocks[i] = *verse.Header + "\n" + *verse.Text
+		}
+	}
+	return strings.Join(blocks, model.VerseSeparator)
+}
+
+func verseType(verse model.Verse) string {
+	if verse.Type == nil || *verse.Type == "" {
+		return model.VerseTypeVerse
+	}
+	return *verse.Type
+}
+
+func verseRepeat(verse model.Verse) int {
+	if verse.Repeat == nil || *verse.Repeat < 1 {
+		return 1
+	}
+	return *verse.Repeat
+}
+
+func isPermutation(order []int, n int) bool {
+	if len(order) != n {
+		return false
+	}
+	seen := make([]bool, n+1)
+	for _, o := range order {
+		if o < 1 || o > n || seen[o] {
+			return false
+		}
+		seen[o] = true
+	}
+	return true
+}
@@ -0,0 +1,257 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type webhookRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookRepository(db *pgxpool.Pool) *webhookRepository {
+	return &webhookRepository{db: db}
+}
+
+const webhookColumns = `id, url, events, active, created_at`
+
+func scanWebhook(row pgx.Row) (model.Webhook, error) {
+	var hook model.Webhook
+	err := row.Scan(&hook.ID, &hook.URL, &hook.Events, &hook.Active, &hook.CreatedAt)
+	return hook, err
+}
+
+// CreateWebhook stores a new webhook signing its deliveries with secret.
+func (r *webhookRepository) CreateWebhook(hook model.Webhook, secret string) (model.Webhook, error) {
+	slog.Info("Начало выполнения CreateWebhook", "url", hook.URL, "events", hook.Events)
+
+	query := `INSERT INTO webhooks (url, events, secret, active) VALUES ($1, $2, $3, $4) RETURNING ` + webhookColumns
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{hook.URL, hook.Events, hook.Active})
+
+	hook, err := scanWebhook(r.db.QueryRow(context.Background(), query, hook.URL, hook.Events, secret, hook.Active))
+	if err != nil {
+		slog.Error("Ошибка при добавлении вебхука", "error", err)
+		return model.Webhook{}, err
+	}
+
+	slog.Info("Вебхук добавлен", "id", hook.ID)
+	return hook, nil
+}
+
+func (r *webhookRepository) ListWebhooks() ([]model.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks ORDER BY id`
+	slog.Debug("Сформированный SQL-запрос", "query", query)
+
+	rows, err := r.db.Query(context.Background(), query)
+	if err != nil {
+		slog.Error("Ошибка при выполнении запроса", "error", err)
+		return nil, err
+	}
+	hooks, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.Webhook, error) {
+		return scanWebhook(row)
+	})
+	if err != nil {
+		slog.Error("Ошибка при сканировании строки", "error", err)
+		return nil, err
+	}
+	return hooks, nil
+}
+
+func (r *webhookRepository) GetWebhook(id int) (model.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1`
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{id})
+
+	hook, err := scanWebhook(r.db.QueryRow(context.Background(), query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Webhook{}, model.NewError(model.ErrNotFound, "Вебхук %d не найден", id)
+		}
+		slog.Error("Ошибка при выполнении запроса", "error", err)
+		return model.Webhook{}, err
+	}
+	return hook, nil
+}
+
+// UpdateWebhook changes the URL, events and activity of the webhook. Events
+// recorded while a webhook is inactive are not delivered to it.
+func (r *webhookRepository) UpdateWebhook(hook model.Webhook) (model.Webhook, error) {
+	slog.Info("Начало выполнения UpdateWebhook", "id", hook.ID)
+
+	query := `UPDATE webhooks SET url = $2, events = $3, active = $4 WHERE id = $1 RETURNING ` + webhookColumns
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{hook.ID, hook.URL, hook.Events, hook.Active})
+
+	updated, err := scanWebhook(r.db.QueryRow(context.Background(), query, hook.ID, hook.URL, hook.Events, hook.Active))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Webhook{}, model.NewError(model.ErrNotFound, "Вебхук %d не найден", hook.ID)
+		}
+		slog.Error("Ошибка при изменении вебхука", "error", err)
+		return model.Webhook{}, err
+	}
+	return updated, nil
+}
+
+// DeleteWebhook removes the webhook together with its deliveries.
+func (r *webhookRepository) DeleteWebhook(id int) error {
+	slog.Info("Начало выполнения DeleteWebhook", "id", id)
+
+	tag, err := r.db.Exec(context.Background(), `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		slog.Error("Ошибка при удалении вебхука", "error", err)
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return model.NewError(model.ErrNotFound, "Вебхук %d не найден", id)
+	}
+	return nil
+}
+
+// deliveryColumns are the columns of a delivery d of the event e. The next
+// attempt time only means something while the delivery is pending.
+const deliveryColumns = `d.id, d.webhook_id, d.event_id, 'song.' || e.type, e.song_id, d.status, d.attempts,
+	CASE WHEN d.status = 'pending' THEN d.next_attempt_at END, d.last_status, d.last_error, d.created_at, d.delivered_at`
+
+func deliveryDest(d *model.WebhookDelivery) []any {
+	return []any{&d.ID, &d.WebhookID, &d.EventID, &d.Event, &d.SongID, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastStatus, &d.LastError, &d.CreatedAt, &d.DeliveredAt}
+}
+
+// GetWebhookDeliveries returns a page of the deliveries of the webhook,
+// newest first, optionally only those in the given state.
+func (r *webhookRepository) GetWebhookDeliveries(webhookID int, status string, page int, limit int) ([]model.WebhookDelivery, error) {
+	slog.Info("Начало выполнения GetWebhookDeliveries", "webhook_id", webhookID, "status", status, "page", page, "limit", limit)
+
+	query := `SELECT ` + deliveryColumns + `
+			  FROM webhook_deliveries AS d
+			  JOIN song_events AS e ON e.id = d.event_id
+			  WHERE d.webhook_id = $1`
+	args := []interface{}{webhookID}
+	argIndex := 2
+	if status != "" {
+		query += fmt.Sprintf(" AND d.status = $%d", argIndex)
+		args = append(args, status)
+		argIndex++
+	}
+	query += fmt.Sprintf(" ORDER BY d.id DESC LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
+	args = append(args, limit, (page-1)*limit)
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", args)
+
+	rows, err := r.db.Query(context.Background(), query, args...)
+	if err != nil {
+		slog.Error("Ошибка при выполнении запроса", "error", err)
+		return nil, err
+	}
+	deliveries, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.WebhookDelivery, error) {
+		var d model.WebhookDelivery
+		err := row.Scan(deliveryDest(&d)...)
+		return d, err
+	})
+	if err != nil {
+		slog.Error("Ошибка при сканировании строки", "error", err)
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// RetryWebhookDelivery makes a dead or pending delivery of the webhook due
+// now with a fresh count of attempts. Delivered ones are left alone.
+func (r *webhookRepository) RetryWebhookDelivery(webhookID int, id int64) (model.WebhookDelivery, error) {
+	slog.Info("Начало выполнения RetryWebhookDelivery", "webhook_id", webhookID, "id", id)
+
+	query := `UPDATE webhook_deliveries AS d
+			  SET status = 'pending', attempts = 0, next_attempt_at = now()
+			  FROM song_events AS e
+			  WHERE d.id = $1 AND d.webhook_id = $2 AND e.id = d.event_id AND d.status <> 'delivered'
+			  RETURNING ` + deliveryColumns
+	slog.Debug("Сформированный SQL-запрос", "query", query, "args", []interface{}{id, webhookID})
+
+	var d model.WebhookDelivery
+	err := r.db.QueryRow(context.Background(), query, id, webhookID).Scan(deliveryDest(&d)...)
+	if err == nil {
+		return d, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		slog.Error("Ошибка при повторе доставки", "error", err)
+		return model.WebhookDelivery{}, err
+	}
+
+	var exists bool
+	query = `SELECT EXISTS (SELECT 1 FROM webhook_deliveries WHERE id = $1 AND webhook_id = $2)`
+	if err := r.db.QueryRow(context.Background(), query, id, webhookID).Scan(&exists); err != nil {
+		slog.Error("Ошибка при выполнении запроса", "error", err)
+		return model.WebhookDelivery{}, err
+	}
+	if !exists {
+		return model.WebhookDelivery{}, model.NewError(model.ErrNotFound, "Доставка %d вебхука %d не найдена", id, webhookID)
+	}
+	return model.WebhookDelivery{}, model.NewError(model.ErrConflict, "Доставка %d уже выполнена", id)
+}
+
+// ClaimWebhookDeliveries takes up to limit due deliveries of active webhooks,
+// oldest first, and puts their next attempt lease away so that no other
+// dispatcher takes them meanwhile. A dispatcher that dies while sending thus
+// leaves them to be sent again after the lease.
+func (r *webhookRepository) ClaimWebhookDeliveries(limit int, lease time.Duration) ([]model.WebhookJob, error) {
+	query := `UPDATE webhook_deliveries AS d
+			  SET next_attempt_at = now() + $2 * INTERVAL '1 second'
+			  FROM webhooks AS w, song_events AS e
+			  WHERE d.id IN (SELECT due.id
+							 FROM webhook_deliveries AS due
+							 JOIN webhooks AS hook ON hook.id = due.webhook_id
+							 WHERE due.status = 'pending' AND due.next_attempt_at <= now() AND hook.active
+							 ORDER BY due.next_attempt_at, due.id
+							 LIMIT $1
+							 FOR UPDATE OF due SKIP LOCKED)
+				AND w.id = d.webhook_id AND e.id = d.event_id
+			  RETURNING ` + deliveryColumns + `, w.url, w.secret, e.group_id, e.version, e.actor, e.at, e.changes`
+
+	rows, err := r.db.Query(context.Background(), query, limit, lease.Seconds())
+	if err != nil {
+		slog.Error("Ошибка при выполнении запроса", "error", err)
+		return nil, err
+	}
+	jobs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (model.WebhookJob, error) {
+		var job model.WebhookJob
+		var changes []byte
+		dest := append(deliveryDest(&job.Delivery), &job.URL, &job.Secret,
+			&job.Event.GroupID, &job.Event.Version, &job.Event.Actor, &job.Event.At, &changes)
+		if err := row.Scan(dest...); err != nil {
+			return model.WebhookJob{}, err
+		}
+		job.Event.ID = job.Delivery.EventID
+		job.Event.SongID = job.Delivery.SongID
+		job.Event.Type = strings.TrimPrefix(job.Delivery.Event, "song.")
+		if err := json.Unmarshal(changes, &job.Event.Changes); err != nil {
+			return model.WebhookJob{}, fmt.Errorf("event %d: %w", job.Event.ID, err)
+		}
+		return job, nil
+	})
+	if err != nil {
+		slog.Error("Ошибка при сканировании строки", "error", err)
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// FinishWebhookDelivery records the outcome of an attempt: the state, the
+// number of attempts, when to try next and what the last attempt got.
+func (r *webhookRepository) FinishWebhookDelivery(d model.WebhookDelivery) error {
+	query := `UPDATE webhook_deliveries
+			  SET status = $2, attempts = $3, next_attempt_at = COALESCE($4, next_attempt_at),
+				  last_status = $5, last_error = $6, delivered_at = $7
+			  WHERE id = $1`
+	_, err := r.db.Exec(context.Background(), query, d.ID, d.Status, d.Attempts, d.NextAttemptAt, d.LastStatus, d.LastError, d.DeliveredAt)
+	if err != nil {
+		slog.Error("Ошибка при сохранении результата доставки", "id", d.ID, "error", err)
+	}
+	return err
+}
@@ -0,0 +1,103 @@
+package rpc
+
+import (
+	"time"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/Xapsiel/EffectiveMobile/internal/rpc/songspb"
+)
+
+// modelDateLayout is the release date format of model.Song.
+const modelDateLayout = "02.01.2006"
+
+func newSong(song model.Song) *songspb.Song {
+	res := &songspb.Song{
+		Id:            int64(valueOrZero(song.ID)),
+		Title:         valueOrZero(song.SongName),
+		Group:         valueOrZero(song.Group),
+		ReleaseDate:   valueOrZero(song.ReleaseDate),
+		Link:          valueOrZero(song.Link),
+		Lyrics:        valueOrZero(song.Text),
+		SectionParser: valueOrZero(song.SectionParser),
+		Version:       int64(valueOrZero(song.Version)),
+	}
+	if date, err := time.Parse(modelDateLayout, res.ReleaseDate); err == nil {
+		res.ReleaseDate = date.Format(time.DateOnly)
+	}
+	return res
+}
+
+func newVerse(verse model.Verse) *songspb.Verse {
+	return &songspb.Verse{
+		Ordinal: int32(valueOrZero(verse.Ordinal)),
+		Type:    valueOrZero(verse.Type),
+		Text:    valueOrZero(verse.Text),
+		Label:   valueOrZero(verse.Label),
+		Repeat:  int32(valueOrZero(verse.Repeat)),
+	}
+}
+
+// songFilter turns a list filter into the song filter of the service.
+func songFilter(f *songspb.SongFilter) (model.Song, error) {
+	if f == nil {
+		return model.Song{}, nil
+	}
+	filter := model.Song{
+		SongName: f.Title,
+		Group:    f.Group,
+		Link:     f.Link,
+		Text:     f.Lyrics,
+	}
+	if f.Id != nil {
+		id := int(f.GetId())
+		filter.ID = &id
+	}
+	if f.GroupId != nil {
+		groupID := int(f.GetGroupId())
+		filter.GroupId = &groupID
+	}
+	if f.ReleasedAfter != nil {
+		date, err := modelDate(f.GetReleasedAfter(), "released_after")
+		if err != nil {
+			return model.Song{}, err
+		}
+		filter.ReleaseDate = &date
+	}
+	return filter, nil
+}
+
+// songUpdate takes the fields set in an update request.
+func songUpdate(req *songspb.UpdateSongRequest) (model.Song, error) {
+	song := model.Song{
+		SongName:      req.Title,
+		Group:         req.Group,
+		Link:          req.Link,
+		Text:          req.Lyrics,
+		SectionParser: req.SectionParser,
+	}
+	if req.ReleaseDate != nil {
+		date, err := modelDate(req.GetReleaseDate(), "release_date")
+		if err != nil {
+			return model.Song{}, err
+		}
+		song.ReleaseDate = &date
+	}
+	return song, nil
+}
+
+// modelDate converts an ISO 8601 date of field to the format of model.Song.
+func modelDate(value, field string) (string, error) {
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return "", model.NewError(model.ErrValidation, "Некорректная дата выпуска %q, ожидается формат ГГГГ-ММ-ДД", value).WithField(field)
+	}
+	return date.Format(modelDateLayout), nil
+}
+
+func valueOrZero[T any](v *T) T {
+	if v == nil {
+		var zero T
+		return zero
+	}
+	return *v
+}
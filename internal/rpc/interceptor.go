@@ -0,0 +1,90 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/Xapsiel/EffectiveMobile/internal/rpc/songspb"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type contextKey int
+
+// principalContextKey is where authenticate stores the caller's
+// model.Principal.
+const principalContextKey contextKey = 0
+
+// methodRoles are the roles needed to call the song service methods, the same
+// as for their HTTP routes. Methods not listed here, such as health checks and
+// reflection, need no authentication.
+var methodRoles = map[string]string{
+	songspb.SongService_ListSongs_FullMethodName:  model.RoleViewer,
+	songspb.SongService_GetSong_FullMethodName:    model.RoleViewer,
+	songspb.SongService_GetVerse_FullMethodName:   model.RoleViewer,
+	songspb.SongService_AddSong_FullMethodName:    model.RoleEditor,
+	songspb.SongService_UpdateSong_FullMethodName: model.RoleEditor,
+	songspb.SongService_DeleteSong_FullMethodName: model.RoleAdmin,
+}
+
+// localize picks the language of error messages from the accept-language
+// metadata and turns the errors of the call into gRPC statuses.
+func (s *songServer) localize(ctx context.Context, req any, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (any, error) {
+	p := defaultPrinter
+	if s.locales != nil {
+		p, _ = s.locales.Printer(strings.Join(metadata.ValueFromIncomingContext(ctx, "accept-language"), ","))
+	}
+	res, err := next(ctx, req)
+	if err != nil {
+		return nil, statusFromErr(p, err)
+	}
+	return res, nil
+}
+
+// authenticate rejects calls to the song service without a valid API key or
+// access token, or whose caller lacks the role of the method.
+func (s *songServer) authenticate(ctx context.Context, req any, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (any, error) {
+	role, ok := methodRoles[info.FullMethod]
+	if !ok {
+		return next(ctx, req)
+	}
+	credential := credentialFromMetadata(ctx)
+	if credential == "" {
+		return nil, model.NewError(model.ErrUnauthorized, "Нужен API-ключ или токен доступа")
+	}
+	principal, err := s.service.Authenticate(credential)
+	if err != nil {
+		return nil, err
+	}
+	if !principal.Allows(role) {
+		return nil, model.NewError(model.ErrForbidden, "Недостаточно прав: нужна роль %s", role)
+	}
+	return next(context.WithValue(ctx, principalContextKey, principal), req)
+}
+
+// credentialFromMetadata reads the API key or access token from
+// "authorization: Bearer <credential>" or, as a fallback, x-api-key.
+func credentialFromMetadata(ctx context.Context) string {
+	if auth := metadata.ValueFromIncomingContext(ctx, "authorization"); len(auth) > 0 {
+		scheme, credential, ok := strings.Cut(auth[0], " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(credential)
+		}
+		return ""
+	}
+	if key := metadata.ValueFromIncomingContext(ctx, "x-api-key"); len(key) > 0 {
+		return key[0]
+	}
+	return ""
+}
+
+func principal(ctx context.Context) model.Principal {
+	p, _ := ctx.Value(principalContextKey).(model.Principal)
+	return p
+}
+
+// defaultPrinter prints messages untranslated.
+var defaultPrinter = message.NewPrinter(language.Russian)
@@ -0,0 +1,183 @@
+// Package rpc serves the songs API over gRPC. It shares the service layer,
+// authentication and message translations with the HTTP handlers.
+package rpc
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/i18n"
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/Xapsiel/EffectiveMobile/internal/rpc/songspb"
+	"github.com/Xapsiel/EffectiveMobile/internal/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type songServer struct {
+	songspb.UnimplementedSongServiceServer
+
+	service service.Service
+	locales *i18n.Localizer
+}
+
+// NewServer returns a gRPC server with the song service, the standard health
+// service and server reflection registered.
+func NewServer(services service.Service, locales *i18n.Localizer) *grpc.Server {
+	s := &songServer{service: services, locales: locales}
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(s.localize, s.authenticate))
+	songspb.RegisterSongServiceServer(srv, s)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(songspb.SongService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, healthServer)
+
+	reflection.Register(srv)
+	return srv
+}
+
+func (s *songServer) ListSongs(ctx context.Context, req *songspb.ListSongsRequest) (*songspb.ListSongsResponse, error) {
+	filter, err := songFilter(req.GetFilter())
+	if err != nil {
+		return nil, err
+	}
+	page, limit := int(req.GetPage()), int(req.GetLimit())
+	if page == 0 {
+		page = 1
+	}
+	if limit == 0 {
+		limit = 10
+	}
+
+	songs, err := s.service.GetSongs(filter, model.SongSort{}, page, limit)
+	if err != nil {
+		slog.Error("Ошибка при получении песен", "error", err)
+		return nil, err
+	}
+
+	res := &songspb.ListSongsResponse{Songs: make([]*songspb.Song, 0, len(songs))}
+	for _, song := range songs {
+		res.Songs = append(res.Songs, newSong(song))
+	}
+	slog.Info("Успешно получен список песен", "количество песен", len(songs))
+	return res, nil
+}
+
+func (s *songServer) GetSong(ctx context.Context, req *songspb.GetSongRequest) (*songspb.Song, error) {
+	song, err := s.service.GetSong(int(req.GetId()))
+	if err != nil {
+		slog.Error("Ошибка при получении песни", "error", err)
+		return nil, err
+	}
+	return newSong(song), nil
+}
+
+func (s *songServer) GetVerse(ctx context.Context, req *songspb.GetVerseRequest) (*songspb.GetVerseResponse, error) {
+	var (
+		verses []model.Verse
+		song   model.Song
+		err    error
+	)
+	switch key := req.GetSong().(type) {
+	case *songspb.GetVerseRequest_Id:
+		verses, song, err = s.service.GetVerses(int(key.Id))
+	case *songspb.GetVerseRequest_Key:
+		title, group := key.Key.GetTitle(), key.Key.GetGroup()
+		verses, song, err = s.service.GetSongVerses(model.Song{SongName: &title, Group: &group})
+	default:
+		err = model.NewError(model.ErrValidation, "Не указан идентификатор или название песни и группа")
+	}
+	if err != nil {
+		slog.Error("Ошибка при получении куплетов", "error", err)
+		return nil, err
+	}
+
+	ordinal := int(req.GetOrdinal())
+	if ordinal == 0 {
+		ordinal = 1
+	}
+	if ordinal < 0 {
+		return nil, model.NewError(model.ErrValidation, "Позиция куплета должна быть от 1 до %d", len(verses)).WithField("ordinal")
+	}
+	if ordinal > len(verses) {
+		return nil, model.NewError(model.ErrNotFound, "Куплет %d не найден", ordinal)
+	}
+
+	slog.Info("Куплет успешно получен", "song_id", *song.ID, "ordinal", ordinal)
+	return &songspb.GetVerseResponse{
+		Verse:       newVerse(verses[ordinal-1]),
+		Total:       int32(len(verses)),
+		SongId:      int64(valueOrZero(song.ID)),
+		SongVersion: int64(valueOrZero(song.Version)),
+	}, nil
+}
+
+func (s *songServer) AddSong(ctx context.Context, req *songspb.AddSongRequest) (*songspb.Song, error) {
+	services := s.as(ctx)
+	id, err := services.Add(req.GetTitle(), req.GetGroup())
+	if err != nil {
+		slog.Error("Ошибка при добавлении песни", "error", err)
+		return nil, err
+	}
+	song, err := services.GetSong(id)
+	if err != nil {
+		slog.Error("Ошибка при получении песни", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Песня успешно добавлена", "id", id)
+	return newSong(song), nil
+}
+
+func (s *songServer) UpdateSong(ctx context.Context, req *songspb.UpdateSongRequest) (*songspb.Song, error) {
+	version, err := requireVersion(req.GetVersion())
+	if err != nil {
+		return nil, err
+	}
+	song, err := songUpdate(req)
+	if err != nil {
+		return nil, err
+	}
+	song.Version = version
+
+	updated, err := s.as(ctx).UpdateSongByID(int(req.GetId()), song)
+	if err != nil {
+		slog.Error("Ошибка при обновлении песни", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Песня успешно обновлена", "id", req.GetId(), "version", valueOrZero(updated.Version))
+	return newSong(updated), nil
+}
+
+func (s *songServer) DeleteSong(ctx context.Context, req *songspb.DeleteSongRequest) (*emptypb.Empty, error) {
+	version, err := requireVersion(req.GetVersion())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.as(ctx).DeleteSongByID(int(req.GetId()), version); err != nil {
+		slog.Error("Ошибка при удалении песни", "error", err)
+		return nil, err
+	}
+
+	slog.Info("Песня успешно удалена", "id", req.GetId())
+	return &emptypb.Empty{}, nil
+}
+
+// as returns the services acting on behalf of the caller, so that song
+// changes are recorded with who made them.
+func (s *songServer) as(ctx context.Context) service.Service {
+	return s.service.As(principal(ctx).Actor())
+}
+
+// requireVersion checks that a write names the song version it is based on.
+func requireVersion(version int64) (*int, error) {
+	if version <= 0 {
+		return nil, model.NewError(model.ErrValidation, "Укажите версию песни в поле version").WithField("version")
+	}
+	v := int(version)
+	return &v, nil
+}
@@ -0,0 +1,1089 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.4
+// 	protoc        (unknown)
+// source: songs/v1/songs.proto
+
+package songspb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Song struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Title string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Group string                 `protobuf:"bytes,3,opt,name=group,proto3" json:"group,omitempty"`
+	// Release date in ISO 8601 (YYYY-MM-DD).
+	ReleaseDate string `protobuf:"bytes,4,opt,name=release_date,json=releaseDate,proto3" json:"release_date,omitempty"`
+	Link        string `protobuf:"bytes,5,opt,name=link,proto3" json:"link,omitempty"`
+	Lyrics      string `protobuf:"bytes,6,opt,name=lyrics,proto3" json:"lyrics,omitempty"`
+	// Section parser the lyrics were split with; empty means the default one.
+	SectionParser string `protobuf:"bytes,7,opt,name=section_parser,json=sectionParser,proto3" json:"section_parser,omitempty"`
+	// Version grows with every change; updates and deletes are conditioned on it.
+	Version       int64 `protobuf:"varint,8,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Song) Reset() {
+	*x = Song{}
+	mi := &file_songs_v1_songs_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Song) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Song) ProtoMessage() {}
+
+func (x *Song) ProtoReflect() protoreflect.Message {
+	mi := &file_songs_v1_songs_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Song.ProtoReflect.Descriptor instead.
+func (*Song) Descriptor() ([]byte, []int) {
+	return file_songs_v1_songs_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Song) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Song) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Song) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *Song) GetReleaseDate() string {
+	if x != nil {
+		return x.ReleaseDate
+	}
+	return ""
+}
+
+func (x *Song) GetLink() string {
+	if x != nil {
+		return x.Link
+	}
+	return ""
+}
+
+func (x *Song) GetLyrics() string {
+	if x != nil {
+		return x.Lyrics
+	}
+	return ""
+}
+
+func (x *Song) GetSectionParser() string {
+	if x != nil {
+		return x.SectionParser
+	}
+	return ""
+}
+
+func (x *Song) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type Verse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Position of the verse in the song, counted from 1.
+	Ordinal int32 `protobuf:"varint,1,opt,name=ordinal,proto3" json:"ordinal,omitempty"`
+	// One of verse, chorus, bridge, intro.
+	Type          string `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Text          string `protobuf:"bytes,3,opt,name=text,proto3" json:"text,omitempty"`
+	Label         string `protobuf:"bytes,4,opt,name=label,proto3" json:"label,omitempty"`
+	Repeat        int32  `protobuf:"varint,5,opt,name=repeat,proto3" json:"repeat,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Verse) Reset() {
+	*x = Verse{}
+	mi := &file_songs_v1_songs_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Verse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Verse) ProtoMessage() {}
+
+func (x *Verse) ProtoReflect() protoreflect.Message {
+	mi := &file_songs_v1_songs_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Verse.ProtoReflect.Descriptor instead.
+func (*Verse) Descriptor() ([]byte, []int) {
+	return file_songs_v1_songs_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Verse) GetOrdinal() int32 {
+	if x != nil {
+		return x.Ordinal
+	}
+	return 0
+}
+
+func (x *Verse) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Verse) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Verse) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *Verse) GetRepeat() int32 {
+	if x != nil {
+		return x.Repeat
+	}
+	return 0
+}
+
+// SongFilter narrows a song list; unset fields do not filter. Text fields
+// match substrings.
+type SongFilter struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Id      *int64                 `protobuf:"varint,1,opt,name=id,proto3,oneof" json:"id,omitempty"`
+	GroupId *int64                 `protobuf:"varint,2,opt,name=group_id,json=groupId,proto3,oneof" json:"group_id,omitempty"`
+	Title   *string                `protobuf:"bytes,3,opt,name=title,proto3,oneof" json:"title,omitempty"`
+	Group   *string                `protobuf:"bytes,4,opt,name=group,proto3,oneof" json:"group,omitempty"`
+	Link    *string                `protobuf:"bytes,5,opt,name=link,proto3,oneof" json:"link,omitempty"`
+	Lyrics  *string                `protobuf:"bytes,6,opt,name=lyrics,proto3,oneof" json:"lyrics,omitempty"`
+	// Only songs released after this date (YYYY-MM-DD).
+	ReleasedAfter *string `protobuf:"bytes,7,opt,name=released_after,json=releasedAfter,proto3,oneof" json:"released_after,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SongFilter) Reset() {
+	*x = SongFilter{}
+	mi := &file_songs_v1_songs_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SongFilter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SongFilter) ProtoMessage() {}
+
+func (x *SongFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_songs_v1_songs_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SongFilter.ProtoReflect.Descriptor instead.
+func (*SongFilter) Descriptor() ([]byte, []int) {
+	return file_songs_v1_songs_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SongFilter) GetId() int64 {
+	if x != nil && x.Id != nil {
+		return *x.Id
+	}
+	return 0
+}
+
+func (x *SongFilter) GetGroupId() int64 {
+	if x != nil && x.GroupId != nil {
+		return *x.GroupId
+	}
+	return 0
+}
+
+func (x *SongFilter) GetTitle() string {
+	if x != nil && x.Title != nil {
+		return *x.Title
+	}
+	return ""
+}
+
+func (x *SongFilter) GetGroup() string {
+	if x != nil && x.Group != nil {
+		return *x.Group
+	}
+	return ""
+}
+
+func (x *SongFilter) GetLink() string {
+	if x != nil && x.Link != nil {
+		return *x.Link
+	}
+	return ""
+}
+
+func (x *SongFilter) GetLyrics() string {
+	if x != nil && x.Lyrics != nil {
+		return *x.Lyrics
+	}
+	return ""
+}
+
+func (x *SongFilter) GetReleasedAfter() string {
+	if x != nil && x.ReleasedAfter != nil {
+		return *x.ReleasedAfter
+	}
+	return ""
+}
+
+type ListSongsRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Filter *SongFilter            `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	// Page number counted from 1; 0 means the first page.
+	Page int32 `protobuf:"varint,2,opt,name=page,proto3" json:"page,omitempty"`
+	// Songs per page; 0 means 10.
+	Limit         int32 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSongsRequest) Reset() {
+	*x = ListSongsRequest{}
+	mi := &file_songs_v1_songs_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSongsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSongsRequest) ProtoMessage() {}
+
+func (x *ListSongsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_songs_v1_songs_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSongsRequest.ProtoReflect.Descriptor instead.
+func (*ListSongsRequest) Descriptor() ([]byte, []int) {
+	return file_songs_v1_songs_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListSongsRequest) GetFilter() *SongFilter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *ListSongsRequest) GetPage() int32 {
+	if x != nil {
+		return x.Page
+	}
+	return 0
+}
+
+func (x *ListSongsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ListSongsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Songs         []*Song                `protobuf:"bytes,1,rep,name=songs,proto3" json:"songs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSongsResponse) Reset() {
+	*x = ListSongsResponse{}
+	mi := &file_songs_v1_songs_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSongsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSongsResponse) ProtoMessage() {}
+
+func (x *ListSongsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_songs_v1_songs_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSongsResponse.ProtoReflect.Descriptor instead.
+func (*ListSongsResponse) Descriptor() ([]byte, []int) {
+	return file_songs_v1_songs_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListSongsResponse) GetSongs() []*Song {
+	if x != nil {
+		return x.Songs
+	}
+	return nil
+}
+
+type GetSongRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSongRequest) Reset() {
+	*x = GetSongRequest{}
+	mi := &file_songs_v1_songs_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSongRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSongRequest) ProtoMessage() {}
+
+func (x *GetSongRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_songs_v1_songs_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSongRequest.ProtoReflect.Descriptor instead.
+func (*GetSongRequest) Descriptor() ([]byte, []int) {
+	return file_songs_v1_songs_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetSongRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type SongKey struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Title         string                 `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
+	Group         string                 `protobuf:"bytes,2,opt,name=group,proto3" json:"group,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SongKey) Reset() {
+	*x = SongKey{}
+	mi := &file_songs_v1_songs_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SongKey) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SongKey) ProtoMessage() {}
+
+func (x *SongKey) ProtoReflect() protoreflect.Message {
+	mi := &file_songs_v1_songs_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SongKey.ProtoReflect.Descriptor instead.
+func (*SongKey) Descriptor() ([]byte, []int) {
+	return file_songs_v1_songs_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SongKey) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *SongKey) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+type GetVerseRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Song:
+	//
+	//	*GetVerseRequest_Id
+	//	*GetVerseRequest_Key
+	Song isGetVerseRequest_Song `protobuf_oneof:"song"`
+	// Verse number counted from 1; 0 means the first verse.
+	Ordinal       int32 `protobuf:"varint,3,opt,name=ordinal,proto3" json:"ordinal,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetVerseRequest) Reset() {
+	*x = GetVerseRequest{}
+	mi := &file_songs_v1_songs_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetVerseRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetVerseRequest) ProtoMessage() {}
+
+func (x *GetVerseRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_songs_v1_songs_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetVerseRequest.ProtoReflect.Descriptor instead.
+func (*GetVerseRequest) Descriptor() ([]byte, []int) {
+	return file_songs_v1_songs_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetVerseRequest) GetSong() isGetVerseRequest_Song {
+	if x != nil {
+		return x.Song
+	}
+	return nil
+}
+
+func (x *GetVerseRequest) GetId() int64 {
+	if x != nil {
+		if x, ok := x.Song.(*GetVerseRequest_Id); ok {
+			return x.Id
+		}
+	}
+	return 0
+}
+
+func (x *GetVerseRequest) GetKey() *SongKey {
+	if x != nil {
+		if x, ok := x.Song.(*GetVerseRequest_Key); ok {
+			return x.Key
+		}
+	}
+	return nil
+}
+
+func (x *GetVerseRequest) GetOrdinal() int32 {
+	if x != nil {
+		return x.Ordinal
+	}
+	return 0
+}
+
+type isGetVerseRequest_Song interface {
+	isGetVerseRequest_Song()
+}
+
+type GetVerseRequest_Id struct {
+	Id int64 `protobuf:"varint,1,opt,name=id,proto3,oneof"`
+}
+
+type GetVerseRequest_Key struct {
+	Key *SongKey `protobuf:"bytes,2,opt,name=key,proto3,oneof"`
+}
+
+func (*GetVerseRequest_Id) isGetVerseRequest_Song() {}
+
+func (*GetVerseRequest_Key) isGetVerseRequest_Song() {}
+
+type GetVerseResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Verse *Verse                 `protobuf:"bytes,1,opt,name=verse,proto3" json:"verse,omitempty"`
+	// Number of verses in the song.
+	Total         int32 `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	SongId        int64 `protobuf:"varint,3,opt,name=song_id,json=songId,proto3" json:"song_id,omitempty"`
+	SongVersion   int64 `protobuf:"varint,4,opt,name=song_version,json=songVersion,proto3" json:"song_version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetVerseResponse) Reset() {
+	*x = GetVerseResponse{}
+	mi := &file_songs_v1_songs_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetVerseResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetVerseResponse) ProtoMessage() {}
+
+func (x *GetVerseResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_songs_v1_songs_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetVerseResponse.ProtoReflect.Descriptor instead.
+func (*GetVerseResponse) Descriptor() ([]byte, []int) {
+	return file_songs_v1_songs_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetVerseResponse) GetVerse() *Verse {
+	if x != nil {
+		return x.Verse
+	}
+	return nil
+}
+
+func (x *GetVerseResponse) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *GetVerseResponse) GetSongId() int64 {
+	if x != nil {
+		return x.SongId
+	}
+	return 0
+}
+
+func (x *GetVerseResponse) GetSongVersion() int64 {
+	if x != nil {
+		return x.SongVersion
+	}
+	return 0
+}
+
+type AddSongRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Title         string                 `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
+	Group         string                 `protobuf:"bytes,2,opt,name=group,proto3" json:"group,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddSongRequest) Reset() {
+	*x = AddSongRequest{}
+	mi := &file_songs_v1_songs_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddSongRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddSongRequest) ProtoMessage() {}
+
+func (x *AddSongRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_songs_v1_songs_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddSongRequest.ProtoReflect.Descriptor instead.
+func (*AddSongRequest) Descriptor() ([]byte, []int) {
+	return file_songs_v1_songs_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *AddSongRequest) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *AddSongRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+// UpdateSongRequest changes the set fields of a song. version is the song
+// version the change is based on; the call fails with FAILED_PRECONDITION
+// when the song has changed since.
+type UpdateSongRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Version       int64                  `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	Title         *string                `protobuf:"bytes,3,opt,name=title,proto3,oneof" json:"title,omitempty"`
+	Group         *string                `protobuf:"bytes,4,opt,name=group,proto3,oneof" json:"group,omitempty"`
+	ReleaseDate   *string                `protobuf:"bytes,5,opt,name=release_date,json=releaseDate,proto3,oneof" json:"release_date,omitempty"`
+	Link          *string                `protobuf:"bytes,6,opt,name=link,proto3,oneof" json:"link,omitempty"`
+	Lyrics        *string                `protobuf:"bytes,7,opt,name=lyrics,proto3,oneof" json:"lyrics,omitempty"`
+	SectionParser *string                `protobuf:"bytes,8,opt,name=section_parser,json=sectionParser,proto3,oneof" json:"section_parser,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateSongRequest) Reset() {
+	*x = UpdateSongRequest{}
+	mi := &file_songs_v1_songs_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateSongRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateSongRequest) ProtoMessage() {}
+
+func (x *UpdateSongRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_songs_v1_songs_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateSongRequest.ProtoReflect.Descriptor instead.
+func (*UpdateSongRequest) Descriptor() ([]byte, []int) {
+	return file_songs_v1_songs_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *UpdateSongRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UpdateSongRequest) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *UpdateSongRequest) GetTitle() string {
+	if x != nil && x.Title != nil {
+		return *x.Title
+	}
+	return ""
+}
+
+func (x *UpdateSongRequest) GetGroup() string {
+	if x != nil && x.Group != nil {
+		return *x.Group
+	}
+	return ""
+}
+
+func (x *UpdateSongRequest) GetReleaseDate() string {
+	if x != nil && x.ReleaseDate != nil {
+		return *x.ReleaseDate
+	}
+	return ""
+}
+
+func (x *UpdateSongRequest) GetLink() string {
+	if x != nil && x.Link != nil {
+		return *x.Link
+	}
+	return ""
+}
+
+func (x *UpdateSongRequest) GetLyrics() string {
+	if x != nil && x.Lyrics != nil {
+		return *x.Lyrics
+	}
+	return ""
+}
+
+func (x *UpdateSongRequest) GetSectionParser() string {
+	if x != nil && x.SectionParser != nil {
+		return *x.SectionParser
+	}
+	return ""
+}
+
+// DeleteSongRequest deletes a song if it still has the given version.
+type DeleteSongRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Version       int64                  `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteSongRequest) Reset() {
+	*x = DeleteSongRequest{}
+	mi := &file_songs_v1_songs_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteSongRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSongRequest) ProtoMessage() {}
+
+func (x *DeleteSongRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_songs_v1_songs_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSongRequest.ProtoReflect.Descriptor instead.
+func (*DeleteSongRequest) Descriptor() ([]byte, []int) {
+	return file_songs_v1_songs_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *DeleteSongRequest) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *DeleteSongRequest) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+var File_songs_v1_songs_proto protoreflect.FileDescriptor
+
+var file_songs_v1_songs_proto_rawDesc = string([]byte{
+	0x0a, 0x14, 0x73, 0x6f, 0x6e, 0x67, 0x73, 0x2f, 0x76, 0x31, 0x2f, 0x73, 0x6f, 0x6e, 0x67, 0x73,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x08, 0x73, 0x6f, 0x6e, 0x67, 0x73, 0x2e, 0x76, 0x31,
+	0x1a, 0x1b, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2f, 0x65, 0x6d, 0x70, 0x74, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xd2, 0x01,
+	0x0a, 0x04, 0x53, 0x6f, 0x6e, 0x67, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x02, 0x69, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x69, 0x74, 0x6c, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x69, 0x74, 0x6c, 0x65, 0x12, 0x14, 0x0a, 0x05,
+	0x67, 0x72, 0x6f, 0x75, 0x70, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x67, 0x72, 0x6f,
+	0x75, 0x70, 0x12, 0x21, 0x0a, 0x0c, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x5f, 0x64, 0x61,
+	0x74, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73,
+	0x65, 0x44, 0x61, 0x74, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6c, 0x69, 0x6e, 0x6b, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x6c, 0x69, 0x6e, 0x6b, 0x12, 0x16, 0x0a, 0x06, 0x6c, 0x79, 0x72,
+	0x69, 0x63, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6c, 0x79, 0x72, 0x69, 0x63,
+	0x73, 0x12, 0x25, 0x0a, 0x0e, 0x73, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x70, 0x61, 0x72,
+	0x73, 0x65, 0x72, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x73, 0x65, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x50, 0x61, 0x72, 0x73, 0x65, 0x72, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x18, 0x08, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69,
+	0x6f, 0x6e, 0x22, 0x77, 0x0a, 0x05, 0x56, 0x65, 0x72, 0x73, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x6f,
+	0x72, 0x64, 0x69, 0x6e, 0x61, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x6f, 0x72,
+	0x64, 0x69, 0x6e, 0x61, 0x6c, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x65, 0x78,
+	0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x65, 0x78, 0x74, 0x12, 0x14, 0x0a,
+	0x05, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6c, 0x61,
+	0x62, 0x65, 0x6c, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x70, 0x65, 0x61, 0x74, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x06, 0x72, 0x65, 0x70, 0x65, 0x61, 0x74, 0x22, 0xa8, 0x02, 0x0a, 0x0a,
+	0x53, 0x6f, 0x6e, 0x67, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x12, 0x13, 0x0a, 0x02, 0x69, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x48, 0x00, 0x52, 0x02, 0x69, 0x64, 0x88, 0x01, 0x01, 0x12,
+	0x1e, 0x0a, 0x08, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x03, 0x48, 0x01, 0x52, 0x07, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x49, 0x64, 0x88, 0x01, 0x01, 0x12,
+	0x19, 0x0a, 0x05, 0x74, 0x69, 0x74, 0x6c, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x48, 0x02,
+	0x52, 0x05, 0x74, 0x69, 0x74, 0x6c, 0x65, 0x88, 0x01, 0x01, 0x12, 0x19, 0x0a, 0x05, 0x67, 0x72,
+	0x6f, 0x75, 0x70, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x48, 0x03, 0x52, 0x05, 0x67, 0x72, 0x6f,
+	0x75, 0x70, 0x88, 0x01, 0x01, 0x12, 0x17, 0x0a, 0x04, 0x6c, 0x69, 0x6e, 0x6b, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x09, 0x48, 0x04, 0x52, 0x04, 0x6c, 0x69, 0x6e, 0x6b, 0x88, 0x01, 0x01, 0x12, 0x1b,
+	0x0a, 0x06, 0x6c, 0x79, 0x72, 0x69, 0x63, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x48, 0x05,
+	0x52, 0x06, 0x6c, 0x79, 0x72, 0x69, 0x63, 0x73, 0x88, 0x01, 0x01, 0x12, 0x2a, 0x0a, 0x0e, 0x72,
+	0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x64, 0x5f, 0x61, 0x66, 0x74, 0x65, 0x72, 0x18, 0x07, 0x20,
+	0x01, 0x28, 0x09, 0x48, 0x06, 0x52, 0x0d, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x64, 0x41,
+	0x66, 0x74, 0x65, 0x72, 0x88, 0x01, 0x01, 0x42, 0x05, 0x0a, 0x03, 0x5f, 0x69, 0x64, 0x42, 0x0b,
+	0x0a, 0x09, 0x5f, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x5f, 0x69, 0x64, 0x42, 0x08, 0x0a, 0x06, 0x5f,
+	0x74, 0x69, 0x74, 0x6c, 0x65, 0x42, 0x08, 0x0a, 0x06, 0x5f, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x42,
+	0x07, 0x0a, 0x05, 0x5f, 0x6c, 0x69, 0x6e, 0x6b, 0x42, 0x09, 0x0a, 0x07, 0x5f, 0x6c, 0x79, 0x72,
+	0x69, 0x63, 0x73, 0x42, 0x11, 0x0a, 0x0f, 0x5f, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x64,
+	0x5f, 0x61, 0x66, 0x74, 0x65, 0x72, 0x22, 0x6a, 0x0a, 0x10, 0x4c, 0x69, 0x73, 0x74, 0x53, 0x6f,
+	0x6e, 0x67, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x2c, 0x0a, 0x06, 0x66, 0x69,
+	0x6c, 0x74, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x73, 0x6f, 0x6e,
+	0x67, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x6f, 0x6e, 0x67, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72,
+	0x52, 0x06, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x67, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x70, 0x61, 0x67, 0x65, 0x12, 0x14, 0x0a, 0x05,
+	0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x6c, 0x69, 0x6d,
+	0x69, 0x74, 0x22, 0x39, 0x0a, 0x11, 0x4c, 0x69, 0x73, 0x74, 0x53, 0x6f, 0x6e, 0x67, 0x73, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x24, 0x0a, 0x05, 0x73, 0x6f, 0x6e, 0x67, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x73, 0x6f, 0x6e, 0x67, 0x73, 0x2e, 0x76,
+	0x31, 0x2e, 0x53, 0x6f, 0x6e, 0x67, 0x52, 0x05, 0x73, 0x6f, 0x6e, 0x67, 0x73, 0x22, 0x20, 0x0a,
+	0x0e, 0x47, 0x65, 0x74, 0x53, 0x6f, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x69, 0x64, 0x22,
+	0x35, 0x0a, 0x07, 0x53, 0x6f, 0x6e, 0x67, 0x4b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x69,
+	0x74, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x69, 0x74, 0x6c, 0x65,
+	0x12, 0x14, 0x0a, 0x05, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x22, 0x6c, 0x0a, 0x0f, 0x47, 0x65, 0x74, 0x56, 0x65, 0x72,
+	0x73, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x02, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x03, 0x48, 0x00, 0x52, 0x02, 0x69, 0x64, 0x12, 0x25, 0x0a, 0x03, 0x6b,
+	0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x73, 0x6f, 0x6e, 0x67, 0x73,
+	0x2e, 0x76, 0x31, 0x2e, 0x53, 0x6f, 0x6e, 0x67, 0x4b, 0x65, 0x79, 0x48, 0x00, 0x52, 0x03, 0x6b,
+	0x65, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x6f, 0x72, 0x64, 0x69, 0x6e, 0x61, 0x6c, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x07, 0x6f, 0x72, 0x64, 0x69, 0x6e, 0x61, 0x6c, 0x42, 0x06, 0x0a, 0x04,
+	0x73, 0x6f, 0x6e, 0x67, 0x22, 0x8b, 0x01, 0x0a, 0x10, 0x47, 0x65, 0x74, 0x56, 0x65, 0x72, 0x73,
+	0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x25, 0x0a, 0x05, 0x76, 0x65, 0x72,
+	0x73, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x73, 0x6f, 0x6e, 0x67, 0x73,
+	0x2e, 0x76, 0x31, 0x2e, 0x56, 0x65, 0x72, 0x73, 0x65, 0x52, 0x05, 0x76, 0x65, 0x72, 0x73, 0x65,
+	0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x12, 0x17, 0x0a, 0x07, 0x73, 0x6f, 0x6e, 0x67, 0x5f, 0x69,
+	0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x73, 0x6f, 0x6e, 0x67, 0x49, 0x64, 0x12,
+	0x21, 0x0a, 0x0c, 0x73, 0x6f, 0x6e, 0x67, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x73, 0x6f, 0x6e, 0x67, 0x56, 0x65, 0x72, 0x73, 0x69,
+	0x6f, 0x6e, 0x22, 0x3c, 0x0a, 0x0e, 0x41, 0x64, 0x64, 0x53, 0x6f, 0x6e, 0x67, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x69, 0x74, 0x6c, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x69, 0x74, 0x6c, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x67, 0x72,
+	0x6f, 0x75, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x67, 0x72, 0x6f, 0x75, 0x70,
+	0x22, 0xc9, 0x02, 0x0a, 0x11, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x53, 0x6f, 0x6e, 0x67, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x02, 0x69, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e,
+	0x12, 0x19, 0x0a, 0x05, 0x74, 0x69, 0x74, 0x6c, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x48,
+	0x00, 0x52, 0x05, 0x74, 0x69, 0x74, 0x6c, 0x65, 0x88, 0x01, 0x01, 0x12, 0x19, 0x0a, 0x05, 0x67,
+	0x72, 0x6f, 0x75, 0x70, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x48, 0x01, 0x52, 0x05, 0x67, 0x72,
+	0x6f, 0x75, 0x70, 0x88, 0x01, 0x01, 0x12, 0x26, 0x0a, 0x0c, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73,
+	0x65, 0x5f, 0x64, 0x61, 0x74, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x48, 0x02, 0x52, 0x0b,
+	0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x44, 0x61, 0x74, 0x65, 0x88, 0x01, 0x01, 0x12, 0x17,
+	0x0a, 0x04, 0x6c, 0x69, 0x6e, 0x6b, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x48, 0x03, 0x52, 0x04,
+	0x6c, 0x69, 0x6e, 0x6b, 0x88, 0x01, 0x01, 0x12, 0x1b, 0x0a, 0x06, 0x6c, 0x79, 0x72, 0x69, 0x63,
+	0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x48, 0x04, 0x52, 0x06, 0x6c, 0x79, 0x72, 0x69, 0x63,
+	0x73, 0x88, 0x01, 0x01, 0x12, 0x2a, 0x0a, 0x0e, 0x73, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x5f,
+	0x70, 0x61, 0x72, 0x73, 0x65, 0x72, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x48, 0x05, 0x52, 0x0d,
+	0x73, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x50, 0x61, 0x72, 0x73, 0x65, 0x72, 0x88, 0x01, 0x01,
+	0x42, 0x08, 0x0a, 0x06, 0x5f, 0x74, 0x69, 0x74, 0x6c, 0x65, 0x42, 0x08, 0x0a, 0x06, 0x5f, 0x67,
+	0x72, 0x6f, 0x75, 0x70, 0x42, 0x0f, 0x0a, 0x0d, 0x5f, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65,
+	0x5f, 0x64, 0x61, 0x74, 0x65, 0x42, 0x07, 0x0a, 0x05, 0x5f, 0x6c, 0x69, 0x6e, 0x6b, 0x42, 0x09,
+	0x0a, 0x07, 0x5f, 0x6c, 0x79, 0x72, 0x69, 0x63, 0x73, 0x42, 0x11, 0x0a, 0x0f, 0x5f, 0x73, 0x65,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x70, 0x61, 0x72, 0x73, 0x65, 0x72, 0x22, 0x3d, 0x0a, 0x11,
+	0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x53, 0x6f, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x69,
+	0x64, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x32, 0xfe, 0x02, 0x0a, 0x0b,
+	0x53, 0x6f, 0x6e, 0x67, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x44, 0x0a, 0x09, 0x4c,
+	0x69, 0x73, 0x74, 0x53, 0x6f, 0x6e, 0x67, 0x73, 0x12, 0x1a, 0x2e, 0x73, 0x6f, 0x6e, 0x67, 0x73,
+	0x2e, 0x76, 0x31, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x53, 0x6f, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x73, 0x6f, 0x6e, 0x67, 0x73, 0x2e, 0x76, 0x31, 0x2e,
+	0x4c, 0x69, 0x73, 0x74, 0x53, 0x6f, 0x6e, 0x67, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x33, 0x0a, 0x07, 0x47, 0x65, 0x74, 0x53, 0x6f, 0x6e, 0x67, 0x12, 0x18, 0x2e, 0x73,
+	0x6f, 0x6e, 0x67, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x53, 0x6f, 0x6e, 0x67, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0e, 0x2e, 0x73, 0x6f, 0x6e, 0x67, 0x73, 0x2e, 0x76,
+	0x31, 0x2e, 0x53, 0x6f, 0x6e, 0x67, 0x12, 0x41, 0x0a, 0x08, 0x47, 0x65, 0x74, 0x56, 0x65, 0x72,
+	0x73, 0x65, 0x12, 0x19, 0x2e, 0x73, 0x6f, 0x6e, 0x67, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65,
+	0x74, 0x56, 0x65, 0x72, 0x73, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e,
+	0x73, 0x6f, 0x6e, 0x67, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x65, 0x74, 0x56, 0x65, 0x72, 0x73,
+	0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x33, 0x0a, 0x07, 0x41, 0x64, 0x64,
+	0x53, 0x6f, 0x6e, 0x67, 0x12, 0x18, 0x2e, 0x73, 0x6f, 0x6e, 0x67, 0x73, 0x2e, 0x76, 0x31, 0x2e,
+	0x41, 0x64, 0x64, 0x53, 0x6f, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0e,
+	0x2e, 0x73, 0x6f, 0x6e, 0x67, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x6f, 0x6e, 0x67, 0x12, 0x39,
+	0x0a, 0x0a, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x53, 0x6f, 0x6e, 0x67, 0x12, 0x1b, 0x2e, 0x73,
+	0x6f, 0x6e, 0x67, 0x73, 0x2e, 0x76, 0x31, 0x2e, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x53, 0x6f,
+	0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0e, 0x2e, 0x73, 0x6f, 0x6e, 0x67,
+	0x73, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x6f, 0x6e, 0x67, 0x12, 0x41, 0x0a, 0x0a, 0x44, 0x65, 0x6c,
+	0x65, 0x74, 0x65, 0x53, 0x6f, 0x6e, 0x67, 0x12, 0x1b, 0x2e, 0x73, 0x6f, 0x6e, 0x67, 0x73, 0x2e,
+	0x76, 0x31, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x53, 0x6f, 0x6e, 0x67, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x42, 0x41, 0x5a, 0x3f,
+	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x58, 0x61, 0x70, 0x73, 0x69,
+	0x65, 0x6c, 0x2f, 0x45, 0x66, 0x66, 0x65, 0x63, 0x74, 0x69, 0x76, 0x65, 0x4d, 0x6f, 0x62, 0x69,
+	0x6c, 0x65, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x72, 0x70, 0x63, 0x2f,
+	0x73, 0x6f, 0x6e, 0x67, 0x73, 0x70, 0x62, 0x3b, 0x73, 0x6f, 0x6e, 0x67, 0x73, 0x70, 0x62, 0x62,
+	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+})
+
+var (
+	file_songs_v1_songs_proto_rawDescOnce sync.Once
+	file_songs_v1_songs_proto_rawDescData []byte
+)
+
+func file_songs_v1_songs_proto_rawDescGZIP() []byte {
+	file_songs_v1_songs_proto_rawDescOnce.Do(func() {
+		file_songs_v1_songs_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_songs_v1_songs_proto_rawDesc), len(file_songs_v1_songs_proto_rawDesc)))
+	})
+	return file_songs_v1_songs_proto_rawDescData
+}
+
+var file_songs_v1_songs_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_songs_v1_songs_proto_goTypes = []any{
+	(*Song)(nil),              // 0: songs.v1.Song
+	(*Verse)(nil),             // 1: songs.v1.Verse
+	(*SongFilter)(nil),        // 2: songs.v1.SongFilter
+	(*ListSongsRequest)(nil),  // 3: songs.v1.ListSongsRequest
+	(*ListSongsResponse)(nil), // 4: songs.v1.ListSongsResponse
+	(*GetSongRequest)(nil),    // 5: songs.v1.GetSongRequest
+	(*SongKey)(nil),           // 6: songs.v1.SongKey
+	(*GetVerseRequest)(nil),   // 7: songs.v1.GetVerseRequest
+	(*GetVerseResponse)(nil),  // 8: songs.v1.GetVerseResponse
+	(*AddSongRequest)(nil),    // 9: songs.v1.AddSongRequest
+	(*UpdateSongRequest)(nil), // 10: songs.v1.UpdateSongRequest
+	(*DeleteSongRequest)(nil), // 11: songs.v1.DeleteSongRequest
+	(*emptypb.Empty)(nil),     // 12: google.protobuf.Empty
+}
+var file_songs_v1_songs_proto_depIdxs = []int32{
+	2,  // 0: songs.v1.ListSongsRequest.filter:type_name -> songs.v1.SongFilter
+	0,  // 1: songs.v1.ListSongsResponse.songs:type_name -> songs.v1.Song
+	6,  // 2: songs.v1.GetVerseRequest.key:type_name -> songs.v1.SongKey
+	1,  // 3: songs.v1.GetVerseResponse.verse:type_name -> songs.v1.Verse
+	3,  // 4: songs.v1.SongService.ListSongs:input_type -> songs.v1.ListSongsRequest
+	5,  // 5: songs.v1.SongService.GetSong:input_type -> songs.v1.GetSongRequest
+	7,  // 6: songs.v1.SongService.GetVerse:input_type -> songs.v1.GetVerseRequest
+	9,  // 7: songs.v1.SongService.AddSong:input_type -> songs.v1.AddSongRequest
+	10, // 8: songs.v1.SongService.UpdateSong:input_type -> songs.v1.UpdateSongRequest
+	11, // 9: songs.v1.SongService.DeleteSong:input_type -> songs.v1.DeleteSongRequest
+	4,  // 10: songs.v1.SongService.ListSongs:output_type -> songs.v1.ListSongsResponse
+	0,  // 11: songs.v1.SongService.GetSong:output_type -> songs.v1.Song
+	8,  // 12: songs.v1.SongService.GetVerse:output_type -> songs.v1.GetVerseResponse
+	0,  // 13: songs.v1.SongService.AddSong:output_type -> songs.v1.Song
+	0,  // 14: songs.v1.SongService.UpdateSong:output_type -> songs.v1.Song
+	12, // 15: songs.v1.SongService.DeleteSong:output_type -> google.protobuf.Empty
+	10, // [10:16] is the sub-list for method output_type
+	4,  // [4:10] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_songs_v1_songs_proto_init() }
+func file_songs_v1_songs_proto_init() {
+	if File_songs_v1_songs_proto != nil {
+		return
+	}
+	file_songs_v1_songs_proto_msgTypes[2].OneofWrappers = []any{}
+	file_songs_v1_songs_proto_msgTypes[7].OneofWrappers = []any{
+		(*GetVerseRequest_Id)(nil),
+		(*GetVerseRequest_Key)(nil),
+	}
+	file_songs_v1_songs_proto_msgTypes[10].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_songs_v1_songs_proto_rawDesc), len(file_songs_v1_songs_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_songs_v1_songs_proto_goTypes,
+		DependencyIndexes: file_songs_v1_songs_proto_depIdxs,
+		MessageInfos:      file_songs_v1_songs_proto_msgTypes,
+	}.Build()
+	File_songs_v1_songs_proto = out.File
+	file_songs_v1_songs_proto_goTypes = nil
+	file_songs_v1_songs_proto_depIdxs = nil
+}
@@ -0,0 +1,334 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: songs/v1/songs.proto
+
+package songspb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	SongService_ListSongs_FullMethodName  = "/songs.v1.SongService/ListSongs"
+	SongService_GetSong_FullMethodName    = "/songs.v1.SongService/GetSong"
+	SongService_GetVerse_FullMethodName   = "/songs.v1.SongService/GetVerse"
+	SongService_AddSong_FullMethodName    = "/songs.v1.SongService/AddSong"
+	SongService_UpdateSong_FullMethodName = "/songs.v1.SongService/UpdateSong"
+	SongService_DeleteSong_FullMethodName = "/songs.v1.SongService/DeleteSong"
+)
+
+// SongServiceClient is the client API for SongService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// SongService is the gRPC counterpart of the songs HTTP API. Calls are
+// authenticated with an API key or access token in the "authorization"
+// ("Bearer <credential>") or "x-api-key" metadata and need the same roles as
+// their HTTP routes: viewer to read, editor to add and update, admin to delete.
+type SongServiceClient interface {
+	// ListSongs returns a page of songs matching the filter.
+	ListSongs(ctx context.Context, in *ListSongsRequest, opts ...grpc.CallOption) (*ListSongsResponse, error)
+	// GetSong returns a song by ID.
+	GetSong(ctx context.Context, in *GetSongRequest, opts ...grpc.CallOption) (*Song, error)
+	// GetVerse returns a verse of a song given by ID or by title and group.
+	GetVerse(ctx context.Context, in *GetVerseRequest, opts ...grpc.CallOption) (*GetVerseResponse, error)
+	// AddSong adds a song, filling its details from the song info service.
+	AddSong(ctx context.Context, in *AddSongRequest, opts ...grpc.CallOption) (*Song, error)
+	// UpdateSong changes the given fields of a song.
+	UpdateSong(ctx context.Context, in *UpdateSongRequest, opts ...grpc.CallOption) (*Song, error)
+	// DeleteSong deletes a song.
+	DeleteSong(ctx context.Context, in *DeleteSongRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+}
+
+type songServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSongServiceClient(cc grpc.ClientConnInterface) SongServiceClient {
+	return &songServiceClient{cc}
+}
+
+func (c *songServiceClient) ListSongs(ctx context.Context, in *ListSongsRequest, opts ...grpc.CallOption) (*ListSongsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListSongsResponse)
+	err := c.cc.Invoke(ctx, SongService_ListSongs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *songServiceClient) GetSong(ctx context.Context, in *GetSongRequest, opts ...grpc.CallOption) (*Song, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Song)
+	err := c.cc.Invoke(ctx, SongService_GetSong_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *songServiceClient) GetVerse(ctx context.Context, in *GetVerseRequest, opts ...grpc.CallOption) (*GetVerseResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetVerseResponse)
+	err := c.cc.Invoke(ctx, SongService_GetVerse_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *songServiceClient) AddSong(ctx context.Context, in *AddSongRequest, opts ...grpc.CallOption) (*Song, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Song)
+	err := c.cc.Invoke(ctx, SongService_AddSong_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *songServiceClient) UpdateSong(ctx context.Context, in *UpdateSongRequest, opts ...grpc.CallOption) (*Song, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Song)
+	err := c.cc.Invoke(ctx, SongService_UpdateSong_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *songServiceClient) DeleteSong(ctx context.Context, in *DeleteSongRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, SongService_DeleteSong_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SongServiceServer is the server API for SongService service.
+// All implementations must embed UnimplementedSongServiceServer
+// for forward compatibility.
+//
+// SongService is the gRPC counterpart of the songs HTTP API. Calls are
+// authenticated with an API key or access token in the "authorization"
+// ("Bearer <credential>") or "x-api-key" metadata and need the same roles as
+// their HTTP routes: viewer to read, editor to add and update, admin to delete.
+type SongServiceServer interface {
+	// ListSongs returns a page of songs matching the filter.
+	ListSongs(context.Context, *ListSongsRequest) (*ListSongsResponse, error)
+	// GetSong returns a song by ID.
+	GetSong(context.Context, *GetSongRequest) (*Song, error)
+	// GetVerse returns a verse of a song given by ID or by title and group.
+	GetVerse(context.Context, *GetVerseRequest) (*GetVerseResponse, error)
+	// AddSong adds a song, filling its details from the song info service.
+	AddSong(context.Context, *AddSongRequest) (*Song, error)
+	// UpdateSong changes the given fields of a song.
+	UpdateSong(context.Context, *UpdateSongRequest) (*Song, error)
+	// DeleteSong deletes a song.
+	DeleteSong(context.Context, *DeleteSongRequest) (*emptypb.Empty, error)
+	mustEmbedUnimplementedSongServiceServer()
+}
+
+// UnimplementedSongServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSongServiceServer struct{}
+
+func (UnimplementedSongServiceServer) ListSongs(context.Context, *ListSongsRequest) (*ListSongsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSongs not implemented")
+}
+func (UnimplementedSongServiceServer) GetSong(context.Context, *GetSongRequest) (*Song, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSong not implemented")
+}
+func (UnimplementedSongServiceServer) GetVerse(context.Context, *GetVerseRequest) (*GetVerseResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVerse not implemented")
+}
+func (UnimplementedSongServiceServer) AddSong(context.Context, *AddSongRequest) (*Song, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddSong not implemented")
+}
+func (UnimplementedSongServiceServer) UpdateSong(context.Context, *UpdateSongRequest) (*Song, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateSong not implemented")
+}
+func (UnimplementedSongServiceServer) DeleteSong(context.Context, *DeleteSongRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSong not implemented")
+}
+func (UnimplementedSongServiceServer) mustEmbedUnimplementedSongServiceServer() {}
+func (UnimplementedSongServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeSongServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SongServiceServer will
+// result in compilation errors.
+type UnsafeSongServiceServer interface {
+	mustEmbedUnimplementedSongServiceServer()
+}
+
+func RegisterSongServiceServer(s grpc.ServiceRegistrar, srv SongServiceServer) {
+	// If the following call pancis, it indicates UnimplementedSongServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&SongService_ServiceDesc, srv)
+}
+
+func _SongService_ListSongs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSongsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SongServiceServer).ListSongs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SongService_ListSongs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SongServiceServer).ListSongs(ctx, req.(*ListSongsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SongService_GetSong_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSongRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SongServiceServer).GetSong(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SongService_GetSong_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SongServiceServer).GetSong(ctx, req.(*GetSongRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SongService_GetVerse_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetVerseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SongServiceServer).GetVerse(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SongService_GetVerse_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SongServiceServer).GetVerse(ctx, req.(*GetVerseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SongService_AddSong_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddSongRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SongServiceServer).AddSong(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SongService_AddSong_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SongServiceServer).AddSong(ctx, req.(*AddSongRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SongService_UpdateSong_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateSongRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SongServiceServer).UpdateSong(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SongService_UpdateSong_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SongServiceServer).UpdateSong(ctx, req.(*UpdateSongRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SongService_DeleteSong_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSongRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SongServiceServer).DeleteSong(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SongService_DeleteSong_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SongServiceServer).DeleteSong(ctx, req.(*DeleteSongRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// SongService_ServiceDesc is the grpc.ServiceDesc for SongService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SongService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "songs.v1.SongService",
+	HandlerType: (*SongServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListSongs",
+			Handler:    _SongService_ListSongs_Handler,
+		},
+		{
+			MethodName: "GetSong",
+			Handler:    _SongService_GetSong_Handler,
+		},
+		{
+			MethodName: "GetVerse",
+			Handler:    _SongService_GetVerse_Handler,
+		},
+		{
+			MethodName: "AddSong",
+			Handler:    _SongService_AddSong_Handler,
+		},
+		{
+			MethodName: "UpdateSong",
+			Handler:    _SongService_UpdateSong_Handler,
+		},
+		{
+			MethodName: "DeleteSong",
+			Handler:    _SongService_DeleteSong_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "songs/v1/songs.proto",
+}
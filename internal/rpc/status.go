@@ -0,0 +1,78 @@
+package rpc
+
+import (
+	"errors"
+	"log/slog"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/i18n"
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"golang.org/x/text/message"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorCodes maps the domain error kinds to gRPC status codes.
+var errorCodes = []struct {
+	kind error
+	code codes.Code
+}{
+	{model.ErrNotFound, codes.NotFound},
+	{model.ErrConflict, codes.AlreadyExists},
+	{model.ErrValidation, codes.InvalidArgument},
+	{model.ErrUpstreamUnavailable, codes.Unavailable},
+	{model.ErrPreconditionFailed, codes.FailedPrecondition},
+	{model.ErrUnauthorized, codes.Unauthenticated},
+	{model.ErrForbidden, codes.PermissionDenied},
+}
+
+// songFieldNames renames the song fields of model.Song to those of the
+// protobuf messages in field violations.
+var songFieldNames = map[string]string{
+	"song_name":   "title",
+	"group_name":  "group",
+	"releaseDate": "release_date",
+	"text":        "lyrics",
+}
+
+// statusFromErr turns err into a gRPC status with its message in the language
+// of p. Field errors are attached as a BadRequest detail; errors that are not
+// domain errors are logged and reported as internal without their message.
+func statusFromErr(p *message.Printer, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	code := codes.Internal
+	for _, e := range errorCodes {
+		if errors.Is(err, e.kind) {
+			code = e.code
+			break
+		}
+	}
+	if code == codes.Internal {
+		slog.Error("Внутренняя ошибка при обработке вызова", "error", err)
+		return status.Error(code, i18n.Sprintf(p, "Не удалось обработать запрос"))
+	}
+
+	st := status.New(code, i18n.Error(p, err))
+	var merr *model.Error
+	if !errors.As(err, &merr) || len(merr.Fields) == 0 {
+		return st.Err()
+	}
+	violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(merr.Fields))
+	for _, f := range merr.Fields {
+		field := f.Field
+		if name, ok := songFieldNames[field]; ok {
+			field = name
+		}
+		description := f.Message
+		if f.Format != "" {
+			description = i18n.Sprintf(p, f.Format, f.Args...)
+		}
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: field, Description: description})
+	}
+	if detailed, derr := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); derr == nil {
+		st = detailed
+	}
+	return st.Err()
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/Xapsiel/EffectiveMobile/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var loginPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{3,64}$`)
+
+const (
+	minPasswordSize = 8
+	// maxPasswordSize is the longest password bcrypt takes into account.
+	maxPasswordSize = 72
+)
+
+// dummyPasswordHash is compared against when the login is unknown, so that
+// the response time does not tell which logins exist.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+type authService struct {
+	users  repository.User
+	keys   *apiKeyService
+	tokens *Tokens
+}
+
+func NewAuthService(users repository.User, keys *apiKeyService, tokens *Tokens) *authService {
+	return &authService{users: users, keys: keys, tokens: tokens}
+}
+
+// Register creates a viewer account; admins grant stronger roles.
+func (s *authService) Register(login, password string) (model.User, error) {
+	if !loginPattern.MatchString(login) {
+		return model.User{}, model.NewError(model.ErrValidation, "Логин должен состоять из 3–64 латинских букв, цифр, точек, дефисов или подчёркиваний").WithField("login")
+	}
+	if len(password) < minPasswordSize || len(password) > maxPasswordSize {
+		return model.User{}, model.NewError(model.ErrValidation, "Пароль должен быть длиной от %d до %d байт", minPasswordSize, maxPasswordSize).WithField("password")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return model.User{}, err
+	}
+	return s.users.CreateUser(login, string(hash), model.RoleViewer)
+}
+
+func (s *authService) Login(login, password string) (model.TokenPair, error) {
+	user, hash, err := s.users.GetUserByLogin(login)
+	if errors.Is(err, model.ErrNotFound) {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return model.TokenPair{}, model.NewError(model.ErrUnauthorized, "Неверный логин или пароль")
+	}
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return model.TokenPair{}, model.NewError(model.ErrUnauthorized, "Неверный логин или пароль")
+	}
+
+	refresh, refreshHash, expires, err := s.tokens.newRefreshToken()
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+	if err := s.users.CreateRefreshToken(user.ID, refreshHash, expires); err != nil {
+		return model.TokenPair{}, err
+	}
+	return s.tokenPair(user, refresh)
+}
+
+// Refresh exchanges a refresh token for a new access and refresh token pair.
+// The old refresh token stops working.
+func (s *authService) Refresh(refresh string) (model.TokenPair, error) {
+	next, nextHash, expires, err := s.tokens.newRefreshToken()
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+	user, err := s.users.RotateRefreshToken(hashSecret(refresh), nextHash, expires)
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+	return s.tokenPair(user, next)
+}
+
+func (s *authService) Logout(refresh string) error {
+	return s.users.RevokeRefreshToken(hashSecret(refresh))
+}
+
+func (s *authService) tokenPair(user model.User, refresh string) (model.TokenPair, error) {
+	access, err := s.tokens.Issue(user)
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+	return model.TokenPair{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.tokens.accessTTL.Seconds()),
+		RefreshToken: refresh,
+	}, nil
+}
+
+// Authenticate identifies the caller by an API key or a user access token.
+func (s *authService) Authenticate(credential string) (model.Principal, error) {
+	if strings.HasPrefix(credential, apiKeyPrefix) {
+		key, err := s.keys.Authenticate(credential)
+		if err != nil {
+			return model.Principal{}, err
+		}
+		return model.Principal{Kind: model.PrincipalAPIKey, ID: key.ID, Name: key.Name, Role: key.Role()}, nil
+	}
+	return s.tokens.Parse(credential)
+}
+
+func (s *authService) ListUsers() ([]model.User, error) {
+	return s.users.ListUsers()
+}
+
+func (s *authService) SetUserRole(id int, role string) (model.User, error) {
+	if !model.ValidRole(role) {
+		return model.User{}, model.NewError(model.ErrValidation, "Неизвестная роль %q, допустимы %s", role, strings.Join(model.Roles, ", ")).WithField("role")
+	}
+	return s.users.SetUserRole(id, role)
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/Xapsiel/EffectiveMobile/internal/repository"
+)
+
+// maxBatchOps caps the number of operations of a batch.
+const maxBatchOps = 1000
+
+// errBatchFailed rolls back an atomic batch with a failed operation.
+var errBatchFailed = errors.New("batch failed")
+
+// Batch runs ops in order, each the way the single song write does. Without
+// atomic every operation is committed on its own. With atomic they are
+// committed together or not at all: when any of them fails, the others are
+// undone and fail with ErrAborted. committed reports whether the changes were
+// kept; the error is only returned when the batch could not run at all.
+func (s *songService) Batch(ops []model.BatchOp, atomic bool) ([]model.BatchResult, bool, error) {
+	if len(ops) == 0 {
+		return nil, false, model.NewError(model.ErrValidation, "Пакет не содержит операций").WithField("operations")
+	}
+	if len(ops) > maxBatchOps {
+		return nil, false, model.NewError(model.ErrValidation, "В пакете может быть не больше %d операций", maxBatchOps).WithField("operations")
+	}
+
+	// The external API describes the new songs before any transaction is
+	// open, so that none waits for it.
+	ops = slices.Clone(ops)
+	for i, op := range ops {
+		if op.Err == nil && op.Op == model.BatchAdd {
+			ops[i].Song, ops[i].Err = s.songInfo(valueOrEmpty(op.Song.SongName), valueOrEmpty(op.Song.Group))
+		}
+	}
+
+	results := make([]model.BatchResult, len(ops))
+	if !atomic {
+		for i, op := range ops {
+			results[i] = s.batchOp(op)
+		}
+		return results, true, nil
+	}
+
+	failed := false
+	for i, op := range ops {
+		if op.Err != nil {
+			results[i].Err, failed = op.Err, true
+		}
+	}
+	if failed {
+		return abortBatch(results), false, nil
+	}
+	// Every operation runs even after one failed, so that the client learns
+	// about all the failures at once. Each runs in a savepoint of its own: a
+	// failed statement would otherwise abort the transaction and fail every
+	// later operation with it.
+	err := s.repo.Atomically(func(repo repository.Song) error {
+		for i, op := range ops {
+			err := repo.Atomically(func(repo repository.Song) error {
+				tx := &songService{api: s.api, repo: repo, lyrics: s.lyrics}
+				results[i] = tx.batchOp(op)
+				return results[i].Err
+			})
+			if err != nil && results[i].Err == nil {
+				results[i].Err = err
+			}
+			failed = failed || results[i].Err != nil
+		}
+		if failed {
+			return errBatchFailed
+		}
+		return nil
+	})
+	if failed {
+		return abortBatch(results), false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return results, true, nil
+}
+
+// batchOp runs an operation. The song of an add is the one songInfo returned.
+func (s *songService) batchOp(op model.BatchOp) model.BatchResult {
+	if op.Err != nil {
+		return model.BatchResult{Err: op.Err}
+	}
+	switch op.Op {
+	case model.BatchAdd:
+		id, err := s.repo.Add(op.Song)
+		if err != nil {
+			return model.BatchResult{Err: err}
+		}
+		song, err := s.repo.GetSong(model.Song{ID: &id})
+		return model.BatchResult{Song: song, Err: err}
+	case model.BatchUpdate:
+		song := op.Song
+		song.Version = op.Version
+		updated, err := s.UpdateSongByID(op.ID, song)
+		return model.BatchResult{Song: updated, Err: err}
+	case model.BatchDelete:
+		return model.BatchResult{Err: s.DeleteSongByID(op.ID, op.Version)}
+	default:
+		return model.BatchResult{Err: model.NewError(model.ErrValidation, "Неизвестная операция %q", op.Op).WithField("op")}
+	}
+}
+
+// abortBatch fails the operations of a rolled back batch that did not fail
+// themselves.
+func abortBatch(results []model.BatchResult) []model.BatchResult {
+	for i := range results {
+		if results[i].Err == nil {
+			results[i] = model.BatchResult{Err: model.NewError(model.ErrAborted, "Операция отменена: другие операции пакета завершились ошибкой")}
+		}
+	}
+	return results
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/Xapsiel/EffectiveMobile/internal/repository"
+)
+
+// listenRetryDelay is how long the broker waits before listening again after
+// the listening connection broke.
+const listenRetryDelay = 5 * time.Second
+
+type eventService struct {
+	repo repository.Event
+
+	once sync.Once
+	mu   sync.Mutex
+	subs map[chan struct{}]struct{}
+}
+
+func NewEventService(repo repository.Event) *eventService {
+	return &eventService{repo: repo, subs: make(map[chan struct{}]struct{})}
+}
+
+func (s *eventService) SongEvents(after int64, filter model.SongEventFilter, limit int) ([]model.SongEvent, error) {
+	return s.repo.GetSongEvents(after, filter, limit)
+}
+
+func (s *eventService) LastSongEventID() (int64, error) {
+	return s.repo.LastSongEventID()
+}
+
+// SubscribeSongEvents returns a channel that receives a value after new song
+// events were recorded, and the function to stop receiving. Wake-ups carry no
+// events and may be coalesced: subscribers read the events themselves.
+func (s *eventService) SubscribeSongEvents() (<-chan struct{}, func()) {
+	s.once.Do(func() { go s.listen() })
+
+	ch := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.subs[ch] = struct{}{}
+	s.mu.Unlock()
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.subs, ch)
+		s.mu.Unlock()
+	}
+}
+
+// listen relays the notifications of the repository to the subscribers for
+// the rest of the process.
+func (s *eventService) listen() {
+	for {
+		err := s.repo.ListenSongEvents(context.Background(), s.notify)
+		slog.Error("Прослушивание событий песен прервано", "error", err)
+		time.Sleep(listenRetryDelay)
+	}
+}
+
+func (s *eventService) notify() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.subs {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/Xapsiel/EffectiveMobile/internal/repository"
+)
+
+type groupService struct {
+	repo repository.Group
+}
+
+func NewGroupService(repo repository.Group) *groupService {
+	return &groupService{repo: repo}
+}
+
+func (s *groupService) GetGroups(filter model.Group, page int, limit int) ([]model.Group, error) {
+	return s.repo.GetGroups(filter, page, limit)
+}
+
+func (s *groupService) GetGroup(id int) (model.Group, error) {
+	notFound := model.NewError(model.ErrNotFound, "Группа %d не найдена", id)
+	if id <= 0 {
+		return model.Group{}, notFound
+	}
+	groups, err := s.repo.GetGroups(model.Group{ID: &id}, 1, 1)
+	if err != nil {
+		return model.Group{}, err
+	}
+	if len(groups) == 0 {
+		return model.Group{}, notFound
+	}
+	return groups[0], nil
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+)
+
+// importChunk is the number of rows validated and stored together, and caps
+// the number of songs stored by one AddBatch call.
+const importChunk = 1000
+
+// importKey identifies a song within an import file.
+type importKey struct{ group, name string }
+
+// Import validates, optionally enriches and stores the rows of an import file
+// as next decodes them, importChunk rows at a time, reporting the outcome of
+// every row. Existing songs are skipped or, with opts.Update, overwritten;
+// with opts.DryRun nothing is written. Rows are read until next returns
+// io.EOF; when it fails, the rows read before are still imported and their
+// results returned along with the error.
+func (s *songService) Import(next func() (model.ImportRow, error), opts model.ImportOptions) ([]model.ImportResult, error) {
+	var results []model.ImportResult
+	seen := make(map[importKey]int)
+	chunk := make([]model.ImportRow, 0, importChunk)
+	for {
+		row, readErr := next()
+		if readErr == nil {
+			chunk = append(chunk, row)
+			if len(chunk) < importChunk {
+				continue
+			}
+		}
+		if len(chunk) > 0 {
+			res, err := s.importRows(chunk, seen, opts)
+			results = append(results, res...)
+			if err != nil {
+				return results, err
+			}
+			chunk = chunk[:0]
+		}
+		switch {
+		case errors.Is(readErr, io.EOF):
+			return results, nil
+		case readErr != nil:
+			return results, readErr
+		}
+	}
+}
+
+// importRows imports a chunk of rows. seen holds the line of every song met in
+// the file so far, to skip its repetitions.
+func (s *songService) importRows(rows []model.ImportRow, seen map[importKey]int, opts model.ImportOptions) ([]model.ImportResult, error) {
+	results := make([]model.ImportResult, len(rows))
+	pending := make([]int, 0, len(rows))
+	for i := range rows {
+		row := &rows[i]
+		res := &results[i]
+		res.Line = row.Line
+		res.Group, res.SongName = valueOrEmpty(row.Song.Group), valueOrEmpty(row.Song.SongName)
+		if err := s.prepareImportRow(row, opts); err != nil {
+			res.Fail(model.ImportFailed, err)
+			continue
+		}
+		pending = append(pending, i)
+	}
+	if len(pending) == 0 {
+		return results, nil
+	}
+
+	keys := make([]model.Song, len(pending))
+	for k, i := range pending {
+		keys[k] = rows[i].Song
+	}
+	ids, err := s.repo.FindSongIDs(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	var toAdd []int
+	for k, i := range pending {
+		song, res := rows[i].Song, &results[i]
+		key := importKey{*song.Group, *song.SongName}
+		if line, dup := seen[key]; dup {
+			res.Fail(model.ImportSkipped, model.NewError(model.ErrConflict, "Песня уже встречалась в строке %d", line))
+			continue
+		}
+		seen[key] = rows[i].Line
+
+		switch {
+		case ids[k] != 0 && !opts.Update:
+			res.ID = ids[k]
+			res.Fail(model.ImportSkipped, model.NewError(model.ErrConflict, "Песня уже существует"))
+		case ids[k] != 0 && opts.DryRun:
+			res.Status, res.ID = model.ImportUpdated, ids[k]
+		case ids[k] != 0:
+			if _, _, err := s.repo.UpdateSongByID(ids[k], song); err != nil {
+				res.Fail(model.ImportFailed, err)
+				continue
+			}
+			res.Status, res.ID = model.ImportUpdated, ids[k]
+		case opts.DryRun:
+			res.Status = model.ImportCreated
+		default:
+			toAdd = append(toAdd, i)
+		}
+	}
+
+	if len(toAdd) == 0 {
+		return results, nil
+	}
+	songs := make([]model.Song, len(toAdd))
+	for k, i := range toAdd {
+		songs[k] = rows[i].Song
+	}
+	stored, err := s.repo.AddBatch(songs)
+	if err != nil {
+		return nil, err
+	}
+	for k, i := range toAdd {
+		res := &results[i]
+		switch err := stored[k].Err; {
+		case err == nil:
+			res.Status, res.ID = model.ImportCreated, stored[k].ID
+		case errors.Is(err, model.ErrConflict):
+			res.Fail(model.ImportSkipped, err)
+		default:
+			res.Fail(model.ImportFailed, err)
+		}
+	}
+	return results, nil
+}
+
+// prepareImportRow checks the row, fills missing fields from the external API
+// when asked to and splits the text into verses.
+func (s *songService) prepareImportRow(row *model.ImportRow, opts model.ImportOptions) error {
+	if row.Err != nil {
+		return row.Err
+	}
+	song := &row.Song
+	if valueOrEmpty(song.Group) == "" || valueOrEmpty(song.SongName) == "" {
+		return model.NewError(model.ErrValidation, "Не указано название песни или группа")
+	}
+	if opts.Enrich && (song.ReleaseDate == nil || song.Text == nil || song.Link == nil) {
+		info, err := s.api.GetInfo(*song.Group, *song.SongName)
+		if err != nil {
+			return model.NewError(model.ErrUpstreamUnavailable, "Не удалось дополнить данные песни: %v", err)
+		}
+		if song.ReleaseDate == nil {
+			song.ReleaseDate = info.ReleaseDate
+		}
+		if song.Text == nil {
+			song.Text = info.Text
+		}
+		if song.Link == nil {
+			song.Link = info.Link
+		}
+	}
+	if song.ReleaseDate == nil {
+		return model.NewError(model.ErrValidation, "Не указана дата выпуска")
+	}
+	if _, err := time.Parse("02.01.2006", *song.ReleaseDate); err != nil {
+		return model.NewError(model.ErrValidation, "Некорректная дата выпуска %q, ожидается формат ДД.ММ.ГГГГ", *song.ReleaseDate)
+	}
+	return s.lyrics.Prepare(song, valueOrEmpty(song.SectionParser))
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/config"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalizer brings lyrics to the canonical form the verse split relies on:
+// "\n" line endings, NFC, no trailing spaces and exactly one blank line
+// between verses.
+type Normalizer struct {
+	steps []func(string) string
+}
+
+func NewNormalizer(cfg config.LyricsConfig) *Normalizer {
+	steps := []func(string) string{
+		normalizeLineEndings,
+		norm.NFC.String,
+		normalizeSpaces,
+	}
+	if cfg.StraightenQuotes {
+		steps = append(steps, straightenQuotes)
+	}
+	steps = append(steps, trimLines, collapseBlankLines)
+	return &Normalizer{steps: steps}
+}
+
+func (n *Normalizer) Normalize(text string) string {
+	for _, step := range n.steps {
+		text = step(text)
+	}
+	return text
+}
+
+func normalizeLineEndings(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	return strings.NewReplacer("\u2028", "\n", "\u2029", "\n\n", "\u0085", "\n").Replace(text)
+}
+
+var spaceReplacer = strings.NewReplacer(
+	"\t", " ",
+	"\u00a0", " ",
+	"\u202f", " ",
+	"\u2007", " ",
+	"\u200b", "",
+	"\ufeff", "",
+)
+
+func normalizeSpaces(text string) string {
+	return spaceReplacer.Replace(text)
+}
+
+var quoteReplacer = strings.NewReplacer(
+	"\u2018", "'", "\u2019", "'", "\u201a", "'", "\u201b", "'",
+	"\u201c", `"`, "\u201d", `"`, "\u201e", `"`, "\u201f", `"`,
+	"\u00ab", `"`, "\u00bb", `"`,
+)
+
+func straightenQuotes(text string) string {
+	return quoteReplacer.Replace(text)
+}
+
+func trimLines(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+var blankRun = regexp.MustCompile(`\n{3,}`)
+
+func collapseBlankLines(text string) string {
+	return blankRun.ReplaceAllString(text, "\n\n")
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/Xapsiel/EffectiveMobile/internal/outbox"
+	"github.com/Xapsiel/EffectiveMobile/internal/repository"
+)
+
+const (
+	// outboxBatch is the number of events, and of retries, the relay takes at
+	// once.
+	outboxBatch = 100
+	// outboxPollInterval is how often the relay looks for events when no
+	// notification wakes it up, and so how soon due retries are made.
+	outboxPollInterval = 5 * time.Second
+	// outboxLease is how long a relay keeps a sink from other relays. The relay
+	// stops publishing a batch halfway through the lease and leaves the rest
+	// of it for the next round.
+	outboxLease = 2 * time.Minute
+	// outboxRetryBase and outboxRetryMax bound the wait before a failed event
+	// is tried again, doubling with every failed attempt.
+	outboxRetryBase = 5 * time.Second
+	outboxRetryMax  = 10 * time.Minute
+)
+
+type outboxService struct {
+	repo   repository.Outbox
+	events Event
+}
+
+func NewOutboxService(repo repository.Outbox, events Event) *outboxService {
+	return &outboxService{repo: repo, events: events}
+}
+
+// RelayOutbox publishes the song events to each of sinks until ctx is done.
+// Every sink follows the feed on its own. Events of a song are published in
+// order: after an event fails, the later events of its song wait behind it
+// until it is published, while the events of the other songs go on.
+func (s *outboxService) RelayOutbox(ctx context.Context, sinks []outbox.Sink) {
+	var wg sync.WaitGroup
+	for _, sink := range sinks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.relayTo(ctx, sink)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *outboxService) relayTo(ctx context.Context, sink outbox.Sink) {
+	wake, unsubscribe := s.events.SubscribeSongEvents()
+	defer unsubscribe()
+	poll := time.NewTicker(outboxPollInterval)
+	defer poll.Stop()
+
+	slog.Info("Запущена публикация событий", "sink", sink.Name())
+	for {
+		more, err := s.relay(ctx, sink)
+		if err != nil {
+			slog.Error("Ошибка при публикации событий", "sink", sink.Name(), "error", err)
+		}
+		if more && err == nil && ctx.Err() == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			slog.Info("Публикация событий остановлена", "sink", sink.Name())
+			return
+		case <-wake:
+		case <-poll.C:
+		}
+	}
+}
+
+// relay leases the sink, publishes the due retries and the next events of
+// the feed with no transaction open, then records the outcomes. It reports
+// whether more may be waiting: a batch was full, or published retries let
+// the events behind them go.
+func (s *outboxService) relay(ctx context.Context, sink outbox.Sink) (bool, error) {
+	cursor, ok, err := s.repo.ClaimOutboxCursor(sink.Name(), outboxLease)
+	if err != nil || !ok {
+		return false, err
+	}
+	retries, err := s.repo.GetOutboxRetries(cursor.Sink, outboxBatch)
+	if err != nil {
+		return false, s.release(cursor, err)
+	}
+	events, err := s.events.SongEvents(cursor.EventID, model.SongEventFilter{}, outboxBatch)
+	if err != nil {
+		return false, s.release(cursor, err)
+	}
+	songIDs := make([]int, len(events))
+	for i, e := range events {
+		songIDs[i] = e.SongID
+	}
+	// Songs with retries stay so until the outcomes are recorded: their new
+	// events join the retries, behind the ones published now.
+	waiting, err := s.repo.GetOutboxRetrySongs(cursor.Sink, songIDs)
+	if err != nil {
+		return false, s.release(cursor, err)
+	}
+
+	publishCtx, cancel := context.WithTimeout(ctx, outboxLease/2)
+	defer cancel()
+	var published []int64
+	var failed []model.OutboxRetry
+	blocked := make(map[int]bool)
+	for _, retry := range retries {
+		if blocked[retry.Event.SongID] || publishCtx.Err() != nil {
+			continue
+		}
+		err := sink.Publish(publishCtx, retry.Event)
+		switch {
+		case err != nil && publishCtx.Err() != nil:
+			// Cut off by shutdown or the end of the lease, not by the sink.
+			blocked[retry.Event.SongID] = true
+		case err != nil:
+			blocked[retry.Event.SongID] = true
+			failed = append(failed, s.retryLater(sink, retry, err))
+		default:
+			published = append(published, retry.Event.ID)
+		}
+	}
+
+	now := time.Now()
+	for _, e := range events {
+		if publishCtx.Err() != nil {
+			break
+		}
+		if waiting[e.SongID] {
+			failed = append(failed, model.OutboxRetry{Event: e, NextAttemptAt: now})
+			cursor.EventID = e.ID
+			continue
+		}
+		err := sink.Publish(publishCtx, e)
+		if err != nil && publishCtx.Err() != nil {
+			break
+		}
+		if err != nil {
+			waiting[e.SongID] = true
+			failed = append(failed, s.retryLater(sink, model.OutboxRetry{Event: e}, err))
+		}
+		cursor.EventID = e.ID
+	}
+
+	if err := s.repo.SaveOutbox(cursor, published, failed); err != nil {
+		return false, err
+	}
+	slog.Debug("Опубликованы события", "sink", cursor.Sink, "event_id", cursor.EventID, "retried", len(published), "failed", len(failed))
+	return len(events) == outboxBatch || len(retries) == outboxBatch || len(published) > 0, nil
+}
+
+// retryLater records a failed attempt to publish retry.Event and schedules the
+// next one.
+func (s *outboxService) retryLater(sink outbox.Sink, retry model.OutboxRetry, err error) model.OutboxRetry {
+	msg := err.Error()
+	retry.Attempts++
+	retry.LastError = &msg
+	retry.NextAttemptAt = time.Now().Add(outboxBackoff(retry.Attempts))
+	slog.Warn("Не удалось опубликовать событие", "sink", sink.Name(), "id", retry.Event.ID, "song_id", retry.Event.SongID,
+		"attempts", retry.Attempts, "next_attempt_at", retry.NextAttemptAt, "error", err)
+	return retry
+}
+
+// release gives the lease of cursor back without moving it, and returns err.
+func (s *outboxService) release(cursor model.OutboxCursor, err error) error {
+	if saveErr := s.repo.SaveOutbox(cursor, nil, nil); saveErr != nil {
+		slog.Error("Не удалось вернуть аренду приёмника", "sink", cursor.Sink, "error", saveErr)
+	}
+	return err
+}
+
+// outboxBackoff is the wait after the given number of failed attempts.
+func outboxBackoff(attempts int) time.Duration {
+	delay := outboxRetryBase
+	for i := 1; i < attempts && delay < outboxRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, outboxRetryMax)
+}
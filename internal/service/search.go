@@ -0,0 +1,169 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"golang.org/x/text/unicode/norm"
+)
+
+// FindPhrase looks the phrase up line by line in the verses of the song given
+// by key (ID, or name and group).
+func (s *verseService) FindPhrase(key model.Song, query model.PhraseQuery) ([]model.PhraseMatch, model.Song, error) {
+	query.Phrase = strings.TrimSpace(s.lyrics.Normalize(query.Phrase))
+	if query.Phrase == "" {
+		return nil, model.Song{}, model.NewError(model.ErrValidation, "Фраза для поиска пуста")
+	}
+	if strings.Contains(query.Phrase, "\n") {
+		return nil, model.Song{}, model.NewError(model.ErrValidation, "Фраза для поиска должна умещаться в одну строку")
+	}
+	pattern := foldRunes(query.Phrase, query.Fold)
+	if query.Fuzzy < 0 || query.Fuzzy >= len(pattern.runes) {
+		return nil, model.Song{}, model.NewError(model.ErrValidation, "Число опечаток должно быть от 0 до %d", len(pattern.runes)-1)
+	}
+
+	song, err := s.songs.GetSong(key)
+	if err != nil {
+		return nil, model.Song{}, err
+	}
+	verses, found, err := s.repo.GetVerses(*song.ID)
+	if err != nil {
+		return nil, model.Song{}, err
+	}
+
+	matches := make([]model.PhraseMatch, 0)
+	for i, verse := range verses {
+		for j, line := range strings.Split(valueOrEmpty(verse.Text), "\n") {
+			text, original := foldRunes(line, query.Fold), []rune(line)
+			for _, m := range approximateMatches(text.runes, pattern.runes, query.Fuzzy) {
+				start, end := text.index[m.start], text.index[m.end-1]+1
+				matches = append(matches, model.PhraseMatch{
+					Verse:    i + 1,
+					Label:    valueOrEmpty(verse.Label),
+					Line:     j + 1,
+					Offset:   start,
+					Length:   end - start,
+					Text:     string(original[start:end]),
+					LineText: line,
+					Distance: m.distance,
+				})
+			}
+		}
+	}
+	return matches, found, nil
+}
+
+// foldedText is text prepared for matching together with, for every rune, the
+// index of the rune of the original text it came from.
+type foldedText struct {
+	runes []rune
+	index []int
+}
+
+// foldRunes splits text into runes. With fold set, letters are lowercased and
+// stripped of combining marks, so "Ё" matches "е" and "É" matches "e".
+func foldRunes(text string, fold bool) foldedText {
+	var out foldedText
+	i := 0
+	for _, r := range text {
+		if !fold {
+			out.runes = append(out.runes, r)
+			out.index = append(out.index, i)
+			i++
+			continue
+		}
+		for _, d := range norm.NFD.String(string(r)) {
+			if unicode.Is(unicode.Mn, d) {
+				continue
+			}
+			out.runes = append(out.runes, unicode.ToLower(d))
+			out.index = append(out.index, i)
+		}
+		i++
+	}
+	return out
+}
+
+type runeMatch struct {
+	start, end, distance int
+}
+
+// approximateMatches finds the substrings of text within maxDist edits of
+// pattern (Sellers' algorithm). Among overlapping candidates the closest one
+// wins; the result is ordered by position.
+func approximateMatches(text, pattern []rune, maxDist int) []runeMatch {
+	n, m := len(text), len(pattern)
+	if n == 0 || m == 0 {
+		return nil
+	}
+
+	// prev/cur hold the edit distance of pattern[:i] against a substring of
+	// text ending at j; prevStart/curStart hold where that substring starts.
+	prev, cur := make([]int, n+1), make([]int, n+1)
+	prevStart, curStart := make([]int, n+1), make([]int, n+1)
+	for j := range prev {
+		prevStart[j] = j
+	}
+	for i := 1; i <= m; i++ {
+		cur[0], curStart[0] = i, 0
+		for j := 1; j <= n; j++ {
+			cost := 1
+			if pattern[i-1] == text[j-1] {
+				cost = 0
+			}
+			best, start := prev[j-1]+cost, prevStart[j-1]
+			if prev[j]+1 < best {
+				best, start = prev[j]+1, prevStart[j]
+			}
+			if cur[j-1]+1 < best {
+				best, start = cur[j-1]+1, curStart[j-1]
+			}
+			cur[j], curStart[j] = best, start
+		}
+		prev, cur = cur, prev
+		prevStart, curStart = curStart, prevStart
+	}
+
+	var candidates []runeMatch
+	for j := 1; j <= n; j++ {
+		if prev[j] <= maxDist && prevStart[j] < j {
+			candidates = append(candidates, runeMatch{start: prevStart[j], end: j, distance: prev[j]})
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		if candidates[a].distance != candidates[b].distance {
+			return candidates[a].distance < candidates[b].distance
+		}
+		return abs(candidates[a].end-candidates[a].start-m) < abs(candidates[b].end-candidates[b].start-m)
+	})
+
+	taken := make([]bool, n)
+	var matches []runeMatch
+	for _, c := range candidates {
+		free := true
+		for k := c.start; k < c.end; k++ {
+			if taken[k] {
+				free = false
+				break
+			}
+		}
+		if !free {
+			continue
+		}
+		for k := c.start; k < c.end; k++ {
+			taken[k] = true
+		}
+		matches = append(matches, c)
+	}
+	sort.Slice(matches, func(a, b int) bool { return matches[a].start < matches[b].start })
+	return matches
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
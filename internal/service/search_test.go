@@ -0,0 +1,34 @@
+package service
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestApproximateMatches(t *testing.T) {
+	tests := []struct {
+		text, pattern string
+		maxDist       int
+		want          []runeMatch
+	}{
+		{text: "hello world", pattern: "world", maxDist: 0, want: []runeMatch{{6, 11, 0}}},
+		{text: "hello world", pattern: "word", maxDist: 0},
+		{text: "hello world", pattern: "word", maxDist: 1, want: []runeMatch{{6, 10, 1}}},
+		{text: "hello wrld", pattern: "world", maxDist: 1, want: []runeMatch{{6, 10, 1}}},
+		{text: "hello wurld", pattern: "world", maxDist: 1, want: []runeMatch{{6, 11, 1}}},
+		{text: "la la la", pattern: "la", maxDist: 0, want: []runeMatch{{0, 2, 0}, {3, 5, 0}, {6, 8, 0}}},
+		{text: "aaaa", pattern: "aa", maxDist: 0, want: []runeMatch{{0, 2, 0}, {2, 4, 0}}},
+		{text: "мама мыла раму", pattern: "рама", maxDist: 1, want: []runeMatch{{0, 4, 1}, {10, 14, 1}}},
+		{text: "abc", pattern: "xyz", maxDist: 1},
+		{text: "abc", pattern: "", maxDist: 1},
+		{text: "", pattern: "abc", maxDist: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text+"/"+tt.pattern, func(t *testing.T) {
+			got := approximateMatches([]rune(tt.text), []rune(tt.pattern), tt.maxDist)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("approximateMatches(%q, %q, %d) = %v, want %v", tt.text, tt.pattern, tt.maxDist, got, tt.want)
+			}
+		})
+	}
+}
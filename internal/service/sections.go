@@ -0,0 +1,173 @@
+package service
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/config"
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+)
+
+// Names of the built-in section parsers.
+const (
+	ParserPlain   = "plain"
+	ParserLabeled = "labeled"
+)
+
+// SectionParser splits normalized song text into sections. Joining the
+// sections back (header line, then text, blocks separated by a blank line)
+// must give the original text.
+type SectionParser interface {
+	Parse(text string) []model.Verse
+}
+
+var sectionParsers = map[string]SectionParser{
+	ParserPlain:   plainParser{},
+	ParserLabeled: labeledParser{},
+}
+
+// Lyrics prepares song text for storage: it normalizes it and splits it into
+// sections with the parser chosen for the song or the configured default.
+type Lyrics struct {
+	normalizer    *Normalizer
+	defaultParser string
+}
+
+func NewLyrics(cfg config.LyricsConfig) (*Lyrics, error) {
+	name := cfg.SectionParser
+	if name == "" {
+		name = ParserPlain
+	}
+	if _, ok := sectionParsers[name]; !ok {
+		return nil, model.NewError(model.ErrValidation, "Неизвестный парсер разделов %q", name)
+	}
+	return &Lyrics{normalizer: NewNormalizer(cfg), defaultParser: name}, nil
+}
+
+// Parser returns the parser registered under name, or the default one for an
+// empty name.
+func (l *Lyrics) Parser(name string) (SectionParser, string, error) {
+	if name == "" {
+		name = l.defaultParser
+	}
+	parser, ok := sectionParsers[name]
+	if !ok {
+		return nil, "", model.NewError(model.ErrValidation, "Неизвестный парсер разделов %q", name)
+	}
+	return parser, name, nil
+}
+
+// Prepare normalizes song.Text and fills song.Verses from it using the named
+// parser.
+func (l *Lyrics) Prepare(song *model.Song, parserName string) error {
+	if song.Text == nil {
+		return nil
+	}
+	parser, _, err := l.Parser(parserName)
+	if err != nil {
+		return err
+	}
+	text := l.normalizer.Normalize(*song.Text)
+	song.Text = &text
+	song.Verses = parser.Parse(text)
+	return nil
+}
+
+func (l *Lyrics) Normalize(text string) string {
+	return l.normalizer.Normalize(text)
+}
+
+// plainParser treats every blank-line separated block as an unlabelled verse.
+type plainParser struct{}
+
+func (plainParser) Parse(text string) []model.Verse {
+	return splitVerses(text)
+}
+
+// labeledParser recognises a header line at the top of a block: bracketed
+// labels ("[Chorus]", "[Куплет 2]"), numbered headers ("Verse 1:", "2.") and
+// repeat markers ("Chorus x2", "[Припев ×3]"). A block made of a header alone
+// refers back to a section sung again.
+type labeledParser struct{}
+
+var (
+	bracketHeader = regexp.MustCompile(`^\[(.+)\]$`)
+	namedHeader   = regexp.MustCompile(`(?i)^((?:pre-?chorus|chorus|refrain|verse|bridge|intro|outro|hook|припев|куплет|бридж|вступление|проигрыш|кода|концовка)(?:\s*\d+)?)\s*:?(?:\s*[(]?[x×х]\s*\d+[)]?)?\s*:?$`)
+	numberHeader  = regexp.MustCompile(`^(\d+)[.):]$`)
+	repeatMarker  = regexp.MustCompile(`(?i)\s*[(]?[x×х]\s*(\d+)[)]?\s*$`)
+)
+
+func (labeledParser) Parse(text string) []model.Verse {
+	verses := splitVerses(text)
+	for i := range verses {
+		block := *verses[i].Text
+		first, rest, _ := strings.Cut(block, "\n")
+		label, repeat, ok := parseHeader(strings.TrimSpace(first))
+		if !ok {
+			continue
+		}
+		typ := sectionType(label)
+		verses[i] = model.Verse{Header: &first, Label: &label, Repeat: &repeat, Type: &typ, Text: &rest}
+	}
+	return verses
+}
+
+// parseHeader reports whether line is a section header and extracts its label
+// and repeat count.
+func parseHeader(line string) (label string, repeat int, ok bool) {
+	repeat = 1
+	inner := line
+	if m := bracketHeader.FindStringSubmatch(line); m != nil {
+		inner = strings.TrimSpace(m[1])
+	} else if m := numberHeader.FindStringSubmatch(line); m != nil {
+		return m[1], 1, true
+	} else if !namedHeader.MatchString(line) {
+		return "", 0, false
+	}
+
+	inner = strings.TrimSuffix(strings.TrimSpace(inner), ":")
+	if m := repeatMarker.FindStringSubmatchIndex(inner); m != nil && m[0] > 0 {
+		if n, err := strconv.Atoi(inner[m[2]:m[3]]); err == nil && n > 0 {
+			repeat = n
+			inner = inner[:m[0]]
+		}
+	}
+	label = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(inner), ":"))
+	if label == "" {
+		return "", 0, false
+	}
+	return label, repeat, true
+}
+
+// sectionType maps a header label onto one of the stored verse types.
+func sectionType(label string) string {
+	lower := strings.ToLower(label)
+	switch {
+	case strings.Contains(lower, "pre-chorus"), strings.Contains(lower, "prechorus"):
+		return model.VerseTypeBridge
+	case strings.Contains(lower, "chorus"), strings.Contains(lower, "refrain"), strings.Contains(lower, "hook"), strings.Contains(lower, "припев"):
+		return model.VerseTypeChorus
+	case strings.Contains(lower, "bridge"), strings.Contains(lower, "бридж"):
+		return model.VerseTypeBridge
+	case strings.Contains(lower, "intro"), strings.Contains(lower, "вступление"):
+		return model.VerseTypeIntro
+	default:
+		return model.VerseTypeVerse
+	}
+}
+
+// sectionHeader builds the header line for a label set through the API.
+func sectionHeader(label string, repeat *int) string {
+	if repeat != nil && *repeat > 1 {
+		return "[" + label + " x" + strconv.Itoa(*repeat) + "]"
+	}
+	return "[" + label + "]"
+}
+
+func valueOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+)
+
+func TestParseHeader(t *testing.T) {
+	tests := []struct {
+		line       string
+		wantLabel  string
+		wantRepeat int
+		wantOK     bool
+	}{
+		{line: "[Chorus]", wantLabel: "Chorus", wantRepeat: 1, wantOK: true},
+		{line: "[Куплет 2]", wantLabel: "Куплет 2", wantRepeat: 1, wantOK: true},
+		{line: "[ Bridge: ]", wantLabel: "Bridge", wantRepeat: 1, wantOK: true},
+		{line: "[Припев ×3]", wantLabel: "Припев", wantRepeat: 3, wantOK: true},
+		{line: "[Chorus (x2)]", wantLabel: "Chorus", wantRepeat: 2, wantOK: true},
+		{line: "Verse 1:", wantLabel: "Verse 1", wantRepeat: 1, wantOK: true},
+		{line: "Chorus x2", wantLabel: "Chorus", wantRepeat: 2, wantOK: true},
+		{line: "pre-chorus", wantLabel: "pre-chorus", wantRepeat: 1, wantOK: true},
+		{line: "Припев х2:", wantLabel: "Припев", wantRepeat: 2, wantOK: true},
+		{line: "2.", wantLabel: "2", wantRepeat: 1, wantOK: true},
+		{line: "3)", wantLabel: "3", wantRepeat: 1, wantOK: true},
+		{line: "[x2]", wantLabel: "x2", wantRepeat: 1, wantOK: true},
+		{line: "[Chorus x0]", wantLabel: "Chorus x0", wantRepeat: 1, wantOK: true},
+		{line: "[]"},
+		{line: "[ ]"},
+		{line: "Chorus of angels"},
+		{line: "Verses"},
+		{line: "12"},
+		{line: "Ooh baby, do you know what that's worth?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			label, repeat, ok := parseHeader(tt.line)
+			if ok != tt.wantOK || label != tt.wantLabel || repeat != tt.wantRepeat {
+				t.Errorf("parseHeader(%q) = %q, %d, %v, want %q, %d, %v", tt.line, label, repeat, ok, tt.wantLabel, tt.wantRepeat, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestLabeledParser(t *testing.T) {
+	type section struct {
+		header, label, typ, text string
+		repeat                   int
+	}
+	tests := []struct {
+		name string
+		text string
+		want []section
+	}{
+		{name: "empty"},
+		{
+			name: "unlabelled",
+			text: "line one\nline two\n\nline three",
+			want: []section{{text: "line one\nline two"}, {text: "line three"}},
+		},
+		{
+			name: "labelled",
+			text: "[Intro]\nla la\n\nVerse 1:\nfirst\n\n[Chorus x2]\nsing\n\nBridge\nslow\n\n[Chorus]",
+			want: []section{
+				{header: "[Intro]", label: "Intro", typ: model.VerseTypeIntro, repeat: 1, text: "la la"},
+				{header: "Verse 1:", label: "Verse 1", typ: model.VerseTypeVerse, repeat: 1, text: "first"},
+				{header: "[Chorus x2]", label: "Chorus", typ: model.VerseTypeChorus, repeat: 2, text: "sing"},
+				{header: "Bridge", label: "Bridge", typ: model.VerseTypeBridge, repeat: 1, text: "slow"},
+				{header: "[Chorus]", label: "Chorus", typ: model.VerseTypeChorus, repeat: 1, text: ""},
+			},
+		},
+		{
+			name: "russian",
+			text: "[Куплет 1]\nпервый\n\n[Pre-Chorus]\nперед\n\nПрипев ×3:\nхором",
+			want: []section{
+				{header: "[Куплет 1]", label: "Куплет 1", typ: model.VerseTypeVerse, repeat: 1, text: "первый"},
+				{header: "[Pre-Chorus]", label: "Pre-Chorus", typ: model.VerseTypeBridge, repeat: 1, text: "перед"},
+				{header: "Припев ×3:", label: "Припев", typ: model.VerseTypeChorus, repeat: 3, text: "хором"},
+			},
+		},
+		{
+			name: "header only in the first line",
+			text: "words\n[Chorus]",
+			want: []section{{text: "words\n[Chorus]"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verses := labeledParser{}.Parse(tt.text)
+			if len(verses) != len(tt.want) {
+				t.Fatalf("got %d sections, want %d", len(verses), len(tt.want))
+			}
+			for i, v := range verses {
+				got := section{text: valueOrEmpty(v.Text), header: valueOrEmpty(v.Header), label: valueOrEmpty(v.Label), typ: valueOrEmpty(v.Type)}
+				if v.Repeat != nil {
+					got.repeat = *v.Repeat
+				}
+				if got != tt.want[i] {
+					t.Errorf("section %d = %+v, want %+v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -1,25 +1,127 @@
 package service
 
 import (
+	"context"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/api"
 	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/Xapsiel/EffectiveMobile/internal/outbox"
 	"github.com/Xapsiel/EffectiveMobile/internal/repository"
 )
 
 type Service struct {
 	Song
+	Verse
+	Group
+	Event
+	Webhook
+	Outbox
+	APIKey
+	Auth
+
+	repo   repository.Repository
+	client *api.Client
+	lyrics *Lyrics
 }
 
 type Song interface {
-	GetSongs(filter model.Song, page int, limit int) ([]model.Song, error)
-	GetSongVerse(song model.Song, verse int) (string, int, error)
+	GetSongs(filter model.Song, sort model.SongSort, page int, limit int) ([]model.Song, error)
+	GetGroupsSongs(groupIDs []int, sort model.SongSort, page int, limit int) (map[int][]model.Song, error)
+	ExportSongs(filter model.Song, withText bool, fn func(model.Song) error) error
+	Songbook(filter model.Song, ids []int) ([]model.SongbookGroup, error)
+	GetSongVerses(song model.Song) ([]model.Verse, model.Song, error)
+	GetSong(id int) (model.Song, error)
 	DeleteSong(song model.Song) (bool, error)
+	DeleteSongByID(id int, version *int) error
 	UpdateSong(song_name, group_name string, song model.Song) (bool, model.Song, error)
+	UpdateSongByID(id int, song model.Song) (model.Song, error)
+	ReplaceSong(id int, song model.Song) (model.Song, error)
+	PatchSong(id int, version *int, patch func(current model.Song) (model.Song, error)) (model.Song, error)
 	Add(song string, group string) (int, error)
+	AddBatch(songs []model.Song) ([]model.AddResult, error)
+	Batch(ops []model.BatchOp, atomic bool) ([]model.BatchResult, bool, error)
+	Import(next func() (model.ImportRow, error), opts model.ImportOptions) ([]model.ImportResult, error)
+	Renormalize(dryRun bool) ([]model.RenormalizeResult, int, error)
 }
 
-func NewService(repo repository.Repository) Service {
+type Verse interface {
+	GetVerses(songID int) ([]model.Verse, model.Song, error)
+	GetSongsVerses(songIDs []int) (map[int][]model.Verse, error)
+	GetSections(songID int, parser string) ([]model.Verse, string, error)
+	FindPhrase(key model.Song, query model.PhraseQuery) ([]model.PhraseMatch, model.Song, error)
+	InsertVerse(songID int, version *int, verse model.Verse) (model.Verse, model.Song, error)
+	UpdateVerse(songID int, version *int, verse model.Verse) (model.Verse, model.Song, error)
+	DeleteVerse(songID int, version *int, ordinal int) (model.Song, error)
+	ReorderVerses(songID int, version *int, order []int) ([]model.Verse, model.Song, error)
+}
+
+type Group interface {
+	GetGroups(filter model.Group, page int, limit int) ([]model.Group, error)
+	GetGroup(id int) (model.Group, error)
+}
+
+type Event interface {
+	SongEvents(after int64, filter model.SongEventFilter, limit int) ([]model.SongEvent, error)
+	LastSongEventID() (int64, error)
+	SubscribeSongEvents() (<-chan struct{}, func())
+}
+
+type Webhook interface {
+	CreateWebhook(hook model.Webhook, secret string) (model.Webhook, string, error)
+	ListWebhooks() ([]model.Webhook, error)
+	GetWebhook(id int) (model.Webhook, error)
+	UpdateWebhook(hook model.Webhook) (model.Webhook, error)
+	DeleteWebhook(id int) error
+	WebhookDeliveries(webhookID int, status string, page int, limit int) ([]model.WebhookDelivery, error)
+	RetryWebhookDelivery(webhookID int, id int64) (model.WebhookDelivery, error)
+	DeliverWebhooks(ctx context.Context)
+}
+
+type Outbox interface {
+	RelayOutbox(ctx context.Context, sinks []outbox.Sink)
+}
+
+type APIKey interface {
+	CreateAPIKey(name string, scopes []string) (model.APIKey, string, error)
+	ListAPIKeys() ([]model.APIKey, error)
+	RevokeAPIKey(id int) (model.APIKey, error)
+}
+
+type Auth interface {
+	Register(login, password string) (model.User, error)
+	Login(login, password string) (model.TokenPair, error)
+	Refresh(refresh string) (model.TokenPair, error)
+	Logout(refresh string) error
+	Authenticate(credential string) (model.Principal, error)
+	ListUsers() ([]model.User, error)
+	SetUserRole(id int, role string) (model.User, error)
+}
+
+func NewService(repo repository.Repository, client *api.Client, lyrics *Lyrics, tokens *Tokens, sender *WebhookSender) Service {
+	keys := NewAPIKeyService(repo)
+	events := NewEventService(repo)
 	return Service{
-		Song: NewSongService(repo),
+		Song:    NewSongService(repo, client, lyrics),
+		Verse:   NewVerseService(repo, repo, lyrics),
+		Group:   NewGroupService(repo),
+		Event:   events,
+		Webhook: NewWebhookService(repo, events, sender),
+		Outbox:  NewOutboxService(repo, events),
+		APIKey:  keys,
+		Auth:    NewAuthService(repo, keys, tokens),
+		repo:    repo,
+		client:  client,
+		lyrics:  lyrics,
 	}
+}
 
+// As returns the services recording actor as the author of the song changes
+// made through them. Only the song and verse services, which make such changes,
+// are bound to actor; the others are shared with s.
+func (s Service) As(actor string) Service {
+	repo := s.repo.As(actor)
+	s.Song = NewSongService(repo, s.client, s.lyrics)
+	s.Verse = NewVerseService(repo, repo, s.lyrics)
+	s.repo = repo
+	return s
 }
@@ -1,7 +1,8 @@
 package service
 
 import (
-	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/Xapsiel/EffectiveMobile/internal/api"
 	"github.com/Xapsiel/EffectiveMobile/internal/model"
@@ -9,54 +10,251 @@ import (
 )
 
 type songService struct {
-	api  api.Client
-	repo repository.Song
+	api    *api.Client
+	repo   repository.Song
+	lyrics *Lyrics
 }
 
-func NewSongService(repo repository.Song) *songService {
-	return &songService{repo: repo}
+func NewSongService(repo repository.Song, client *api.Client, lyrics *Lyrics) *songService {
+	return &songService{repo: repo, api: client, lyrics: lyrics}
 }
 
-func (s *songService) GetSongs(filter model.Song, page int, limit int) ([]model.Song, error) {
-	return s.repo.GetSongs(filter, page, limit)
+func (s *songService) GetSongs(filter model.Song, sort model.SongSort, page int, limit int) ([]model.Song, error) {
+	if err := validSort(sort); err != nil {
+		return nil, err
+	}
+	return s.repo.GetSongs(filter, sort, page, limit)
+
+}
+
+// GetGroupsSongs returns a page of the songs of each of groupIDs, keyed by
+// group ID, with a single repository query.
+func (s *songService) GetGroupsSongs(groupIDs []int, sort model.SongSort, page int, limit int) (map[int][]model.Song, error) {
+	if err := validSort(sort); err != nil {
+		return nil, err
+	}
+	if len(groupIDs) == 0 {
+		return map[int][]model.Song{}, nil
+	}
+	return s.repo.GetGroupsSongs(groupIDs, sort, page, limit)
+}
 
+func validSort(sort model.SongSort) error {
+	if sort.Field != "" && !slices.Contains(model.SongSortFields, sort.Field) {
+		return model.NewError(model.ErrValidation, "Неизвестное поле сортировки %q, допустимы %s", sort.Field, strings.Join(model.SongSortFields, ", ")).WithField("sort")
+	}
+	return nil
+}
+func (s *songService) ExportSongs(filter model.Song, withText bool, fn func(model.Song) error) error {
+	return s.repo.ExportSongs(filter, withText, fn)
 }
+
 func (s *songService) Add(song string, group string) (int, error) {
+	res, err := s.songInfo(song, group)
+	if err != nil {
+		return -1, err
+	}
+	return s.repo.Add(res)
+}
+
+// songInfo is the new song the external API describes, ready to be stored.
+func (s *songService) songInfo(song string, group string) (model.Song, error) {
 	if song == "" || group == "" {
-		return -1, fmt.Errorf("invalid params")
+		return model.Song{}, model.NewError(model.ErrValidation, "Не указано название песни или группа")
 	}
 	res, err := s.api.GetInfo(group, song)
 	if err != nil {
-		return -1, err
+		return model.Song{}, err
+	}
+	res.SongName = &song
+	res.Group = &group
+	if err := s.lyrics.Prepare(&res, ""); err != nil {
+		return model.Song{}, err
 	}
-	res.SongName = res.SongName
-	res.Group = res.Group
-	res.Link = res.Link
-	res.ReleaseDate = res.ReleaseDate
-	res.Text = res.Text
+	return res, nil
+}
 
-	//if err != nil {
-	//	return 0, err
-	//}
+func (s *songService) AddBatch(songs []model.Song) ([]model.AddResult, error) {
+	if len(songs) == 0 {
+		return nil, nil
+	}
+	results := make([]model.AddResult, len(songs))
+	prepared := make([]model.Song, 0, len(songs))
+	index := make([]int, 0, len(songs))
+	for i, song := range songs {
+		if err := s.lyrics.Prepare(&song, valueOrEmpty(song.SectionParser)); err != nil {
+			results[i].Err = err
+			continue
+		}
+		prepared = append(prepared, song)
+		index = append(index, i)
+	}
+	if len(prepared) == 0 {
+		return results, nil
+	}
 
-	return s.repo.Add(res)
+	stored, err := s.repo.AddBatch(prepared)
+	if err != nil {
+		return nil, err
+	}
+	for i, res := range stored {
+		results[index[i]] = res
+	}
+	return results, nil
 }
 
-func (s *songService) GetSongVerse(song model.Song, verse int) (string, int, error) {
+func (s *songService) GetSongVerses(song model.Song) ([]model.Verse, model.Song, error) {
 	if song.SongName == nil || song.Group == nil {
-		return "", -1, fmt.Errorf("song name or group is empty")
+		return nil, model.Song{}, model.NewError(model.ErrValidation, "Не указано название песни или группа")
 	}
-	return s.repo.GetSongVerse(song, verse)
+	return s.repo.GetSongVerses(song)
 }
 func (s *songService) DeleteSong(song model.Song) (bool, error) {
 	if song.SongName == nil || song.Group == nil {
-		return false, fmt.Errorf("song name or group is empty")
+		return false, model.NewError(model.ErrValidation, "Не указано название песни или группа")
 	}
 	return s.repo.DeleteSong(song)
 }
 func (s *songService) UpdateSong(song_name, group_name string, song model.Song) (bool, model.Song, error) {
-	if song_name == "" && group_name == "" {
-		return false, model.Song{}, nil
+	if song_name == "" || group_name == "" {
+		return false, model.Song{}, model.NewError(model.ErrValidation, "Не указано название песни или группа")
+	}
+	if song.Text != nil || song.SectionParser != nil {
+		parser, err := s.effectiveParser(model.Song{SongName: &song_name, Group: &group_name}, &song)
+		if err != nil {
+			return false, model.Song{}, err
+		}
+		if err := s.lyrics.Prepare(&song, parser); err != nil {
+			return false, model.Song{}, err
+		}
 	}
 	return s.repo.UpdateSong(song_name, group_name, song)
 }
+
+func (s *songService) GetSong(id int) (model.Song, error) {
+	return s.repo.GetSong(model.Song{ID: &id})
+}
+
+// UpdateSongByID applies the set fields of song and returns the whole song as
+// stored afterwards.
+func (s *songService) UpdateSongByID(id int, song model.Song) (model.Song, error) {
+	if song.Text != nil || song.SectionParser != nil {
+		parser, err := s.effectiveParser(model.Song{ID: &id}, &song)
+		if err != nil {
+			return model.Song{}, err
+		}
+		if err := s.lyrics.Prepare(&song, parser); err != nil {
+			return model.Song{}, err
+		}
+	}
+	if _, _, err := s.repo.UpdateSongByID(id, song); err != nil {
+		return model.Song{}, err
+	}
+	return s.repo.GetSong(model.Song{ID: &id})
+}
+
+// ReplaceSong is UpdateSongByID for a full representation of the song: every
+// field but the section parser must be given.
+func (s *songService) ReplaceSong(id int, song model.Song) (model.Song, error) {
+	if song.SongName == nil || song.Group == nil || song.ReleaseDate == nil || song.Text == nil || song.Link == nil {
+		return model.Song{}, model.NewError(model.ErrValidation, "Для замены песни нужны название, группа, дата выпуска, текст и ссылка")
+	}
+	if *song.SongName == "" || *song.Group == "" {
+		return model.Song{}, model.NewError(model.ErrValidation, "Не указано название песни или группа")
+	}
+	return s.UpdateSongByID(id, song)
+}
+
+// PatchSong derives the new song from the stored one with patch and saves it,
+// all inside one repository transaction. patch returns the complete song:
+// a missing text or link clears it, a missing section parser resets it to the
+// default.
+func (s *songService) PatchSong(id int, version *int, patch func(current model.Song) (model.Song, error)) (model.Song, error) {
+	return s.repo.PatchSong(id, version, func(current model.Song) (model.Song, error) {
+		next, err := patch(current)
+		if err != nil {
+			return model.Song{}, err
+		}
+		if valueOrEmpty(next.SongName) == "" || valueOrEmpty(next.Group) == "" || valueOrEmpty(next.ReleaseDate) == "" {
+			return model.Song{}, model.NewError(model.ErrValidation, "Название, группа и дата выпуска песни обязательны")
+		}
+		text, link := valueOrEmpty(next.Text), valueOrEmpty(next.Link)
+		next.Text, next.Link = &text, &link
+		if text != *current.Text || valueOrEmpty(next.SectionParser) != valueOrEmpty(current.SectionParser) {
+			if err := s.lyrics.Prepare(&next, valueOrEmpty(next.SectionParser)); err != nil {
+				return model.Song{}, err
+			}
+			if next.Verses == nil {
+				next.Verses = []model.Verse{}
+			}
+		}
+		return next, nil
+	})
+}
+
+func (s *songService) DeleteSongByID(id int, version *int) error {
+	_, err := s.repo.DeleteSongByID(id, version)
+	return err
+}
+
+// effectiveParser resolves the parser the updated song text must be split
+// with. Changing only the parser re-splits the stored text, so in that case the
+// stored text is copied into song.
+func (s *songService) effectiveParser(key model.Song, song *model.Song) (string, error) {
+	if song.SectionParser != nil {
+		if _, _, err := s.lyrics.Parser(*song.SectionParser); err != nil {
+			return "", err
+		}
+		if song.Text != nil {
+			return *song.SectionParser, nil
+		}
+	}
+	current, err := s.repo.GetSong(key)
+	if err != nil {
+		return "", err
+	}
+	if song.SectionParser != nil {
+		song.Text = current.Text
+		return *song.SectionParser, nil
+	}
+	return valueOrEmpty(current.SectionParser), nil
+}
+
+// Renormalize runs every stored song through the normalizer and rewrites the
+// ones whose text changed. With dryRun nothing is written. It returns the songs
+// whose verse count changed and the total number of changed songs.
+func (s *songService) Renormalize(dryRun bool) ([]model.RenormalizeResult, int, error) {
+	songs, err := s.repo.GetSongTexts()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var report []model.RenormalizeResult
+	changed := 0
+	for _, song := range songs {
+		before := *song.Text
+		if err := s.lyrics.Prepare(&song, valueOrEmpty(song.SectionParser)); err != nil {
+			return report, changed, err
+		}
+		if *song.Text == before {
+			continue
+		}
+		changed++
+		if !dryRun {
+			if _, err := s.repo.SetSongText(*song.ID, song.Verses); err != nil {
+				return report, changed, err
+			}
+		}
+		versesBefore, versesAfter := len(splitVerses(before)), len(song.Verses)
+		if versesBefore != versesAfter {
+			report = append(report, model.RenormalizeResult{
+				ID:           *song.ID,
+				Group:        *song.Group,
+				SongName:     *song.SongName,
+				VersesBefore: versesBefore,
+				VersesAfter:  versesAfter,
+			})
+		}
+	}
+	return report, changed, nil
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+)
+
+// maxSongbookSongs caps the number of songs one songbook can hold.
+const maxSongbookSongs = 500
+
+// Songbook collects the songs for a songbook grouped by group name. With ids
+// set exactly those songs are taken (still narrowed by filter), and every one
+// of them must exist.
+func (s *songService) Songbook(filter model.Song, ids []int) ([]model.SongbookGroup, error) {
+	songs, err := s.repo.GetSongbookSongs(filter, ids, maxSongbookSongs+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(songs) > maxSongbookSongs {
+		return nil, model.NewError(model.ErrValidation, "В сборник можно включить не больше %d песен, уточните выборку", maxSongbookSongs)
+	}
+	if ids != nil {
+		found := make(map[int]bool, len(songs))
+		for _, song := range songs {
+			found[*song.ID] = true
+		}
+		var missing []string
+		for _, id := range ids {
+			if !found[id] {
+				missing = append(missing, fmt.Sprint(id))
+			}
+		}
+		if len(missing) > 0 {
+			return nil, model.NewError(model.ErrNotFound, "Песни не найдены: %s", strings.Join(missing, ", "))
+		}
+	}
+	if len(songs) == 0 {
+		return nil, model.NewError(model.ErrNotFound, "Не найдено ни одной песни для сборника")
+	}
+
+	var groups []model.SongbookGroup
+	for _, song := range songs {
+		if n := len(groups); n == 0 || groups[n-1].Name != *song.Group {
+			groups = append(groups, model.SongbookGroup{Name: *song.Group})
+		}
+		last := &groups[len(groups)-1]
+		last.Songs = append(last.Songs, song)
+	}
+	return groups, nil
+}
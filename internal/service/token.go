@@ -0,0 +1,116 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/config"
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const tokenIssuer = "songs"
+
+// minSecretSize is the shortest HMAC secret accepted, the size of an HS256 key.
+const minSecretSize = 32
+
+// Tokens issues and checks the signed access tokens of users and mints their
+// refresh tokens.
+type Tokens struct {
+	method     jwt.SigningMethod
+	signKey    any
+	verifyKey  any
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// accessClaims are the claims of an access token; the subject is the user ID.
+type accessClaims struct {
+	jwt.RegisteredClaims
+	Login string `json:"login"`
+	Role  string `json:"role"`
+}
+
+func NewTokens(cfg config.AuthConfig) (*Tokens, error) {
+	t := &Tokens{accessTTL: cfg.AccessTokenTTL, refreshTTL: cfg.RefreshTokenTTL}
+	switch {
+	case cfg.JWTPrivateKeyFile != "":
+		data, err := os.ReadFile(cfg.JWTPrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read JWT private key: %w", err)
+		}
+		key, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse JWT private key: %w", err)
+		}
+		t.method, t.signKey, t.verifyKey = jwt.SigningMethodRS256, key, &key.PublicKey
+	case cfg.JWTSecret != "":
+		if len(cfg.JWTSecret) < minSecretSize {
+			return nil, fmt.Errorf("jwt_secret must be at least %d bytes long", minSecretSize)
+		}
+		t.method, t.signKey, t.verifyKey = jwt.SigningMethodHS256, []byte(cfg.JWTSecret), []byte(cfg.JWTSecret)
+	default:
+		secret := make([]byte, minSecretSize)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		slog.Warn("jwt_secret не задан: токены подписываются случайным ключом и перестанут действовать после перезапуска")
+		t.method, t.signKey, t.verifyKey = jwt.SigningMethodHS256, secret, secret
+	}
+	return t, nil
+}
+
+// Issue signs an access token for user.
+func (t *Tokens) Issue(user model.User) (string, error) {
+	now := time.Now()
+	claims := accessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenIssuer,
+			Subject:   strconv.Itoa(user.ID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(t.accessTTL)),
+		},
+		Login: user.Login,
+		Role:  user.Role,
+	}
+	return jwt.NewWithClaims(t.method, claims).SignedString(t.signKey)
+}
+
+// Parse checks an access token and returns the user it was issued to.
+func (t *Tokens) Parse(token string) (model.Principal, error) {
+	var claims accessClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (any, error) { return t.verifyKey, nil },
+		jwt.WithValidMethods([]string{t.method.Alg()}),
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithExpirationRequired(),
+	)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return model.Principal{}, model.NewError(model.ErrUnauthorized, "Срок действия токена истёк")
+	}
+	if err != nil {
+		slog.Debug("Недействительный токен", "error", err)
+		return model.Principal{}, model.NewError(model.ErrUnauthorized, "Недействительный токен")
+	}
+	id, err := strconv.Atoi(claims.Subject)
+	if err != nil || !model.ValidRole(claims.Role) {
+		return model.Principal{}, model.NewError(model.ErrUnauthorized, "Недействительный токен")
+	}
+	return model.Principal{Kind: model.PrincipalUser, ID: id, Name: claims.Login, Role: claims.Role}, nil
+}
+
+// newRefreshToken mints an opaque refresh token and returns it with its hash
+// and expiry.
+func (t *Tokens) newRefreshToken() (string, []byte, time.Time, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, time.Time{}, err
+	}
+	raw := base64.RawURLEncoding.EncodeToString(secret)
+	return raw, hashSecret(raw), time.Now().Add(t.refreshTTL), nil
+}
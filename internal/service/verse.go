@@ -0,0 +1,160 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/Xapsiel/EffectiveMobile/internal/repository"
+)
+
+type verseService struct {
+	repo   repository.Verse
+	songs  repository.Song
+	lyrics *Lyrics
+}
+
+func NewVerseService(repo repository.Verse, songs repository.Song, lyrics *Lyrics) *verseService {
+	return &verseService{repo: repo, songs: songs, lyrics: lyrics}
+}
+
+func (s *verseService) GetVerses(songID int) ([]model.Verse, model.Song, error) {
+	return s.repo.GetVerses(songID)
+}
+
+// GetSongsVerses returns the verses of each of songIDs, keyed by song ID.
+func (s *verseService) GetSongsVerses(songIDs []int) (map[int][]model.Verse, error) {
+	if len(songIDs) == 0 {
+		return map[int][]model.Verse{}, nil
+	}
+	return s.repo.GetSongsVerses(songIDs)
+}
+
+// GetSections returns the song as labelled sections. With an empty parser name
+// the stored verses are returned; otherwise the stored text is split with the
+// named parser without saving the result. The name of the parser that produced
+// the sections is returned alongside.
+func (s *verseService) GetSections(songID int, parser string) ([]model.Verse, string, error) {
+	song, err := s.songs.GetSong(model.Song{ID: &songID})
+	if err != nil {
+		return nil, "", err
+	}
+	if parser == "" {
+		verses, _, err := s.repo.GetVerses(songID)
+		if err != nil {
+			return nil, "", err
+		}
+		_, name, err := s.lyrics.Parser(valueOrEmpty(song.SectionParser))
+		return verses, name, err
+	}
+
+	p, name, err := s.lyrics.Parser(parser)
+	if err != nil {
+		return nil, "", err
+	}
+	verses := p.Parse(*song.Text)
+	for i := range verses {
+		ordinal := i + 1
+		verses[i].SongID, verses[i].Ordinal = &songID, &ordinal
+		if verses[i].Type == nil {
+			typ := model.VerseTypeVerse
+			verses[i].Type = &typ
+		}
+	}
+	return verses, name, nil
+}
+
+func (s *verseService) InsertVerse(songID int, version *int, verse model.Verse) (model.Verse, model.Song, error) {
+	verse.Text = s.normalize(verse.Text)
+	if (verse.Text == nil || *verse.Text == "") && verse.Label == nil {
+		return model.Verse{}, model.Song{}, model.NewError(model.ErrValidation, "Текст куплета пуст").WithField("text")
+	}
+	if err := validateVerse(verse); err != nil {
+		return model.Verse{}, model.Song{}, err
+	}
+	labelVerse(&verse)
+	return s.repo.InsertVerse(songID, version, verse)
+}
+
+func (s *verseService) UpdateVerse(songID int, version *int, verse model.Verse) (model.Verse, model.Song, error) {
+	verse.Text = s.normalize(verse.Text)
+	if verse.Type == nil && verse.Text == nil && verse.Label == nil {
+		return model.Verse{}, model.Song{}, model.NewError(model.ErrValidation, "Нет данных для обновления")
+	}
+	if verse.Repeat != nil && verse.Label == nil {
+		return model.Verse{}, model.Song{}, model.NewError(model.ErrValidation, "Число повторов задаётся только вместе с меткой").WithField("repeat")
+	}
+	if err := validateVerse(verse); err != nil {
+		return model.Verse{}, model.Song{}, err
+	}
+	labelVerse(&verse)
+	return s.repo.UpdateVerse(songID, version, verse)
+}
+
+func (s *verseService) DeleteVerse(songID int, version *int, ordinal int) (model.Song, error) {
+	return s.repo.DeleteVerse(songID, version, ordinal)
+}
+
+func (s *verseService) ReorderVerses(songID int, version *int, order []int) ([]model.Verse, model.Song, error) {
+	return s.repo.ReorderVerses(songID, version, order)
+}
+
+func (s *verseService) normalize(text *string) *string {
+	if text == nil {
+		return nil
+	}
+	normalized := s.lyrics.Normalize(*text)
+	return &normalized
+}
+
+// validateVerse rejects verse types outside the known set and texts that would
+// split into several verses once joined back into the song text.
+func validateVerse(verse model.Verse) error {
+	if verse.Type != nil {
+		switch *verse.Type {
+		case model.VerseTypeVerse, model.VerseTypeChorus, model.VerseTypeBridge, model.VerseTypeIntro:
+		default:
+			return model.NewError(model.ErrValidation, "Неизвестный тип куплета %q", *verse.Type).WithField("type")
+		}
+	}
+	if verse.Text != nil && strings.Contains(*verse.Text, model.VerseSeparator) {
+		return model.NewError(model.ErrValidation, "Текст куплета не должен содержать пустых строк").WithField("text")
+	}
+	if verse.Label != nil && (strings.TrimSpace(*verse.Label) == "" || strings.ContainsAny(*verse.Label, "[]\n")) {
+		return model.NewError(model.ErrValidation, "Некорректная метка раздела %q", *verse.Label).WithField("label")
+	}
+	if verse.Repeat != nil && *verse.Repeat < 1 {
+		return model.NewError(model.ErrValidation, "Число повторов должно быть положительным").WithField("repeat")
+	}
+	return nil
+}
+
+// labelVerse builds the header line for a label given through the API and
+// derives the verse type from it when the type is not set explicitly.
+func labelVerse(verse *model.Verse) {
+	if verse.Label == nil {
+		return
+	}
+	header := sectionHeader(*verse.Label, verse.Repeat)
+	verse.Header = &header
+	if verse.Repeat == nil {
+		one := 1
+		verse.Repeat = &one
+	}
+	if verse.Type == nil {
+		typ := sectionType(*verse.Label)
+		verse.Type = &typ
+	}
+}
+
+// splitVerses breaks song text into verses on blank lines.
+func splitVerses(text string) []model.Verse {
+	if text == "" {
+		return nil
+	}
+	parts := strings.Split(text, model.VerseSeparator)
+	verses := make([]model.Verse, len(parts))
+	for i := range parts {
+		verses[i] = model.Verse{Text: &parts[i]}
+	}
+	return verses
+}
@@ -0,0 +1,324 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Xapsiel/EffectiveMobile/internal/config"
+	"github.com/Xapsiel/EffectiveMobile/internal/model"
+	"github.com/Xapsiel/EffectiveMobile/internal/repository"
+)
+
+const (
+	// webhookBatch is the number of deliveries claimed and sent at once.
+	webhookBatch = 20
+	// webhookPollInterval is how often the dispatcher looks for deliveries
+	// due for a retry when no new events wake it up.
+	webhookPollInterval = 5 * time.Second
+	// webhookSecretPrefix starts every generated webhook secret.
+	webhookSecretPrefix = "whsec_"
+	// minWebhookSecretSize is the shortest secret accepted from a client.
+	minWebhookSecretSize = 16
+	// maxWebhookResponse is how much of a response body is read before the
+	// connection is given back.
+	maxWebhookResponse = 64 << 10
+)
+
+// WebhookSender sends webhook deliveries and decides when failed ones are
+// tried again.
+type WebhookSender struct {
+	client      *http.Client
+	maxAttempts int
+	retryBase   time.Duration
+	retryMax    time.Duration
+}
+
+func NewWebhookSender(cfg config.WebhookConfig) (*WebhookSender, error) {
+	if cfg.Timeout <= 0 || cfg.RetryBase <= 0 || cfg.RetryMax < cfg.RetryBase {
+		return nil, fmt.Errorf("webhook_timeout and webhook_retry_base must be positive, webhook_retry_max at least webhook_retry_base")
+	}
+	if cfg.MaxAttempts < 1 {
+		return nil, fmt.Errorf("webhook_max_attempts must be positive")
+	}
+	return &WebhookSender{
+		client: &http.Client{
+			Timeout: cfg.Timeout,
+			// A redirect is answered like any other non-2xx status: the
+			// subscriber has to fix the URL rather than have payloads follow it.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		maxAttempts: cfg.MaxAttempts,
+		retryBase:   cfg.RetryBase,
+		retryMax:    cfg.RetryMax,
+	}, nil
+}
+
+// lease is how long a claimed delivery is kept from other dispatchers: long
+// enough for the request to time out and its outcome to be recorded.
+func (w *WebhookSender) lease() time.Duration {
+	return 2 * w.client.Timeout
+}
+
+// backoff is the wait after the given number of failed attempts.
+func (w *WebhookSender) backoff(attempts int) time.Duration {
+	delay := w.retryBase
+	for i := 1; i < attempts && delay < w.retryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, w.retryMax)
+}
+
+// webhookPayload is the body of a delivery. Changes names the song fields as
+// the first API version does.
+type webhookPayload struct {
+	ID      int64      `json:"id"`
+	Event   string     `json:"event"`
+	SongID  int        `json:"song_id"`
+	GroupID int        `json:"group_id"`
+	Version int        `json:"version"`
+	Actor   *string    `json:"actor,omitempty"`
+	At      time.Time  `json:"at"`
+	Changes model.Song `json:"changes"`
+}
+
+// signWebhook is the X-Webhook-Signature of a delivery: the hex HMAC-SHA256
+// of "<timestamp>.<body>" keyed with the webhook secret.
+func signWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// send makes one attempt at job and returns the response status, if any.
+// Only a 2xx status counts as delivered.
+func (w *WebhookSender) send(ctx context.Context, job model.WebhookJob) (*int, error) {
+	event := job.Event
+	body, err := json.Marshal(webhookPayload{
+		ID:      event.ID,
+		Event:   job.Delivery.Event,
+		SongID:  event.SongID,
+		GroupID: event.GroupID,
+		Version: event.Version,
+		Actor:   event.Actor,
+		At:      event.At,
+		Changes: event.Changes,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "songs-webhooks")
+	req.Header.Set("X-Webhook-Id", strconv.FormatInt(job.Delivery.ID, 10))
+	req.Header.Set("X-Webhook-Event", job.Delivery.Event)
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", signWebhook(job.Secret, timestamp, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxWebhookResponse))
+
+	status := resp.StatusCode
+	if status < 200 || status > 299 {
+		return &status, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return &status, nil
+}
+
+type webhookService struct {
+	repo   repository.Webhook
+	events Event
+	sender *WebhookSender
+}
+
+func NewWebhookService(repo repository.Webhook, events Event, sender *WebhookSender) *webhookService {
+	return &webhookService{repo: repo, events: events, sender: sender}
+}
+
+// CreateWebhook subscribes hook.URL to hook.Events. Without a secret one is
+// generated; the returned secret is the only time it is shown.
+func (s *webhookService) CreateWebhook(hook model.Webhook, secret string) (model.Webhook, string, error) {
+	if err := validWebhook(hook); err != nil {
+		return model.Webhook{}, "", err
+	}
+	if secret == "" {
+		raw := make([]byte, 32)
+		if _, err := rand.Read(raw); err != nil {
+			return model.Webhook{}, "", err
+		}
+		secret = webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(raw)
+	} else if len(secret) < minWebhookSecretSize {
+		return model.Webhook{}, "", model.NewError(model.ErrValidation, "Секрет вебхука должен быть не короче %d символов", minWebhookSecretSize).WithField("secret")
+	}
+
+	hook.Active = true
+	created, err := s.repo.CreateWebhook(hook, secret)
+	if err != nil {
+		return model.Webhook{}, "", err
+	}
+	return created, secret, nil
+}
+
+func (s *webhookService) ListWebhooks() ([]model.Webhook, error) {
+	return s.repo.ListWebhooks()
+}
+
+func (s *webhookService) GetWebhook(id int) (model.Webhook, error) {
+	return s.repo.GetWebhook(id)
+}
+
+func (s *webhookService) UpdateWebhook(hook model.Webhook) (model.Webhook, error) {
+	if err := validWebhook(hook); err != nil {
+		return model.Webhook{}, err
+	}
+	return s.repo.UpdateWebhook(hook)
+}
+
+func (s *webhookService) DeleteWebhook(id int) error {
+	return s.repo.DeleteWebhook(id)
+}
+
+// WebhookDeliveries returns a page of the delivery log of the webhook, newest
+// first; a non-empty status keeps the deliveries in that state.
+func (s *webhookService) WebhookDeliveries(webhookID int, status string, page int, limit int) ([]model.WebhookDelivery, error) {
+	if status != "" && !validDeliveryStatus(status) {
+		return nil, model.NewError(model.ErrValidation, "Неизвестное состояние доставки %q, допустимы %s", status, strings.Join(model.DeliveryStatuses, ", ")).WithField("status")
+	}
+	if _, err := s.repo.GetWebhook(webhookID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetWebhookDeliveries(webhookID, status, page, limit)
+}
+
+// RetryWebhookDelivery sends a dead or pending delivery again as soon as
+// possible, with all its attempts ahead of it.
+func (s *webhookService) RetryWebhookDelivery(webhookID int, id int64) (model.WebhookDelivery, error) {
+	return s.repo.RetryWebhookDelivery(webhookID, id)
+}
+
+// DeliverWebhooks sends the due webhook deliveries until ctx is done. It
+// wakes up on new song events and polls for retries in between.
+func (s *webhookService) DeliverWebhooks(ctx context.Context) {
+	wake, unsubscribe := s.events.SubscribeSongEvents()
+	defer unsubscribe()
+	poll := time.NewTicker(webhookPollInterval)
+	defer poll.Stop()
+
+	slog.Info("Запущена доставка вебхуков")
+	for {
+		n, err := s.deliverDue(ctx)
+		if err != nil {
+			slog.Error("Ошибка при получении доставок вебхуков", "error", err)
+		}
+		if n == webhookBatch {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			slog.Info("Доставка вебхуков остановлена")
+			return
+		case <-wake:
+		case <-poll.C:
+		}
+	}
+}
+
+// deliverDue sends one batch of due deliveries concurrently and records the
+// outcomes. It returns how many deliveries it sent.
+func (s *webhookService) deliverDue(ctx context.Context) (int, error) {
+	jobs, err := s.repo.ClaimWebhookDeliveries(webhookBatch, s.sender.lease())
+	if err != nil {
+		return 0, err
+	}
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.deliver(ctx, job)
+		}()
+	}
+	wg.Wait()
+	return len(jobs), nil
+}
+
+func (s *webhookService) deliver(ctx context.Context, job model.WebhookJob) {
+	d := job.Delivery
+	d.Attempts++
+	d.NextAttemptAt = nil
+	status, err := s.sender.send(ctx, job)
+	if errors.Is(err, context.Canceled) && ctx.Err() != nil {
+		// Shutting down: the delivery is sent again once the lease runs out.
+		return
+	}
+	d.LastStatus, d.LastError = status, nil
+	now := time.Now()
+	switch {
+	case err == nil:
+		d.Status, d.DeliveredAt = model.DeliveryDelivered, &now
+		slog.Info("Вебхук доставлен", "delivery_id", d.ID, "webhook_id", d.WebhookID, "event", d.Event, "attempts", d.Attempts)
+	case d.Attempts >= s.sender.maxAttempts:
+		msg := err.Error()
+		d.Status, d.LastError = model.DeliveryDead, &msg
+		slog.Error("Доставка вебхука исчерпала попытки", "delivery_id", d.ID, "webhook_id", d.WebhookID, "attempts", d.Attempts, "error", err)
+	default:
+		msg := err.Error()
+		next := now.Add(s.sender.backoff(d.Attempts))
+		d.Status, d.LastError, d.NextAttemptAt = model.DeliveryPending, &msg, &next
+		slog.Warn("Не удалось доставить вебхук", "delivery_id", d.ID, "webhook_id", d.WebhookID, "attempts", d.Attempts, "next_attempt_at", next, "error", err)
+	}
+	if err := s.repo.FinishWebhookDelivery(d); err != nil {
+		slog.Error("Не удалось сохранить результат доставки вебхука", "delivery_id", d.ID, "error", err)
+	}
+}
+
+// validWebhook checks the URL and events of a webhook.
+func validWebhook(hook model.Webhook) error {
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return model.NewError(model.ErrValidation, "Некорректный URL вебхука %q: нужен абсолютный адрес http или https", hook.URL).WithField("url")
+	}
+	if len(hook.Events) == 0 {
+		return model.NewError(model.ErrValidation, "Не указаны события вебхука").WithField("events")
+	}
+	for _, event := range hook.Events {
+		if !model.ValidWebhookEvent(event) {
+			return model.NewError(model.ErrValidation, "Неизвестное событие %q, допустимы %s", event, strings.Join(model.WebhookEvents, ", ")).WithField("events")
+		}
+	}
+	return nil
+}
+
+func validDeliveryStatus(status string) bool {
+	for _, s := range model.DeliveryStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
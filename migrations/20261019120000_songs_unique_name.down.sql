@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_songs_group_id_song_name;
@@ -0,0 +1,23 @@
+-- Add never prevented duplicates, so an existing database may hold several
+-- songs with the same name in one group. Which of them to keep is for the
+-- operator to decide: stop with the list instead of deleting any.
+DO $$
+DECLARE
+    duplicates TEXT;
+BEGIN
+    SELECT string_agg(format('группа %L, песня %L: id %s', g.name, d.song_name, d.ids), E'\n' ORDER BY g.name, d.song_name)
+    INTO duplicates
+    FROM (
+        SELECT group_id, song_name, string_agg(id::text, ', ' ORDER BY id) AS ids
+        FROM songs
+        GROUP BY group_id, song_name
+        HAVING count(*) > 1
+    ) d
+    JOIN groups g ON g.id = d.group_id;
+    IF duplicates IS NOT NULL THEN
+        RAISE EXCEPTION 'В таблице songs есть повторяющиеся песни, удалите или переименуйте лишние строки и повторите миграцию:%', E'\n' || duplicates;
+    END IF;
+END
+$$;
+
+CREATE UNIQUE INDEX idx_songs_group_id_song_name ON songs (group_id, song_name);
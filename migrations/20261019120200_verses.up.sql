@@ -0,0 +1,14 @@
+CREATE TABLE verses (
+                        id SERIAL PRIMARY KEY,
+                        song_id INTEGER NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+                        ordinal INTEGER NOT NULL CHECK (ordinal > 0),
+                        type TEXT NOT NULL DEFAULT 'verse' CHECK (type IN ('verse', 'chorus', 'bridge', 'intro')),
+                        text TEXT NOT NULL,
+                        CONSTRAINT verses_song_id_ordinal_key UNIQUE (song_id, ordinal) DEFERRABLE INITIALLY DEFERRED
+);
+
+INSERT INTO verses (song_id, ordinal, text)
+SELECT s.id, v.ordinal, v.text
+FROM songs AS s,
+     regexp_split_to_table(s.text, E'\n\n') WITH ORDINALITY AS v(text, ordinal)
+WHERE s.text <> '';
@@ -0,0 +1,6 @@
+ALTER TABLE verses
+    DROP COLUMN IF EXISTS repeat,
+    DROP COLUMN IF EXISTS header,
+    DROP COLUMN IF EXISTS label;
+
+ALTER TABLE songs DROP COLUMN IF EXISTS section_parser;
@@ -0,0 +1,6 @@
+ALTER TABLE songs ADD COLUMN section_parser TEXT;
+
+ALTER TABLE verses
+    ADD COLUMN label TEXT,
+    ADD COLUMN header TEXT,
+    ADD COLUMN repeat INTEGER NOT NULL DEFAULT 1 CHECK (repeat > 0);
@@ -0,0 +1,10 @@
+CREATE TABLE api_keys (
+                          id SERIAL PRIMARY KEY,
+                          name TEXT NOT NULL,
+                          prefix TEXT NOT NULL UNIQUE,
+                          hash BYTEA NOT NULL,
+                          scopes TEXT[] NOT NULL CHECK (cardinality(scopes) > 0 AND scopes <@ ARRAY['read', 'write', 'admin']),
+                          created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                          last_used_at TIMESTAMPTZ,
+                          revoked_at TIMESTAMPTZ
+);
@@ -0,0 +1,18 @@
+CREATE TABLE users (
+                       id SERIAL PRIMARY KEY,
+                       login TEXT NOT NULL UNIQUE,
+                       password_hash TEXT NOT NULL,
+                       role TEXT NOT NULL DEFAULT 'viewer' CHECK (role IN ('viewer', 'editor', 'admin')),
+                       created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE refresh_tokens (
+                                id SERIAL PRIMARY KEY,
+                                user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+                                hash BYTEA NOT NULL UNIQUE,
+                                created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                expires_at TIMESTAMPTZ NOT NULL,
+                                revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id);
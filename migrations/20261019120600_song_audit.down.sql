@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS songs_audit ON songs;
+DROP FUNCTION IF EXISTS record_song_audit();
+DROP TABLE IF EXISTS song_audit;
@@ -0,0 +1,29 @@
+-- Every change of a song row is recorded with the acting user, which the
+-- application passes as the transaction-local setting app.actor.
+CREATE TABLE song_audit (
+                            id BIGSERIAL PRIMARY KEY,
+                            song_id INTEGER NOT NULL,
+                            action TEXT NOT NULL CHECK (action IN ('insert', 'update', 'delete')),
+                            actor TEXT,
+                            version INTEGER NOT NULL,
+                            at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX song_audit_song_id_idx ON song_audit (song_id);
+
+CREATE FUNCTION record_song_audit() RETURNS trigger AS $$
+DECLARE
+    actor TEXT := NULLIF(current_setting('app.actor', true), '');
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO song_audit (song_id, action, actor, version) VALUES (OLD.id, 'delete', actor, OLD.version);
+        RETURN OLD;
+    END IF;
+    INSERT INTO song_audit (song_id, action, actor, version) VALUES (NEW.id, lower(TG_OP), actor, NEW.version);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER songs_audit
+    AFTER INSERT OR UPDATE OR DELETE ON songs
+    FOR EACH ROW EXECUTE FUNCTION record_song_audit();
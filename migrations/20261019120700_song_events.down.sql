@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS songs_events ON songs;
+DROP FUNCTION IF EXISTS record_song_event();
+DROP FUNCTION IF EXISTS song_event_fields(songs);
+DROP TABLE IF EXISTS song_events;
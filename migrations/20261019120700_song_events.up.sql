@@ -0,0 +1,62 @@
+-- song_events is the change feed of the catalogue: every change of a song row
+-- is written with the fields it set, in the transaction that made it, and
+-- announced on the song_events channel for the listeners to read it.
+CREATE TABLE song_events (
+                             id BIGSERIAL PRIMARY KEY,
+                             song_id INTEGER NOT NULL,
+                             group_id INTEGER NOT NULL,
+                             type TEXT NOT NULL CHECK (type IN ('created', 'updated', 'deleted')),
+                             version INTEGER NOT NULL,
+                             changes JSONB NOT NULL DEFAULT '{}',
+                             actor TEXT,
+                             at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX song_events_group_id_idx ON song_events (group_id, id);
+
+-- song_event_fields are the fields of a song as model.Song names them in JSON.
+CREATE FUNCTION song_event_fields(s songs) RETURNS JSONB AS $$
+    SELECT jsonb_build_object(
+        'group_name', (SELECT name FROM groups WHERE id = s.group_id),
+        'song_name', s.song_name,
+        'releaseDate', to_char(s.release_date, 'DD.MM.YYYY'),
+        'link', s.link,
+        'text', s.text,
+        'version', s.version,
+        'section_parser', COALESCE(s.section_parser, ''))
+$$ LANGUAGE sql STABLE;
+
+CREATE FUNCTION record_song_event() RETURNS trigger AS $$
+DECLARE
+    actor TEXT := NULLIF(current_setting('app.actor', true), '');
+    old_fields JSONB;
+    changes JSONB;
+    event_id BIGINT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO song_events (song_id, group_id, type, version, actor)
+        VALUES (OLD.id, OLD.group_id, 'deleted', OLD.version, actor)
+        RETURNING id INTO event_id;
+    ELSE
+        changes := song_event_fields(NEW);
+        IF TG_OP = 'UPDATE' THEN
+            old_fields := song_event_fields(OLD);
+            SELECT COALESCE(jsonb_object_agg(f.key, f.value), '{}') INTO changes
+            FROM jsonb_each(changes) AS f
+            WHERE f.value IS DISTINCT FROM old_fields -> f.key;
+            IF changes = '{}' THEN
+                RETURN NULL;
+            END IF;
+        END IF;
+        INSERT INTO song_events (song_id, group_id, type, version, changes, actor)
+        VALUES (NEW.id, NEW.group_id, CASE TG_OP WHEN 'INSERT' THEN 'created' ELSE 'updated' END, NEW.version, changes, actor)
+        RETURNING id INTO event_id;
+    END IF;
+    PERFORM pg_notify('song_events', event_id::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER songs_events
+    AFTER INSERT OR UPDATE OR DELETE ON songs
+    FOR EACH ROW EXECUTE FUNCTION record_song_event();
@@ -0,0 +1,45 @@
+-- webhooks are the subscriptions of downstream systems to song events. The
+-- secret signs the deliveries, so it is kept as is.
+CREATE TABLE webhooks (
+                          id SERIAL PRIMARY KEY,
+                          url TEXT NOT NULL,
+                          events TEXT[] NOT NULL,
+                          secret TEXT NOT NULL,
+                          active BOOLEAN NOT NULL DEFAULT true,
+                          created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- webhook_deliveries is the outbox of the webhooks: a delivery of every song
+-- event to every active webhook subscribed to it is added in the transaction
+-- that recorded the event, then sent until it succeeds or runs out of
+-- attempts and is marked dead.
+CREATE TABLE webhook_deliveries (
+                                    id BIGSERIAL PRIMARY KEY,
+                                    webhook_id INTEGER NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+                                    event_id BIGINT NOT NULL REFERENCES song_events (id) ON DELETE CASCADE,
+                                    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'dead')),
+                                    attempts INTEGER NOT NULL DEFAULT 0,
+                                    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                    last_status INTEGER,
+                                    last_error TEXT,
+                                    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                    delivered_at TIMESTAMPTZ,
+                                    UNIQUE (webhook_id, event_id)
+);
+
+CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id, id);
+
+CREATE FUNCTION enqueue_webhook_deliveries() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO webhook_deliveries (webhook_id, event_id)
+    SELECT w.id, NEW.id
+    FROM webhooks AS w
+    WHERE w.active AND 'song.' || NEW.type = ANY (w.events);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER song_events_webhooks
+    AFTER INSERT ON song_events
+    FOR EACH ROW EXECUTE FUNCTION enqueue_webhook_deliveries();
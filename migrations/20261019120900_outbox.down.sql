@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS outbox_retries;
+DROP TABLE IF EXISTS outbox_cursors;
@@ -0,0 +1,25 @@
+-- The outbox relay publishes the song events of song_events, which are written
+-- in the transaction that made the change: an event exists if and only if the
+-- change was committed. outbox_cursors holds for every sink the last event the
+-- relay handled, and until when a relay leases the sink away from the others.
+CREATE TABLE outbox_cursors (
+                                sink TEXT PRIMARY KEY,
+                                event_id BIGINT NOT NULL,
+                                leased_until TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- outbox_retries holds the events behind the cursor a sink has not taken
+-- yet: the event it failed to take, retried after a growing backoff, and the
+-- later events of its song, which wait behind it. The events of the other
+-- songs go on.
+CREATE TABLE outbox_retries (
+                                sink TEXT NOT NULL REFERENCES outbox_cursors (sink) ON DELETE CASCADE,
+                                event_id BIGINT NOT NULL REFERENCES song_events (id) ON DELETE CASCADE,
+                                song_id INTEGER NOT NULL,
+                                attempts INTEGER NOT NULL DEFAULT 0,
+                                last_error TEXT,
+                                next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+                                PRIMARY KEY (sink, event_id)
+);
+
+CREATE INDEX outbox_retries_song_id_idx ON outbox_retries (sink, song_id, event_id) INCLUDE (next_attempt_at);
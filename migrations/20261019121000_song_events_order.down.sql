@@ -0,0 +1,36 @@
+DROP INDEX IF EXISTS song_events_song_id_idx;
+DROP INDEX IF EXISTS song_events_group_id_idx;
+DROP INDEX IF EXISTS song_events_order_idx;
+ALTER TABLE song_events DROP COLUMN IF EXISTS xact_order;
+CREATE INDEX song_events_group_id_idx ON song_events (group_id, id);
+
+CREATE OR REPLACE FUNCTION record_song_event() RETURNS trigger AS $$
+DECLARE
+    actor TEXT := NULLIF(current_setting('app.actor', true), '');
+    old_fields JSONB;
+    changes JSONB;
+    event_id BIGINT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO song_events (song_id, group_id, type, version, actor)
+        VALUES (OLD.id, OLD.group_id, 'deleted', OLD.version, actor)
+        RETURNING id INTO event_id;
+    ELSE
+        changes := song_event_fields(NEW);
+        IF TG_OP = 'UPDATE' THEN
+            old_fields := song_event_fields(OLD);
+            SELECT COALESCE(jsonb_object_agg(f.key, f.value), '{}') INTO changes
+            FROM jsonb_each(changes) AS f
+            WHERE f.value IS DISTINCT FROM old_fields -> f.key;
+            IF changes = '{}' THEN
+                RETURN NULL;
+            END IF;
+        END IF;
+        INSERT INTO song_events (song_id, group_id, type, version, changes, actor)
+        VALUES (NEW.id, NEW.group_id, CASE TG_OP WHEN 'INSERT' THEN 'created' ELSE 'updated' END, NEW.version, changes, actor)
+        RETURNING id INTO event_id;
+    END IF;
+    PERFORM pg_notify('song_events', event_id::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
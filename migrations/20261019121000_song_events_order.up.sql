@@ -0,0 +1,52 @@
+-- IDs of song events do not follow the commit order of the transactions that
+-- wrote them, so a reader resuming after an ID could miss an event committed
+-- later with a smaller one. Events are read in xact_order, then ID order
+-- instead (see GetSongEvents), and only when xact_order is below the oldest
+-- running transaction: an event committed later gets a greater xact_order.
+--
+-- xact_order is the ID of the transaction that recorded the event, raised to
+-- the xact_order of the previous event of the song, so that the events of a
+-- song keep the order in which their changes were committed.
+ALTER TABLE song_events ADD COLUMN xact_order xid8 NOT NULL DEFAULT pg_current_xact_id();
+
+DROP INDEX song_events_group_id_idx;
+CREATE INDEX song_events_order_idx ON song_events (xact_order, id);
+CREATE INDEX song_events_group_id_idx ON song_events (group_id, xact_order, id);
+CREATE INDEX song_events_song_id_idx ON song_events (song_id, xact_order);
+
+CREATE OR REPLACE FUNCTION record_song_event() RETURNS trigger AS $$
+DECLARE
+    actor TEXT := NULLIF(current_setting('app.actor', true), '');
+    old_fields JSONB;
+    changes JSONB;
+    event_id BIGINT;
+    event_order xid8;
+BEGIN
+    -- The previous change of the song is committed: this one waited for its
+    -- row lock.
+    SELECT greatest(pg_current_xact_id(), max(e.xact_order)) INTO event_order
+    FROM song_events AS e
+    WHERE e.song_id = CASE TG_OP WHEN 'DELETE' THEN OLD.id ELSE NEW.id END;
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO song_events (song_id, group_id, type, version, actor, xact_order)
+        VALUES (OLD.id, OLD.group_id, 'deleted', OLD.version, actor, event_order)
+        RETURNING id INTO event_id;
+    ELSE
+        changes := song_event_fields(NEW);
+        IF TG_OP = 'UPDATE' THEN
+            old_fields := song_event_fields(OLD);
+            SELECT COALESCE(jsonb_object_agg(f.key, f.value), '{}') INTO changes
+            FROM jsonb_each(changes) AS f
+            WHERE f.value IS DISTINCT FROM old_fields -> f.key;
+            IF changes = '{}' THEN
+                RETURN NULL;
+            END IF;
+        END IF;
+        INSERT INTO song_events (song_id, group_id, type, version, changes, actor, xact_order)
+        VALUES (NEW.id, NEW.group_id, CASE TG_OP WHEN 'INSERT' THEN 'created' ELSE 'updated' END, NEW.version, changes, actor, event_order)
+        RETURNING id INTO event_id;
+    END IF;
+    PERFORM pg_notify('song_events', event_id::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;